@@ -1,74 +1,129 @@
-package main
-
-import (
-	"context"
-	"crawler/internal/api"
-	"crawler/internal/config"
-	"crawler/internal/crawler"
-	"crawler/internal/monitoring"
-	"crawler/internal/proxy"
-	"crawler/internal/storage"
-	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"go.uber.org/zap"
-)
-
-func main() {
-	// Initialize structured logger
-	logger, _ := zap.NewProduction()
-	defer logger.Sync()
-
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		logger.Fatal("could not load config", zap.Error(err))
-	}
-
-	// Initialize Storage Layer
-	pgStore, err := storage.NewPostgresStore(cfg.PostgresURL)
-	if err != nil {
-		logger.Fatal("failed to connect to postgres", zap.Error(err))
-	}
-	redisStore := storage.NewRedisStore(cfg.RedisAddr)
-
-	// Initialize Monitoring, Proxies
-	metrics := monitoring.NewMetrics()
-	proxyManager := proxy.NewManager()
-
-	// Initialize Core Crawler
-	coreCrawler := crawler.NewCrawler(cfg, redisStore, pgStore, proxyManager, metrics, logger)
-	coreCrawler.Start()
-
-	// Initialize API Server
-	server := api.NewServer(cfg, coreCrawler, pgStore, redisStore, metrics, logger)
-
-	// Graceful Shutdown
-	go func() {
-		if err := server.Start(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal("could not start server", zap.Error(err))
-		}
-	}()
-
-	logger.Info("server started", zap.String("port", cfg.ServerPort))
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	logger.Info("shutting down server...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	coreCrawler.Stop()
-
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Fatal("server forced to shutdown", zap.Error(err))
-	}
-
-	logger.Info("server exiting")
-}
+package main
+
+import (
+	"context"
+	"crawler/internal/api"
+	"crawler/internal/config"
+	"crawler/internal/crawler"
+	"crawler/internal/monitoring"
+	"crawler/internal/proxy"
+	"crawler/internal/storage"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	// Initialize structured logger
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal("could not load config", zap.Error(err))
+	}
+
+	// Initialize Storage Layer
+	var pageStore storage.PageStore
+	if cfg.DataBackend == "file" {
+		logger.Warn("using the file data backend: listings and summaries scan every page, so keep crawls small",
+			zap.String("dir", cfg.DataDir))
+		pageStore, err = storage.NewFileStore(cfg.DataDir)
+		if err != nil {
+			logger.Fatal("failed to open the data directory", zap.Error(err))
+		}
+	} else {
+		pageStore, err = storage.NewPostgresStore(cfg.PostgresURL, cfg.PostgresSchema, cfg.TablePrefix)
+		if err != nil {
+			logger.Fatal("failed to connect to postgres", zap.Error(err))
+		}
+	}
+	var stateStore storage.StateStore
+	if cfg.QueueBackend == "memory" {
+		logger.Warn("using the in-memory queue backend: state is not persisted and must not be shared between instances")
+		stateStore = storage.NewMemoryStore()
+	} else {
+		stateStore = storage.NewRedisStore(cfg.RedisAddr, cfg.RedisKeyPrefix)
+	}
+
+	// Initialize Monitoring, Proxies
+	metrics := monitoring.NewMetrics()
+	proxyManager := proxy.NewManager()
+	proxyManager.SetAffinityTTL(time.Duration(cfg.ProxyAffinityTTL) * time.Second)
+	proxyManager.SetMaxPerProxy(cfg.ProxyConcurrency)
+	report := proxyManager.LoadAndValidate(strings.Split(cfg.Proxies, ","), strings.Split(cfg.UserAgents, "|"))
+	logger.Info(fmt.Sprintf("loaded %d proxies, rejected %d malformed", report.LoadedProxies, len(report.RejectedProxies)),
+		zap.Strings("rejected_proxies", report.RejectedProxies),
+		zap.Int("user_agents", report.LoadedUserAgents),
+		zap.Int("rejected_user_agents", report.RejectedUserAgents),
+	)
+
+	if cfg.FailureScreenshotDir != "" {
+		if err := os.MkdirAll(cfg.FailureScreenshotDir, 0o755); err != nil {
+			logger.Fatal("could not create failure screenshot directory", zap.Error(err))
+		}
+	}
+
+	// Initialize Core Crawler
+	coreCrawler := crawler.NewCrawler(cfg, stateStore, pageStore, proxyManager, metrics, logger)
+	if cfg.ChromeRemoteURL != "" {
+		checkCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		err := coreCrawler.CheckRemoteBrowser(checkCtx)
+		cancel()
+		if err != nil {
+			logger.Fatal("remote chrome is unreachable", zap.Error(err))
+		}
+		if report.LoadedProxies > 0 || cfg.HostResolverRules != "" {
+			logger.Warn("PROXIES and HOST_RESOLVER_RULES are not applied to a remote chrome")
+		}
+		logger.Info("crawling with remote chrome", zap.String("url", cfg.ChromeRemoteURL))
+	}
+	coreCrawler.Start()
+
+	// Initialize API Server
+	server := api.NewServer(cfg, coreCrawler, pageStore, stateStore, metrics, logger)
+
+	// Graceful Shutdown
+	go func() {
+		if err := server.Start(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("could not start server", zap.Error(err))
+		}
+	}()
+
+	logger.Info("server started", zap.String("port", cfg.ServerPort))
+
+	if cfg.GRPCPort != "" {
+		go func() {
+			if err := server.StartGRPC(); err != nil {
+				logger.Fatal("could not start grpc server", zap.Error(err))
+			}
+		}()
+		logger.Info("grpc server started", zap.String("port", cfg.GRPCPort))
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Stop taking submissions before the crawler closes its queue
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("server forced to shutdown", zap.Error(err))
+	}
+
+	coreCrawler.Stop()
+	coreCrawler.ReportSummary(ctx)
+
+	logger.Info("server exiting")
+}
@@ -0,0 +1,43 @@
+// Command migrations writes the migrations rendered for the POSTGRES_SCHEMA
+// and TABLE_PREFIX in the environment to a directory, from which the migrate
+// tool applies them. See the migrate-up target of the Makefile.
+package main
+
+import (
+	"crawler/internal/storage"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	in := flag.String("in", "migrations", "directory holding the migrations")
+	out := flag.String("out", ".migrations", "directory to write the rendered migrations to")
+	flag.Parse()
+
+	files, err := filepath.Glob(filepath.Join(*in, "*.sql"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		log.Fatalf("no migrations found in %s", *in)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	schema, prefix := os.Getenv("POSTGRES_SCHEMA"), os.Getenv("TABLE_PREFIX")
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		rendered, err := storage.RenderMigration(string(sql), schema, prefix)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(*out, filepath.Base(file)), []byte(rendered), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	errAdminDisabled = errors.New("Admin operations are disabled")
+	errInvalidToken  = errors.New("Invalid or missing admin token")
+)
+
+// checkAdminToken validates the value of an Authorization header against
+// ADMIN_TOKEN, for both the REST and the gRPC API.
+func (s *Server) checkAdminToken(authorization string) error {
+	if s.config.AdminToken == "" {
+		return errAdminDisabled
+	}
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+		return errInvalidToken
+	}
+	return nil
+}
+
+// requireAdmin restricts a route to requests bearing ADMIN_TOKEN. Admin routes
+// are disabled entirely when no token is configured.
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch err := s.checkAdminToken(r.Header.Get("Authorization")); err {
+		case nil:
+			next.ServeHTTP(w, r)
+		case errAdminDisabled:
+			s.respondWithError(w, http.StatusForbidden, err.Error())
+		default:
+			s.respondWithError(w, http.StatusUnauthorized, err.Error())
+		}
+	})
+}
+
+// requireTokenIfSet restricts a route to requests bearing ADMIN_TOKEN when one
+// is configured, and lets every request through otherwise, like the gRPC API.
+// It guards operational routes that must work out of the box.
+func (s *Server) requireTokenIfSet(next http.Handler) http.Handler {
+	if s.config.AdminToken == "" {
+		return next
+	}
+	return s.requireAdmin(next)
+}
@@ -0,0 +1,933 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: crawler.proto
+
+package crawlerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SubmitRequest struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Urls                 []string               `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	ForceCrawl           bool                   `protobuf:"varint,2,opt,name=force_crawl,json=forceCrawl,proto3" json:"force_crawl,omitempty"`
+	Referer              string                 `protobuf:"bytes,3,opt,name=referer,proto3" json:"referer,omitempty"`
+	SpaNavigation        bool                   `protobuf:"varint,4,opt,name=spa_navigation,json=spaNavigation,proto3" json:"spa_navigation,omitempty"`
+	RemoveConsentBanners bool                   `protobuf:"varint,5,opt,name=remove_consent_banners,json=removeConsentBanners,proto3" json:"remove_consent_banners,omitempty"`
+	Emulation            *Emulation             `protobuf:"bytes,6,opt,name=emulation,proto3" json:"emulation,omitempty"`
+	FollowHreflang       bool                   `protobuf:"varint,7,opt,name=follow_hreflang,json=followHreflang,proto3" json:"follow_hreflang,omitempty"`
+	AutoScroll           bool                   `protobuf:"varint,8,opt,name=auto_scroll,json=autoScroll,proto3" json:"auto_scroll,omitempty"`
+	DisableJavascript    bool                   `protobuf:"varint,9,opt,name=disable_javascript,json=disableJavascript,proto3" json:"disable_javascript,omitempty"`
+	Headers              map[string]string      `protobuf:"bytes,10,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Cookies              map[string]string      `protobuf:"bytes,11,rep,name=cookies,proto3" json:"cookies,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Device preset to emulate: "desktop", "iphone", "iphone-landscape",
+	// "ipad", "ipad-landscape" or "android"
+	Device string `protobuf:"bytes,12,opt,name=device,proto3" json:"device,omitempty"`
+	// Extractors to run, from "meta_tags", "headers", "images", "content",
+	// "dates", "hreflang", "feeds", "links", "microdata", "dom_hash",
+	// "contacts", "markdown" and "fields"; EXTRACTORS by default
+	Extract []string `protobuf:"bytes,13,rep,name=extract,proto3" json:"extract,omitempty"`
+	// URLs still queued at this time are abandoned; the submission is then
+	// tracked as a job under its crawl_request_id, see GET /api/jobs/{id}
+	JobDeadline *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=job_deadline,json=jobDeadline,proto3" json:"job_deadline,omitempty"`
+	// Crawl no earlier than this time, within SCHEDULE_MAX_HORIZON
+	NotBefore *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=not_before,json=notBefore,proto3" json:"not_before,omitempty"`
+	// schema.org type, e.g. "Product" or "Article", whose JSON-LD item is
+	// extracted and checked for the type's required properties
+	SchemaType    string `protobuf:"bytes,16,opt,name=schema_type,json=schemaType,proto3" json:"schema_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitRequest) Reset() {
+	*x = SubmitRequest{}
+	mi := &file_crawler_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitRequest) ProtoMessage() {}
+
+func (x *SubmitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitRequest.ProtoReflect.Descriptor instead.
+func (*SubmitRequest) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubmitRequest) GetUrls() []string {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+func (x *SubmitRequest) GetForceCrawl() bool {
+	if x != nil {
+		return x.ForceCrawl
+	}
+	return false
+}
+
+func (x *SubmitRequest) GetReferer() string {
+	if x != nil {
+		return x.Referer
+	}
+	return ""
+}
+
+func (x *SubmitRequest) GetSpaNavigation() bool {
+	if x != nil {
+		return x.SpaNavigation
+	}
+	return false
+}
+
+func (x *SubmitRequest) GetRemoveConsentBanners() bool {
+	if x != nil {
+		return x.RemoveConsentBanners
+	}
+	return false
+}
+
+func (x *SubmitRequest) GetEmulation() *Emulation {
+	if x != nil {
+		return x.Emulation
+	}
+	return nil
+}
+
+func (x *SubmitRequest) GetFollowHreflang() bool {
+	if x != nil {
+		return x.FollowHreflang
+	}
+	return false
+}
+
+func (x *SubmitRequest) GetAutoScroll() bool {
+	if x != nil {
+		return x.AutoScroll
+	}
+	return false
+}
+
+func (x *SubmitRequest) GetDisableJavascript() bool {
+	if x != nil {
+		return x.DisableJavascript
+	}
+	return false
+}
+
+func (x *SubmitRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *SubmitRequest) GetCookies() map[string]string {
+	if x != nil {
+		return x.Cookies
+	}
+	return nil
+}
+
+func (x *SubmitRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *SubmitRequest) GetExtract() []string {
+	if x != nil {
+		return x.Extract
+	}
+	return nil
+}
+
+func (x *SubmitRequest) GetJobDeadline() *timestamppb.Timestamp {
+	if x != nil {
+		return x.JobDeadline
+	}
+	return nil
+}
+
+func (x *SubmitRequest) GetNotBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NotBefore
+	}
+	return nil
+}
+
+func (x *SubmitRequest) GetSchemaType() string {
+	if x != nil {
+		return x.SchemaType
+	}
+	return ""
+}
+
+type Emulation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Geolocation   *Geolocation           `protobuf:"bytes,1,opt,name=geolocation,proto3" json:"geolocation,omitempty"`
+	Timezone      string                 `protobuf:"bytes,2,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	Locale        string                 `protobuf:"bytes,3,opt,name=locale,proto3" json:"locale,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Emulation) Reset() {
+	*x = Emulation{}
+	mi := &file_crawler_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Emulation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Emulation) ProtoMessage() {}
+
+func (x *Emulation) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Emulation.ProtoReflect.Descriptor instead.
+func (*Emulation) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Emulation) GetGeolocation() *Geolocation {
+	if x != nil {
+		return x.Geolocation
+	}
+	return nil
+}
+
+func (x *Emulation) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *Emulation) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+type Geolocation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Latitude      float64                `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64                `protobuf:"fixed64,2,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Accuracy      float64                `protobuf:"fixed64,3,opt,name=accuracy,proto3" json:"accuracy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Geolocation) Reset() {
+	*x = Geolocation{}
+	mi := &file_crawler_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Geolocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Geolocation) ProtoMessage() {}
+
+func (x *Geolocation) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Geolocation.ProtoReflect.Descriptor instead.
+func (*Geolocation) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Geolocation) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Geolocation) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Geolocation) GetAccuracy() float64 {
+	if x != nil {
+		return x.Accuracy
+	}
+	return 0
+}
+
+type SubmitResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	CrawlRequestId string                 `protobuf:"bytes,1,opt,name=crawl_request_id,json=crawlRequestId,proto3" json:"crawl_request_id,omitempty"`
+	Message        string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Results        []*SubmitResult        `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SubmitResponse) Reset() {
+	*x = SubmitResponse{}
+	mi := &file_crawler_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitResponse) ProtoMessage() {}
+
+func (x *SubmitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitResponse.ProtoReflect.Descriptor instead.
+func (*SubmitResponse) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SubmitResponse) GetCrawlRequestId() string {
+	if x != nil {
+		return x.CrawlRequestId
+	}
+	return ""
+}
+
+func (x *SubmitResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SubmitResponse) GetResults() []*SubmitResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type SubmitResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Url   string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// "accepted", "scheduled", "rejected", "already_queued" or
+	// "duplicate_in_batch"
+	Status        string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	QueuePosition int32  `protobuf:"varint,4,opt,name=queue_position,json=queuePosition,proto3" json:"queue_position,omitempty"`
+	// Unset until there is enough recent throughput to base it on
+	EtaSeconds *float64 `protobuf:"fixed64,5,opt,name=eta_seconds,json=etaSeconds,proto3,oneof" json:"eta_seconds,omitempty"`
+	// When a scheduled URL becomes eligible for crawling
+	EligibleAt    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=eligible_at,json=eligibleAt,proto3" json:"eligible_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitResult) Reset() {
+	*x = SubmitResult{}
+	mi := &file_crawler_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitResult) ProtoMessage() {}
+
+func (x *SubmitResult) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitResult.ProtoReflect.Descriptor instead.
+func (*SubmitResult) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SubmitResult) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *SubmitResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SubmitResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SubmitResult) GetQueuePosition() int32 {
+	if x != nil {
+		return x.QueuePosition
+	}
+	return 0
+}
+
+func (x *SubmitResult) GetEtaSeconds() float64 {
+	if x != nil && x.EtaSeconds != nil {
+		return *x.EtaSeconds
+	}
+	return 0
+}
+
+func (x *SubmitResult) GetEligibleAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EligibleAt
+	}
+	return nil
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_crawler_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetStatusRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type CrawlStatus struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Url              string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Status           string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	FailReason       string                 `protobuf:"bytes,3,opt,name=fail_reason,json=failReason,proto3" json:"fail_reason,omitempty"`
+	UpdatedAt        *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	FailScreenshot   string                 `protobuf:"bytes,5,opt,name=fail_screenshot,json=failScreenshot,proto3" json:"fail_screenshot,omitempty"`
+	SchemaVersion    int32                  `protobuf:"varint,6,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	RetryCount       int64                  `protobuf:"varint,7,opt,name=retry_count,json=retryCount,proto3" json:"retry_count,omitempty"`
+	MaxRetries       int32                  `protobuf:"varint,8,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`
+	NextRetryAt      *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=next_retry_at,json=nextRetryAt,proto3" json:"next_retry_at,omitempty"`
+	RequestCount     int32                  `protobuf:"varint,10,opt,name=request_count,json=requestCount,proto3" json:"request_count,omitempty"`
+	BytesTransferred int64                  `protobuf:"varint,11,opt,name=bytes_transferred,json=bytesTransferred,proto3" json:"bytes_transferred,omitempty"`
+	// Set when the status is "scheduled"
+	EligibleAt    *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=eligible_at,json=eligibleAt,proto3" json:"eligible_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrawlStatus) Reset() {
+	*x = CrawlStatus{}
+	mi := &file_crawler_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrawlStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrawlStatus) ProtoMessage() {}
+
+func (x *CrawlStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrawlStatus.ProtoReflect.Descriptor instead.
+func (*CrawlStatus) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CrawlStatus) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CrawlStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CrawlStatus) GetFailReason() string {
+	if x != nil {
+		return x.FailReason
+	}
+	return ""
+}
+
+func (x *CrawlStatus) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *CrawlStatus) GetFailScreenshot() string {
+	if x != nil {
+		return x.FailScreenshot
+	}
+	return ""
+}
+
+func (x *CrawlStatus) GetSchemaVersion() int32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *CrawlStatus) GetRetryCount() int64 {
+	if x != nil {
+		return x.RetryCount
+	}
+	return 0
+}
+
+func (x *CrawlStatus) GetMaxRetries() int32 {
+	if x != nil {
+		return x.MaxRetries
+	}
+	return 0
+}
+
+func (x *CrawlStatus) GetNextRetryAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NextRetryAt
+	}
+	return nil
+}
+
+func (x *CrawlStatus) GetRequestCount() int32 {
+	if x != nil {
+		return x.RequestCount
+	}
+	return 0
+}
+
+func (x *CrawlStatus) GetBytesTransferred() int64 {
+	if x != nil {
+		return x.BytesTransferred
+	}
+	return 0
+}
+
+func (x *CrawlStatus) GetEligibleAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EligibleAt
+	}
+	return nil
+}
+
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domains       []string               `protobuf:"bytes,1,rep,name=domains,proto3" json:"domains,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_crawler_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WatchEventsRequest) GetDomains() []string {
+	if x != nil {
+		return x.Domains
+	}
+	return nil
+}
+
+type CrawlEvent struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Url    string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Domain string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	// "succeeded", "failed", "retrying" or "skipped"
+	Outcome string `protobuf:"bytes,3,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	// The error of a failed or retried crawl, or why it was skipped
+	Reason        string                 `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	At            *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=at,proto3" json:"at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrawlEvent) Reset() {
+	*x = CrawlEvent{}
+	mi := &file_crawler_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrawlEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrawlEvent) ProtoMessage() {}
+
+func (x *CrawlEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_crawler_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrawlEvent.ProtoReflect.Descriptor instead.
+func (*CrawlEvent) Descriptor() ([]byte, []int) {
+	return file_crawler_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CrawlEvent) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CrawlEvent) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *CrawlEvent) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+func (x *CrawlEvent) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *CrawlEvent) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+var File_crawler_proto protoreflect.FileDescriptor
+
+const file_crawler_proto_rawDesc = "" +
+	"\n" +
+	"\rcrawler.proto\x12\n" +
+	"crawler.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb2\x06\n" +
+	"\rSubmitRequest\x12\x12\n" +
+	"\x04urls\x18\x01 \x03(\tR\x04urls\x12\x1f\n" +
+	"\vforce_crawl\x18\x02 \x01(\bR\n" +
+	"forceCrawl\x12\x18\n" +
+	"\areferer\x18\x03 \x01(\tR\areferer\x12%\n" +
+	"\x0espa_navigation\x18\x04 \x01(\bR\rspaNavigation\x124\n" +
+	"\x16remove_consent_banners\x18\x05 \x01(\bR\x14removeConsentBanners\x123\n" +
+	"\temulation\x18\x06 \x01(\v2\x15.crawler.v1.EmulationR\temulation\x12'\n" +
+	"\x0ffollow_hreflang\x18\a \x01(\bR\x0efollowHreflang\x12\x1f\n" +
+	"\vauto_scroll\x18\b \x01(\bR\n" +
+	"autoScroll\x12-\n" +
+	"\x12disable_javascript\x18\t \x01(\bR\x11disableJavascript\x12@\n" +
+	"\aheaders\x18\n" +
+	" \x03(\v2&.crawler.v1.SubmitRequest.HeadersEntryR\aheaders\x12@\n" +
+	"\acookies\x18\v \x03(\v2&.crawler.v1.SubmitRequest.CookiesEntryR\acookies\x12\x16\n" +
+	"\x06device\x18\f \x01(\tR\x06device\x12\x18\n" +
+	"\aextract\x18\r \x03(\tR\aextract\x12=\n" +
+	"\fjob_deadline\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\vjobDeadline\x129\n" +
+	"\n" +
+	"not_before\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\tnotBefore\x12\x1f\n" +
+	"\vschema_type\x18\x10 \x01(\tR\n" +
+	"schemaType\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a:\n" +
+	"\fCookiesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"z\n" +
+	"\tEmulation\x129\n" +
+	"\vgeolocation\x18\x01 \x01(\v2\x17.crawler.v1.GeolocationR\vgeolocation\x12\x1a\n" +
+	"\btimezone\x18\x02 \x01(\tR\btimezone\x12\x16\n" +
+	"\x06locale\x18\x03 \x01(\tR\x06locale\"c\n" +
+	"\vGeolocation\x12\x1a\n" +
+	"\blatitude\x18\x01 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x02 \x01(\x01R\tlongitude\x12\x1a\n" +
+	"\baccuracy\x18\x03 \x01(\x01R\baccuracy\"\x88\x01\n" +
+	"\x0eSubmitResponse\x12(\n" +
+	"\x10crawl_request_id\x18\x01 \x01(\tR\x0ecrawlRequestId\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x122\n" +
+	"\aresults\x18\x03 \x03(\v2\x18.crawler.v1.SubmitResultR\aresults\"\xe8\x01\n" +
+	"\fSubmitResult\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12%\n" +
+	"\x0equeue_position\x18\x04 \x01(\x05R\rqueuePosition\x12$\n" +
+	"\veta_seconds\x18\x05 \x01(\x01H\x00R\n" +
+	"etaSeconds\x88\x01\x01\x12;\n" +
+	"\veligible_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"eligibleAtB\x0e\n" +
+	"\f_eta_seconds\"$\n" +
+	"\x10GetStatusRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\"\xf4\x03\n" +
+	"\vCrawlStatus\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1f\n" +
+	"\vfail_reason\x18\x03 \x01(\tR\n" +
+	"failReason\x129\n" +
+	"\n" +
+	"updated_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12'\n" +
+	"\x0ffail_screenshot\x18\x05 \x01(\tR\x0efailScreenshot\x12%\n" +
+	"\x0eschema_version\x18\x06 \x01(\x05R\rschemaVersion\x12\x1f\n" +
+	"\vretry_count\x18\a \x01(\x03R\n" +
+	"retryCount\x12\x1f\n" +
+	"\vmax_retries\x18\b \x01(\x05R\n" +
+	"maxRetries\x12>\n" +
+	"\rnext_retry_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\vnextRetryAt\x12#\n" +
+	"\rrequest_count\x18\n" +
+	" \x01(\x05R\frequestCount\x12+\n" +
+	"\x11bytes_transferred\x18\v \x01(\x03R\x10bytesTransferred\x12;\n" +
+	"\veligible_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"eligibleAt\".\n" +
+	"\x12WatchEventsRequest\x12\x18\n" +
+	"\adomains\x18\x01 \x03(\tR\adomains\"\x94\x01\n" +
+	"\n" +
+	"CrawlEvent\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\x12\x18\n" +
+	"\aoutcome\x18\x03 \x01(\tR\aoutcome\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12*\n" +
+	"\x02at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x02at2\xd7\x01\n" +
+	"\aCrawler\x12?\n" +
+	"\x06Submit\x12\x19.crawler.v1.SubmitRequest\x1a\x1a.crawler.v1.SubmitResponse\x12B\n" +
+	"\tGetStatus\x12\x1c.crawler.v1.GetStatusRequest\x1a\x17.crawler.v1.CrawlStatus\x12G\n" +
+	"\vWatchEvents\x12\x1e.crawler.v1.WatchEventsRequest\x1a\x16.crawler.v1.CrawlEvent0\x01B Z\x1ecrawler/internal/api/crawlerpbb\x06proto3"
+
+var (
+	file_crawler_proto_rawDescOnce sync.Once
+	file_crawler_proto_rawDescData []byte
+)
+
+func file_crawler_proto_rawDescGZIP() []byte {
+	file_crawler_proto_rawDescOnce.Do(func() {
+		file_crawler_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_crawler_proto_rawDesc), len(file_crawler_proto_rawDesc)))
+	})
+	return file_crawler_proto_rawDescData
+}
+
+var file_crawler_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_crawler_proto_goTypes = []any{
+	(*SubmitRequest)(nil),         // 0: crawler.v1.SubmitRequest
+	(*Emulation)(nil),             // 1: crawler.v1.Emulation
+	(*Geolocation)(nil),           // 2: crawler.v1.Geolocation
+	(*SubmitResponse)(nil),        // 3: crawler.v1.SubmitResponse
+	(*SubmitResult)(nil),          // 4: crawler.v1.SubmitResult
+	(*GetStatusRequest)(nil),      // 5: crawler.v1.GetStatusRequest
+	(*CrawlStatus)(nil),           // 6: crawler.v1.CrawlStatus
+	(*WatchEventsRequest)(nil),    // 7: crawler.v1.WatchEventsRequest
+	(*CrawlEvent)(nil),            // 8: crawler.v1.CrawlEvent
+	nil,                           // 9: crawler.v1.SubmitRequest.HeadersEntry
+	nil,                           // 10: crawler.v1.SubmitRequest.CookiesEntry
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+}
+var file_crawler_proto_depIdxs = []int32{
+	1,  // 0: crawler.v1.SubmitRequest.emulation:type_name -> crawler.v1.Emulation
+	9,  // 1: crawler.v1.SubmitRequest.headers:type_name -> crawler.v1.SubmitRequest.HeadersEntry
+	10, // 2: crawler.v1.SubmitRequest.cookies:type_name -> crawler.v1.SubmitRequest.CookiesEntry
+	11, // 3: crawler.v1.SubmitRequest.job_deadline:type_name -> google.protobuf.Timestamp
+	11, // 4: crawler.v1.SubmitRequest.not_before:type_name -> google.protobuf.Timestamp
+	2,  // 5: crawler.v1.Emulation.geolocation:type_name -> crawler.v1.Geolocation
+	4,  // 6: crawler.v1.SubmitResponse.results:type_name -> crawler.v1.SubmitResult
+	11, // 7: crawler.v1.SubmitResult.eligible_at:type_name -> google.protobuf.Timestamp
+	11, // 8: crawler.v1.CrawlStatus.updated_at:type_name -> google.protobuf.Timestamp
+	11, // 9: crawler.v1.CrawlStatus.next_retry_at:type_name -> google.protobuf.Timestamp
+	11, // 10: crawler.v1.CrawlStatus.eligible_at:type_name -> google.protobuf.Timestamp
+	11, // 11: crawler.v1.CrawlEvent.at:type_name -> google.protobuf.Timestamp
+	0,  // 12: crawler.v1.Crawler.Submit:input_type -> crawler.v1.SubmitRequest
+	5,  // 13: crawler.v1.Crawler.GetStatus:input_type -> crawler.v1.GetStatusRequest
+	7,  // 14: crawler.v1.Crawler.WatchEvents:input_type -> crawler.v1.WatchEventsRequest
+	3,  // 15: crawler.v1.Crawler.Submit:output_type -> crawler.v1.SubmitResponse
+	6,  // 16: crawler.v1.Crawler.GetStatus:output_type -> crawler.v1.CrawlStatus
+	8,  // 17: crawler.v1.Crawler.WatchEvents:output_type -> crawler.v1.CrawlEvent
+	15, // [15:18] is the sub-list for method output_type
+	12, // [12:15] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_crawler_proto_init() }
+func file_crawler_proto_init() {
+	if File_crawler_proto != nil {
+		return
+	}
+	file_crawler_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_crawler_proto_rawDesc), len(file_crawler_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_crawler_proto_goTypes,
+		DependencyIndexes: file_crawler_proto_depIdxs,
+		MessageInfos:      file_crawler_proto_msgTypes,
+	}.Build()
+	File_crawler_proto = out.File
+	file_crawler_proto_goTypes = nil
+	file_crawler_proto_depIdxs = nil
+}
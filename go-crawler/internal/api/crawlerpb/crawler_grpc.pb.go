@@ -0,0 +1,217 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: crawler.proto
+
+package crawlerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Crawler_Submit_FullMethodName      = "/crawler.v1.Crawler/Submit"
+	Crawler_GetStatus_FullMethodName   = "/crawler.v1.Crawler/GetStatus"
+	Crawler_WatchEvents_FullMethodName = "/crawler.v1.Crawler/WatchEvents"
+)
+
+// CrawlerClient is the client API for Crawler service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Crawler is the gRPC counterpart of the REST API, for internal clients.
+// Calls must carry "authorization: Bearer <ADMIN_TOKEN>" metadata when an
+// admin token is configured.
+type CrawlerClient interface {
+	// Submit enqueues URLs for crawling, like POST /api/crawl.
+	Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error)
+	// GetStatus returns the crawl status of a URL, like GET /api/status.
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*CrawlStatus, error)
+	// WatchEvents streams crawl outcomes as they happen, optionally limited to
+	// some domains. Events of a client that falls behind are dropped.
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CrawlEvent], error)
+}
+
+type crawlerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCrawlerClient(cc grpc.ClientConnInterface) CrawlerClient {
+	return &crawlerClient{cc}
+}
+
+func (c *crawlerClient) Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitResponse)
+	err := c.cc.Invoke(ctx, Crawler_Submit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crawlerClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*CrawlStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CrawlStatus)
+	err := c.cc.Invoke(ctx, Crawler_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crawlerClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CrawlEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Crawler_ServiceDesc.Streams[0], Crawler_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, CrawlEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Crawler_WatchEventsClient = grpc.ServerStreamingClient[CrawlEvent]
+
+// CrawlerServer is the server API for Crawler service.
+// All implementations must embed UnimplementedCrawlerServer
+// for forward compatibility.
+//
+// Crawler is the gRPC counterpart of the REST API, for internal clients.
+// Calls must carry "authorization: Bearer <ADMIN_TOKEN>" metadata when an
+// admin token is configured.
+type CrawlerServer interface {
+	// Submit enqueues URLs for crawling, like POST /api/crawl.
+	Submit(context.Context, *SubmitRequest) (*SubmitResponse, error)
+	// GetStatus returns the crawl status of a URL, like GET /api/status.
+	GetStatus(context.Context, *GetStatusRequest) (*CrawlStatus, error)
+	// WatchEvents streams crawl outcomes as they happen, optionally limited to
+	// some domains. Events of a client that falls behind are dropped.
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[CrawlEvent]) error
+	mustEmbedUnimplementedCrawlerServer()
+}
+
+// UnimplementedCrawlerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCrawlerServer struct{}
+
+func (UnimplementedCrawlerServer) Submit(context.Context, *SubmitRequest) (*SubmitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Submit not implemented")
+}
+func (UnimplementedCrawlerServer) GetStatus(context.Context, *GetStatusRequest) (*CrawlStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedCrawlerServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[CrawlEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedCrawlerServer) mustEmbedUnimplementedCrawlerServer() {}
+func (UnimplementedCrawlerServer) testEmbeddedByValue()                 {}
+
+// UnsafeCrawlerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CrawlerServer will
+// result in compilation errors.
+type UnsafeCrawlerServer interface {
+	mustEmbedUnimplementedCrawlerServer()
+}
+
+func RegisterCrawlerServer(s grpc.ServiceRegistrar, srv CrawlerServer) {
+	// If the following call pancis, it indicates UnimplementedCrawlerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Crawler_ServiceDesc, srv)
+}
+
+func _Crawler_Submit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlerServer).Submit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Crawler_Submit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlerServer).Submit(ctx, req.(*SubmitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Crawler_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlerServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Crawler_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlerServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Crawler_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrawlerServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, CrawlEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Crawler_WatchEventsServer = grpc.ServerStreamingServer[CrawlEvent]
+
+// Crawler_ServiceDesc is the grpc.ServiceDesc for Crawler service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Crawler_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "crawler.v1.Crawler",
+	HandlerType: (*CrawlerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Submit",
+			Handler:    _Crawler_Submit_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _Crawler_GetStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Crawler_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "crawler.proto",
+}
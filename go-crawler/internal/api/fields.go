@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// fieldsParam parses the ?fields= projection, e.g. "url,title,images". It
+// returns nil when all fields are wanted.
+func fieldsParam(r *http.Request) map[string]bool {
+	v := r.URL.Query().Get("fields")
+	if v == "" {
+		return nil
+	}
+	fields := make(map[string]bool)
+	for _, f := range strings.Split(v, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields[f] = true
+		}
+	}
+	return fields
+}
+
+// project marshals payload to a JSON object holding only the given top-level
+// fields. Unknown fields are ignored. A nil field set keeps everything.
+func project(payload interface{}, fields map[string]bool) ([]byte, error) {
+	full, err := json.Marshal(payload)
+	if err != nil || fields == nil {
+		return full, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(full, &object); err != nil {
+		return nil, err
+	}
+	for key := range object {
+		if !fields[key] {
+			delete(object, key)
+		}
+	}
+	return json.Marshal(object)
+}
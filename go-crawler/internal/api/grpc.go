@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"crawler/internal/api/crawlerpb"
+	"crawler/internal/crawler"
+	"crawler/internal/domain"
+	"fmt"
+	"net"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcService implements the gRPC API on top of the same crawler and stores
+// as the REST handlers.
+type grpcService struct {
+	crawlerpb.UnimplementedCrawlerServer
+	s *Server
+}
+
+// newGRPCServer creates the gRPC server with the API registered.
+func (s *Server) newGRPCServer() *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(s.grpcUnaryAuth),
+		grpc.StreamInterceptor(s.grpcStreamAuth),
+	)
+	crawlerpb.RegisterCrawlerServer(server, &grpcService{s: s})
+	return server
+}
+
+// StartGRPC serves the gRPC API on GRPC_PORT until Shutdown, returning nil
+// once stopped, including when Shutdown came first.
+func (s *Server) StartGRPC() error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", s.config.GRPCPort))
+	if err != nil {
+		return err
+	}
+	if err := s.grpcServer.Serve(lis); err != grpc.ErrServerStopped {
+		return err
+	}
+	return nil
+}
+
+// grpcAuthorize checks the authorization metadata of a call against
+// ADMIN_TOKEN, the same way requireAdmin checks the Authorization header.
+// Unlike admin routes, calls are let through when no token is configured.
+func (s *Server) grpcAuthorize(ctx context.Context) error {
+	if s.config.AdminToken == "" {
+		return nil
+	}
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	if err := s.checkAdminToken(authorization); err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	return nil
+}
+
+func (s *Server) grpcUnaryAuth(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.grpcAuthorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) grpcStreamAuth(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.grpcAuthorize(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (g *grpcService) Submit(ctx context.Context, in *crawlerpb.SubmitRequest) (*crawlerpb.SubmitResponse, error) {
+	req := domain.CrawlRequest{
+		URLs:                 in.GetUrls(),
+		ForceCrawl:           in.GetForceCrawl(),
+		Referer:              in.GetReferer(),
+		SPANavigation:        in.GetSpaNavigation(),
+		RemoveConsentBanners: in.GetRemoveConsentBanners(),
+		FollowHreflang:       in.GetFollowHreflang(),
+		AutoScroll:           in.GetAutoScroll(),
+		DisableJavaScript:    in.GetDisableJavascript(),
+		Device:               in.GetDevice(),
+		Extract:              in.GetExtract(),
+		SchemaType:           in.GetSchemaType(),
+		Headers:              in.GetHeaders(),
+		Cookies:              in.GetCookies(),
+	}
+	if in.GetJobDeadline() != nil {
+		deadline := in.GetJobDeadline().AsTime()
+		req.JobDeadline = &deadline
+	}
+	if in.GetNotBefore() != nil {
+		notBefore := in.GetNotBefore().AsTime()
+		req.NotBefore = &notBefore
+	}
+	if e := in.GetEmulation(); e != nil {
+		req.Emulation = &domain.Emulation{Timezone: e.GetTimezone(), Locale: e.GetLocale()}
+		if geo := e.GetGeolocation(); geo != nil {
+			req.Emulation.Geolocation = &domain.Geolocation{
+				Latitude:  geo.GetLatitude(),
+				Longitude: geo.GetLongitude(),
+				Accuracy:  geo.GetAccuracy(),
+			}
+		}
+	}
+	if err := g.s.validateCrawlRequest(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Per-URL outcomes are in the results, so even a batch with no accepted
+	// URLs is answered rather than failed
+	resp, _ := g.s.submitURLs(req, fmt.Sprintf("grpc-%06d", middleware.NextRequestID()))
+	out := &crawlerpb.SubmitResponse{
+		CrawlRequestId: resp.CrawlRequestID,
+		Message:        resp.Message,
+		Results:        make([]*crawlerpb.SubmitResult, 0, len(resp.Results)),
+	}
+	for _, r := range resp.Results {
+		result := &crawlerpb.SubmitResult{
+			Url:           r.URL,
+			Status:        r.Status,
+			Error:         r.Error,
+			QueuePosition: int32(r.QueuePosition),
+			EtaSeconds:    r.ETASeconds,
+		}
+		if r.EligibleAt != nil {
+			result.EligibleAt = timestamppb.New(*r.EligibleAt)
+		}
+		out.Results = append(out.Results, result)
+	}
+	return out, nil
+}
+
+func (g *grpcService) GetStatus(ctx context.Context, in *crawlerpb.GetStatusRequest) (*crawlerpb.CrawlStatus, error) {
+	if in.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "URL is required")
+	}
+	st, err := g.s.crawlStatus(ctx, g.s.crawler.NormalizeURL(in.GetUrl(), false), "")
+	if err != nil {
+		if err.Error() == "not_found" {
+			return nil, status.Error(codes.NotFound, "URL status not found")
+		}
+		return nil, status.Error(codes.Internal, "Could not retrieve status")
+	}
+	out := &crawlerpb.CrawlStatus{
+		Url:              st.URL,
+		Status:           st.Status,
+		FailReason:       st.FailReason,
+		UpdatedAt:        timestamppb.New(st.UpdatedAt),
+		FailScreenshot:   st.FailScreenshot,
+		SchemaVersion:    int32(st.SchemaVersion),
+		RetryCount:       st.RetryCount,
+		MaxRetries:       int32(st.MaxRetries),
+		RequestCount:     int32(st.RequestCount),
+		BytesTransferred: st.BytesTransferred,
+	}
+	if st.NextRetryAt != nil {
+		out.NextRetryAt = timestamppb.New(*st.NextRetryAt)
+	}
+	if st.EligibleAt != nil {
+		out.EligibleAt = timestamppb.New(*st.EligibleAt)
+	}
+	return out, nil
+}
+
+func (g *grpcService) WatchEvents(in *crawlerpb.WatchEventsRequest, stream grpc.ServerStreamingServer[crawlerpb.CrawlEvent]) error {
+	var domains map[string]bool
+	if len(in.GetDomains()) > 0 {
+		domains = make(map[string]bool, len(in.GetDomains()))
+		for _, d := range in.GetDomains() {
+			domains[d] = true
+		}
+	}
+
+	events, unsubscribe := g.s.crawler.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if domains != nil && !domains[event.Domain] {
+				continue
+			}
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toProtoEvent(event crawler.CrawlEvent) *crawlerpb.CrawlEvent {
+	return &crawlerpb.CrawlEvent{
+		Url:     event.URL,
+		Domain:  event.Domain,
+		Outcome: event.Outcome,
+		Reason:  event.Reason,
+		At:      timestamppb.New(event.At),
+	}
+}
+
+// stopGRPC stops the gRPC server, letting calls in flight finish until ctx is
+// done. Event streams only end when their clients go away, so they are cut off.
+func (s *Server) stopGRPC(ctx context.Context) {
+	if s.grpcServer == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	}
+}
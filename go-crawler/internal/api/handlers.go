@@ -1,101 +1,739 @@
-package api
-
-import (
-	"context"
-	"crawler/internal/domain"
-	"encoding/json"
-	"net/http"
-	"net/url"
-	"time"
-
-	"go.uber.org/zap"
-)
-
-func (s *Server) handleCrawlRequest(w http.ResponseWriter, r *http.Request) {
-	var req domain.CrawlRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.respondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	if len(req.URLs) == 0 {
-		s.respondWithError(w, http.StatusBadRequest, "URLs list cannot be empty")
-		return
-	}
-
-	for _, u := range req.URLs {
-		if _, err := url.ParseRequestURI(u); err != nil {
-			s.respondWithError(w, http.StatusBadRequest, "Invalid URL in list: "+u)
-			return
-		}
-		task := domain.URLTask{URL: u, ForceCrawl: req.ForceCrawl}
-		s.crawler.Submit(task)
-	}
-
-	s.respondWithJSON(w, http.StatusAccepted, map[string]string{"message": "URLs accepted for crawling"})
-}
-
-func (s *Server) handleStatusRequest(w http.ResponseWriter, r *http.Request) {
-	urlParam := r.URL.Query().Get("url")
-	if urlParam == "" {
-		s.respondWithError(w, http.StatusBadRequest, "URL query parameter is required")
-		return
-	}
-
-	status, err := s.pgStore.GetCrawlStatus(r.Context(), urlParam)
-	if err != nil {
-		if err.Error() == "not_found" {
-			s.respondWithError(w, http.StatusNotFound, "URL status not found")
-			return
-		}
-		s.logger.Error("failed to get crawl status", zap.Error(err))
-		s.respondWithError(w, http.StatusInternalServerError, "Could not retrieve status")
-		return
-	}
-
-	s.respondWithJSON(w, http.StatusOK, status)
-}
-
-func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-
-	healthStatus := make(map[string]string)
-
-	// Check Postgres
-	if err := s.pgStore.Ping(ctx); err != nil {
-		healthStatus["postgres"] = "unhealthy"
-		s.logger.Error("health check failed for postgres", zap.Error(err))
-	} else {
-		healthStatus["postgres"] = "healthy"
-	}
-
-	// Check Redis
-	if err := s.redisStore.Ping(ctx); err != nil {
-		healthStatus["redis"] = "unhealthy"
-		s.logger.Error("health check failed for redis", zap.Error(err))
-	} else {
-		healthStatus["redis"] = "healthy"
-	}
-
-	isHealthy := healthStatus["postgres"] == "healthy" && healthStatus["redis"] == "healthy"
-	if !isHealthy {
-		s.respondWithJSON(w, http.StatusServiceUnavailable, healthStatus)
-		return
-	}
-
-	s.respondWithJSON(w, http.StatusOK, healthStatus)
-}
-
-// --- Helper Functions ---
-
-func (s *Server) respondWithError(w http.ResponseWriter, code int, message string) {
-	s.respondWithJSON(w, code, map[string]string{"error": message})
-}
-
-func (s *Server) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-	response, _ := json.Marshal(payload)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write(response)
-}
+package api
+
+import (
+	"context"
+	"crawler/internal/config"
+	"crawler/internal/crawler"
+	"crawler/internal/domain"
+	"crawler/internal/storage"
+	"crawler/pkg/extract"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+func (s *Server) handleCrawlRequest(w http.ResponseWriter, r *http.Request) {
+	var req domain.CrawlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := s.validateCrawlRequest(req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Retried submissions with the same Idempotency-Key get the original response
+	idemKey, answered := s.replaySubmission(w, r)
+	if answered {
+		return
+	}
+
+	resp, status := s.submitURLs(req, middleware.GetReqID(r.Context()))
+	if idemKey != "" {
+		s.saveSubmission(r, idemKey, status, resp)
+	}
+	s.respondWithJSON(w, status, resp)
+}
+
+// validateCrawlRequest checks the options of a crawl submission, returning
+// the message of the error response when they are invalid.
+func (s *Server) validateCrawlRequest(req domain.CrawlRequest) error {
+	if len(req.URLs) == 0 {
+		return errors.New("URLs list cannot be empty")
+	}
+	if req.Referer != "" {
+		if _, err := url.ParseRequestURI(req.Referer); err != nil {
+			return errors.New("Invalid referer URL")
+		}
+	}
+	if err := crawler.ValidateEmulation(req.Emulation); err != nil {
+		return errors.New("Invalid emulation: " + err.Error())
+	}
+	if err := crawler.ValidateDevice(req.Device); err != nil {
+		return errors.New("Invalid device: " + err.Error())
+	}
+	if err := extract.ValidateExtractors(req.Extract); err != nil {
+		return errors.New("Invalid extract list: " + err.Error())
+	}
+	if err := extract.ValidateSchemaType(req.SchemaType); err != nil {
+		return errors.New("Invalid schema_type: " + err.Error())
+	}
+	if req.JobDeadline != nil && !req.JobDeadline.After(time.Now()) {
+		return errors.New("job_deadline must be in the future")
+	}
+	if req.NotBefore != nil {
+		if !req.NotBefore.After(time.Now()) {
+			return errors.New("not_before must be in the future")
+		}
+		horizon := time.Duration(s.config.ScheduleHorizon) * time.Second
+		if horizon > 0 && req.NotBefore.After(time.Now().Add(horizon)) {
+			return fmt.Errorf("not_before must be within %s", horizon)
+		}
+		if req.JobDeadline != nil && !req.JobDeadline.After(*req.NotBefore) {
+			return errors.New("job_deadline must be after not_before")
+		}
+	}
+	if err := (config.DomainHeaders{Headers: req.Headers, Cookies: req.Cookies}).Validate(); err != nil {
+		return errors.New("Invalid headers: " + err.Error())
+	}
+	return nil
+}
+
+// submitURLs enqueues the URLs of a validated crawl request, returning the
+// per-URL results and the HTTP status summing them up.
+func (s *Server) submitURLs(req domain.CrawlRequest, requestID string) (domain.SubmitCrawlResponse, int) {
+	// Valid URLs are accepted even when others in the batch are rejected
+	resp := domain.SubmitCrawlResponse{
+		CrawlRequestID: requestID,
+		Results:        make([]domain.SubmitResult, 0, len(req.URLs)),
+	}
+	var jobID string
+	if req.JobDeadline != nil {
+		jobID = requestID
+		s.crawler.CreateJob(jobID, *req.JobDeadline)
+	}
+	accepted, duplicates := 0, 0
+	firstSeen := make(map[string]string, len(req.URLs)) // Normalized URL to its first occurrence
+	for _, u := range req.URLs {
+		normalized := s.crawler.NormalizeURL(u, req.SPANavigation)
+		if first, ok := firstSeen[normalized]; ok {
+			resp.Results = append(resp.Results, domain.SubmitResult{URL: u, Status: "duplicate_in_batch", Error: "same URL as " + first})
+			duplicates++
+			continue
+		}
+		firstSeen[normalized] = u
+
+		task := domain.URLTask{
+			URL:                  u,
+			ForceCrawl:           req.ForceCrawl,
+			Referer:              req.Referer,
+			SPANavigation:        req.SPANavigation,
+			RemoveConsentBanners: req.RemoveConsentBanners,
+			Emulation:            req.Emulation,
+			Device:               req.Device,
+			Extract:              req.Extract,
+			SchemaType:           req.SchemaType,
+			JobID:                jobID,
+			FollowHreflang:       req.FollowHreflang,
+			AutoScroll:           req.AutoScroll,
+			DisableJavaScript:    req.DisableJavaScript,
+			Headers:              req.Headers,
+			Cookies:              req.Cookies,
+		}
+		if req.NotBefore != nil {
+			if err := s.crawler.Schedule(task, *req.NotBefore); err != nil {
+				resp.Results = append(resp.Results, domain.SubmitResult{URL: u, Status: "rejected", Error: err.Error()})
+				continue
+			}
+			resp.Results = append(resp.Results, domain.SubmitResult{URL: u, Status: "scheduled", EligibleAt: req.NotBefore})
+			accepted++
+			continue
+		}
+		position, err := s.crawler.Submit(task)
+		if errors.Is(err, crawler.ErrAlreadyQueued) {
+			resp.Results = append(resp.Results, domain.SubmitResult{URL: u, Status: "already_queued"})
+			duplicates++
+			continue
+		}
+		if err != nil {
+			resp.Results = append(resp.Results, domain.SubmitResult{URL: u, Status: "rejected", Error: err.Error()})
+			continue
+		}
+		result := domain.SubmitResult{URL: u, Status: "accepted", QueuePosition: position}
+		if eta, ok := s.crawler.EstimateWait(position); ok {
+			seconds := math.Round(eta.Seconds())
+			result.ETASeconds = &seconds
+		}
+		resp.Results = append(resp.Results, result)
+		accepted++
+	}
+
+	var status int
+	switch {
+	case duplicates == len(req.URLs):
+		// Nothing failed, but nothing new was enqueued either
+		resp.Message = "No URLs were enqueued, all are already queued"
+		status = http.StatusOK
+	case accepted == len(req.URLs)-duplicates:
+		resp.Message = "URLs accepted for crawling"
+		status = http.StatusAccepted
+	case accepted == 0:
+		resp.Message = "No URLs were accepted"
+		status = http.StatusBadRequest
+	default:
+		resp.Message = "Some URLs were rejected"
+		status = http.StatusMultiStatus
+	}
+	return resp, status
+}
+
+// deviceParam returns the device query parameter, which selects the record
+// of a URL crawled with that device preset, answering 400 when it names none.
+func (s *Server) deviceParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	device := r.URL.Query().Get("device")
+	if err := crawler.ValidateDevice(device); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "Invalid device: "+err.Error())
+		return "", false
+	}
+	return device, true
+}
+
+func (s *Server) handleStatusRequest(w http.ResponseWriter, r *http.Request) {
+	urlParam := r.URL.Query().Get("url")
+	if urlParam == "" {
+		s.respondWithError(w, http.StatusBadRequest, "URL query parameter is required")
+		return
+	}
+	urlParam = s.crawler.NormalizeURL(urlParam, false)
+	device, ok := s.deviceParam(w, r)
+	if !ok {
+		return
+	}
+
+	var maxAge time.Duration
+	if v := r.URL.Query().Get("max_age_seconds"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			s.respondWithError(w, http.StatusBadRequest, "max_age_seconds must be a positive integer")
+			return
+		}
+		maxAge = time.Duration(seconds) * time.Second
+	}
+
+	status, err := s.crawlStatus(r.Context(), urlParam, device)
+	if err != nil {
+		if err.Error() == "not_found" {
+			s.respondWithError(w, http.StatusNotFound, "URL status not found")
+			return
+		}
+		s.respondWithError(w, http.StatusInternalServerError, "Could not retrieve status")
+		return
+	}
+
+	// A URL that is still being crawled, or about to be, is never considered stale
+	if maxAge > 0 && status.Status != "processing" && status.Status != "scheduled" && time.Since(status.UpdatedAt) > maxAge {
+		status.Stale = true
+		if r.URL.Query().Get("recrawl_if_stale") == "true" {
+			if err := s.crawler.TrySubmit(domain.URLTask{URL: urlParam, Device: device, ForceCrawl: true}); err != nil && !errors.Is(err, crawler.ErrAlreadyQueued) {
+				s.logger.Warn("failed to enqueue stale URL for recrawl", zap.String("url", urlParam), zap.Error(err))
+			} else {
+				status.RecrawlQueued = true
+			}
+		}
+	}
+
+	s.respondWithValidators(w, r, status, "", status.UpdatedAt)
+}
+
+// crawlStatus looks up the status of a normalized URL crawled with a device
+// preset, if any, along with where it is in its retry lifecycle. A URL held
+// in the schedule queue until a later time is reported as scheduled, even
+// before it has a record.
+func (s *Server) crawlStatus(ctx context.Context, url, device string) (*domain.CrawlStatusResponse, error) {
+	key := storage.PageKey(url, device)
+	status, err := s.pageStore.GetCrawlStatus(ctx, key)
+	if err != nil && err.Error() != "not_found" {
+		s.logger.Error("failed to get crawl status", zap.Error(err))
+		return nil, err
+	}
+	notFound := err != nil
+
+	retryCount, nextRetryAt, err := s.stateStore.RetryInfo(ctx, key)
+	if err != nil {
+		s.logger.Warn("failed to get retry info", zap.String("url", url), zap.Error(err))
+	}
+	eligibleAt, err := s.stateStore.TaskScheduledAt(ctx, key)
+	if err != nil {
+		s.logger.Warn("failed to get schedule info", zap.String("url", url), zap.Error(err))
+	}
+	scheduled := eligibleAt.After(time.Now())
+	if notFound {
+		if nextRetryAt.IsZero() && !scheduled {
+			return nil, fmt.Errorf("not_found")
+		}
+		status = &domain.CrawlStatusResponse{URL: url, Device: device}
+	}
+	status.RetryCount = retryCount
+	status.MaxRetries = s.config.MaxRetries
+	if !nextRetryAt.IsZero() {
+		status.NextRetryAt = &nextRetryAt
+	}
+	if scheduled && status.Status != "processing" {
+		status.Status = "scheduled"
+		status.EligibleAt = &eligibleAt
+	}
+	return status, nil
+}
+
+// handlePageRequest is a read-through cache: it returns stored data when it is
+// fresh enough, and otherwise crawls the URL and waits for the result.
+func (s *Server) handlePageRequest(w http.ResponseWriter, r *http.Request) {
+	urlParam := r.URL.Query().Get("url")
+	if urlParam == "" {
+		s.respondWithError(w, http.StatusBadRequest, "URL query parameter is required")
+		return
+	}
+	urlParam = s.crawler.NormalizeURL(urlParam, false)
+	device, ok := s.deviceParam(w, r)
+	if !ok {
+		return
+	}
+	key := storage.PageKey(urlParam, device)
+
+	var maxAge time.Duration
+	if v := r.URL.Query().Get("max_age"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			s.respondWithError(w, http.StatusBadRequest, "max_age must be a positive number of seconds")
+			return
+		}
+		maxAge = time.Duration(seconds) * time.Second
+	}
+	var explain bool
+	if v := r.URL.Query().Get("explain"); v != "" {
+		var err error
+		if explain, err = strconv.ParseBool(v); err != nil {
+			s.respondWithError(w, http.StatusBadRequest, "explain must be true or false")
+			return
+		}
+	}
+
+	// Stored records carry no explanation, so explaining always crawls
+	if !explain {
+		data, err := s.pageStore.GetPageData(r.Context(), key)
+		if err != nil && err.Error() != "not_found" {
+			s.logger.Error("failed to get page data", zap.String("url", urlParam), zap.Error(err))
+			s.respondWithError(w, http.StatusInternalServerError, "Could not retrieve page data")
+			return
+		}
+		if err == nil && data.Status == "completed" && (maxAge == 0 || time.Since(data.CrawledAt) <= maxAge) {
+			s.respondWithValidators(w, r, data, data.ContentHash, data.CrawledAt)
+			return
+		}
+	}
+
+	submittedAt := time.Now()
+	// An already queued crawl is waited for like our own
+	err := s.crawler.TrySubmit(domain.URLTask{URL: urlParam, Device: device, ForceCrawl: true, Explain: explain})
+	switch {
+	case errors.Is(err, crawler.ErrQueueFull), errors.Is(err, crawler.ErrStopping):
+		w.Header().Set("Retry-After", "10")
+		s.respondWithError(w, http.StatusServiceUnavailable, "Could not queue the crawl: "+err.Error())
+		return
+	case err != nil && !errors.Is(err, crawler.ErrAlreadyQueued):
+		s.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wait := time.Duration(s.config.PageWaitTimeout) * time.Second
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(wait + 10*time.Second))
+
+	status, err := s.waitForCrawl(r.Context(), key, submittedAt, wait)
+	switch {
+	case err != nil:
+		statusURL := "/api/status?url=" + url.QueryEscape(urlParam)
+		if device != "" {
+			statusURL += "&device=" + url.QueryEscape(device)
+		}
+		s.respondWithJSON(w, http.StatusAccepted, map[string]string{
+			"message":    "Crawl is still in progress",
+			"status_url": statusURL,
+		})
+	case explain:
+		s.respondExplained(w, r, key, status)
+	case status.Status == "failed":
+		s.respondWithError(w, http.StatusBadGateway, "Crawl failed: "+status.FailReason)
+	case status.Status == "skipped":
+		s.respondWithError(w, http.StatusUnprocessableEntity, "Crawl skipped: "+status.FailReason)
+	default:
+		data, err := s.pageStore.GetPageData(r.Context(), key)
+		if err != nil {
+			s.logger.Error("failed to get page data", zap.String("url", urlParam), zap.Error(err))
+			s.respondWithError(w, http.StatusInternalServerError, "Could not retrieve page data")
+			return
+		}
+		s.respondWithValidators(w, r, data, data.ContentHash, data.CrawledAt)
+	}
+}
+
+// respondExplained answers an explained page request whose crawl of the page
+// of url, a storage.PageKey, finished with the page, or the reason it has
+// none, along with the crawl's explanation.
+func (s *Server) respondExplained(w http.ResponseWriter, r *http.Request, url string, status *domain.CrawlStatusResponse) {
+	// The crawl's status is saved just before its explanation is complete
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	response := domain.ExplainedPage{Explain: s.crawler.Explanation(ctx, url)}
+	if response.Explain == nil {
+		response.Explain = &domain.CrawlExplanation{
+			Warnings: []string{"no explanation was recorded, as the URL was crawled for a request without explain"},
+		}
+	}
+
+	switch status.Status {
+	case "failed":
+		response.Error = "Crawl failed: " + status.FailReason
+		s.respondWithJSON(w, http.StatusBadGateway, response)
+	case "skipped":
+		response.Error = "Crawl skipped: " + status.FailReason
+		s.respondWithJSON(w, http.StatusUnprocessableEntity, response)
+	default:
+		data, err := s.pageStore.GetPageData(r.Context(), url)
+		if err != nil {
+			s.logger.Error("failed to get page data", zap.String("url", url), zap.Error(err))
+			s.respondWithError(w, http.StatusInternalServerError, "Could not retrieve page data")
+			return
+		}
+		response.Page = data
+		s.respondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// waitForCrawl polls the status of the page of url, a storage.PageKey, until
+// a crawl submitted at submittedAt has finished, or the wait times out.
+func (s *Server) waitForCrawl(ctx context.Context, url string, submittedAt time.Time, wait time.Duration) (*domain.CrawlStatusResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+			status, err := s.pageStore.GetCrawlStatus(ctx, url)
+			if err != nil {
+				continue
+			}
+			if status.Status != "processing" && status.UpdatedAt.After(submittedAt) {
+				return status, nil
+			}
+		}
+	}
+}
+
+func (s *Server) handleReprocessRequest(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("below_schema_version") {
+		s.handleReprocessOutdated(w, r)
+		return
+	}
+
+	urlParam := r.URL.Query().Get("url")
+	if urlParam == "" {
+		s.respondWithError(w, http.StatusBadRequest, "URL query parameter is required")
+		return
+	}
+	urlParam = s.crawler.NormalizeURL(urlParam, false)
+	device, ok := s.deviceParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.crawler.Reprocess(r.Context(), storage.PageKey(urlParam, device)); err != nil {
+		if err.Error() == "not_found" {
+			s.respondWithError(w, http.StatusNotFound, "No archived HTML found for URL")
+			return
+		}
+		s.logger.Error("failed to reprocess URL", zap.String("url", urlParam), zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not reprocess URL")
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"message": "URL reprocessed"})
+}
+
+// maxReprocessBatch bounds a batch reprocess so it finishes within the request timeout.
+const maxReprocessBatch = 500
+
+// handleReprocessOutdated reprocesses a batch of records older than a schema version.
+func (s *Server) handleReprocessOutdated(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(r.URL.Query().Get("below_schema_version"))
+	if err != nil || version <= 0 {
+		s.respondWithError(w, http.StatusBadRequest, "below_schema_version must be a positive integer")
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxReprocessBatch {
+			s.respondWithError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxReprocessBatch))
+			return
+		}
+	}
+
+	count, err := s.crawler.ReprocessBelowVersion(r.Context(), version, limit)
+	if err != nil {
+		s.logger.Error("failed to reprocess outdated pages", zap.Int("below_schema_version", version), zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not reprocess outdated pages")
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, map[string]int{"reprocessed": count})
+}
+
+func (s *Server) handleExportRequest(w http.ResponseWriter, r *http.Request) {
+	domainParam := strings.ToLower(r.URL.Query().Get("domain"))
+	if domainParam == "" {
+		s.respondWithError(w, http.StatusBadRequest, "domain query parameter is required")
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.respondWithError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+		since = t
+	}
+
+	// Lift the server's write timeout for this response
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	fields := fieldsParam(r)
+	exported := 0
+	err := s.pageStore.ExportDomain(r.Context(), domainParam, since, func(data *domain.PageData) error {
+		line, err := project(data, fields)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		exported++
+		if exported%100 == 0 {
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent, so all we can do is log and cut the stream short
+		s.logger.Error("export failed", zap.String("domain", domainParam), zap.Int("exported", exported), zap.Error(err))
+		return
+	}
+	_ = rc.Flush()
+}
+
+// handleDomainsRequest returns a per-domain rollup, with the last crawl time
+// of each domain to spot domains whose crawls have stalled.
+func (s *Server) handleDomainsRequest(w http.ResponseWriter, r *http.Request) {
+	summaries, err := s.pageStore.DomainSummaries(r.Context())
+	if err != nil {
+		s.logger.Error("failed to get domain summaries", zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not retrieve domains")
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, summaries)
+}
+
+// maxDuplicateGroups bounds the duplicate groups returned in one response.
+const maxDuplicateGroups = 1000
+
+// handleDuplicatesRequest lists pages stored as duplicates of another page's
+// content, grouped by that page, optionally for a single domain.
+func (s *Server) handleDuplicatesRequest(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxDuplicateGroups {
+			s.respondWithError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxDuplicateGroups))
+			return
+		}
+	}
+	domainParam := strings.ToLower(r.URL.Query().Get("domain"))
+
+	groups, err := s.pageStore.DuplicateGroups(r.Context(), domainParam, limit)
+	if err != nil {
+		s.logger.Error("failed to get duplicate groups", zap.String("domain", domainParam), zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not retrieve duplicates")
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, groups)
+}
+
+// handleRecrawlRequest re-enqueues all previously crawled URLs of a domain.
+// Domains larger than RECRAWL_MAX_URLS need confirm=true.
+func (s *Server) handleRecrawlRequest(w http.ResponseWriter, r *http.Request) {
+	domainParam := strings.ToLower(r.URL.Query().Get("domain"))
+	if domainParam == "" {
+		s.respondWithError(w, http.StatusBadRequest, "domain query parameter is required")
+		return
+	}
+
+	count, err := s.pageStore.CountDomainPages(r.Context(), domainParam)
+	if err != nil {
+		s.logger.Error("failed to count domain pages", zap.String("domain", domainParam), zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not recrawl domain")
+		return
+	}
+	if count == 0 {
+		s.respondWithError(w, http.StatusNotFound, "No pages found for domain")
+		return
+	}
+	if count > s.config.RecrawlMaxURLs && r.URL.Query().Get("confirm") != "true" {
+		s.respondWithError(w, http.StatusConflict,
+			fmt.Sprintf("Domain has %d pages, more than the limit of %d; pass confirm=true to recrawl it anyway", count, s.config.RecrawlMaxURLs))
+		return
+	}
+
+	enqueued, err := s.crawler.RecrawlDomain(r.Context(), domainParam)
+	if err != nil {
+		s.logger.Error("failed to recrawl domain", zap.String("domain", domainParam), zap.Int("enqueued", enqueued), zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not recrawl domain")
+		return
+	}
+
+	s.logger.Info("domain queued for recrawl", zap.String("domain", domainParam), zap.Int("enqueued", enqueued))
+	s.respondWithJSON(w, http.StatusAccepted, map[string]int{"enqueued": enqueued})
+}
+
+// handleQueueRestoreRequest replays the queue snapshots into the retry queue,
+// e.g. after Redis lost its data.
+func (s *Server) handleQueueRestoreRequest(w http.ResponseWriter, r *http.Request) {
+	restored, err := s.crawler.RestoreQueue(r.Context())
+	if err != nil {
+		s.logger.Error("failed to restore queue", zap.Int("restored", restored), zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not restore queue")
+		return
+	}
+
+	s.logger.Info("queue restored from snapshot", zap.Int("restored", restored))
+	s.respondWithJSON(w, http.StatusAccepted, map[string]int{"restored": restored})
+}
+
+// handleJobRequest returns the status of a submission made with a job_deadline.
+func (s *Server) handleJobRequest(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.crawler.Job(chi.URLParam(r, "id"))
+	if !ok {
+		s.respondWithError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, job)
+}
+
+// handleSummaryRequest returns the crawler's run summary so far.
+func (s *Server) handleSummaryRequest(w http.ResponseWriter, r *http.Request) {
+	s.respondWithJSON(w, http.StatusOK, s.crawler.Summary(r.Context()))
+}
+
+// handleStatsRequest returns the crawler's current state, including whether
+// crawling is paused.
+func (s *Server) handleStatsRequest(w http.ResponseWriter, r *http.Request) {
+	s.respondWithJSON(w, http.StatusOK, s.crawler.Stats(r.Context()))
+}
+
+// handlePauseRequest stops workers on every instance from taking new tasks,
+// keeping the queue intact; running crawls finish.
+func (s *Server) handlePauseRequest(w http.ResponseWriter, r *http.Request) {
+	if err := s.crawler.Pause(r.Context()); err != nil {
+		s.logger.Error("failed to pause crawling", zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not pause crawling")
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+// handleResumeRequest lets workers take tasks again after a pause.
+func (s *Server) handleResumeRequest(w http.ResponseWriter, r *http.Request) {
+	if err := s.crawler.Resume(r.Context()); err != nil {
+		s.logger.Error("failed to resume crawling", zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not resume crawling")
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+// handleDeleteResultsRequest purges all stored data of a domain, along with
+// its recently-crawled markers so the domain can be crawled again, and its
+// failure screenshots.
+func (s *Server) handleDeleteResultsRequest(w http.ResponseWriter, r *http.Request) {
+	domainParam := strings.ToLower(r.URL.Query().Get("domain"))
+	if domainParam == "" {
+		s.respondWithError(w, http.StatusBadRequest, "domain query parameter is required")
+		return
+	}
+
+	deleted, err := s.crawler.DeleteDomain(r.Context(), domainParam)
+	if err != nil {
+		s.logger.Error("failed to delete domain results", zap.String("domain", domainParam), zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not delete results")
+		return
+	}
+
+	s.logger.Warn("destructive operation: deleted domain results",
+		zap.String("domain", domainParam),
+		zap.Int("deleted", deleted),
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	s.respondWithJSON(w, http.StatusOK, map[string]int{"deleted": deleted})
+}
+
+func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	healthStatus := make(map[string]string)
+
+	// Check the page store, reported under its backend name
+	dataBackend := s.config.DataBackend
+	if err := s.pageStore.Ping(ctx); err != nil {
+		healthStatus[dataBackend] = "unhealthy"
+		s.logger.Error("health check failed for "+dataBackend, zap.Error(err))
+	} else {
+		healthStatus[dataBackend] = "healthy"
+	}
+
+	// Check the state store, reported under its backend name
+	backend := s.config.QueueBackend
+	if err := s.stateStore.Ping(ctx); errors.Is(err, storage.ErrQueueUnavailable) {
+		healthStatus[backend] = "unreachable"
+		s.logger.Error("health check failed for "+backend, zap.Error(err))
+	} else if err != nil {
+		healthStatus[backend] = "unhealthy"
+		s.logger.Error("health check failed for "+backend, zap.Error(err))
+	} else {
+		healthStatus[backend] = "healthy"
+	}
+
+	isHealthy := healthStatus[dataBackend] == "healthy" && healthStatus[backend] == "healthy"
+	if !isHealthy {
+		s.respondWithJSON(w, http.StatusServiceUnavailable, healthStatus)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, healthStatus)
+}
+
+// --- Helper Functions ---
+
+func (s *Server) respondWithError(w http.ResponseWriter, code int, message string) {
+	s.respondWithJSON(w, code, map[string]string{"error": message})
+}
+
+func (s *Server) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, _ := json.Marshal(payload)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
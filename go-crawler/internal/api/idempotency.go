@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyClaimTTL bounds how long a claimed key stays reserved while its
+// submission is in progress, so a request that dies before its response is
+// stored doesn't lock the key for the whole IDEMPOTENCY_TTL.
+const idempotencyClaimTTL = time.Minute
+
+// storedSubmission is the response kept for an Idempotency-Key.
+type storedSubmission struct {
+	Status int                        `json:"status"`
+	Body   domain.SubmitCrawlResponse `json:"body"`
+}
+
+// idempotencyKey scopes a client-supplied key to the caller's credentials, so
+// clients with different API keys can't replay each other's submissions.
+// Unauthenticated callers share one scope.
+func idempotencyKey(r *http.Request, key string) string {
+	scope := sha256.Sum256([]byte(r.Header.Get("Authorization")))
+	return hex.EncodeToString(scope[:8]) + ":" + key
+}
+
+// replaySubmission claims the request's Idempotency-Key. It returns the scoped
+// key to store the response under ("" without a key), and whether the request
+// has already been answered, with the stored response or an error.
+func (s *Server) replaySubmission(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		return "", false
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		s.respondWithError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+		return "", true
+	}
+
+	key = idempotencyKey(r, key)
+	claimed, stored, err := s.stateStore.ClaimIdempotencyKey(r.Context(), key, min(idempotencyClaimTTL, s.idempotencyTTL()))
+	if err != nil {
+		s.logger.Error("failed to claim idempotency key", zap.Error(err))
+		s.respondWithError(w, http.StatusServiceUnavailable, "Could not check Idempotency-Key")
+		return "", true
+	}
+	if claimed {
+		return key, false
+	}
+	if len(stored) == 0 {
+		s.respondWithError(w, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+		return "", true
+	}
+
+	var prev storedSubmission
+	if err := json.Unmarshal(stored, &prev); err != nil {
+		s.logger.Error("failed to decode stored submission", zap.Error(err))
+		s.respondWithError(w, http.StatusInternalServerError, "Could not replay Idempotency-Key")
+		return "", true
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	s.respondWithJSON(w, prev.Status, prev.Body)
+	return "", true
+}
+
+// saveSubmission stores the response of a submission under its claimed key
+// for the full TTL. If that fails the claim is released, so a retry submits
+// again rather than being told the original is still in progress. The
+// submission is done by now, so a client hanging up doesn't cancel this.
+func (s *Server) saveSubmission(r *http.Request, key string, status int, resp domain.SubmitCrawlResponse) {
+	ctx := context.WithoutCancel(r.Context())
+	payload, err := json.Marshal(storedSubmission{Status: status, Body: resp})
+	if err == nil {
+		err = s.stateStore.SaveIdempotentResponse(ctx, key, payload, s.idempotencyTTL())
+	}
+	if err != nil {
+		s.logger.Error("failed to store submission for idempotency key", zap.Error(err))
+		if err := s.stateStore.ReleaseIdempotencyKey(ctx, key); err != nil {
+			s.logger.Error("failed to release idempotency key", zap.Error(err))
+		}
+	}
+}
+
+func (s *Server) idempotencyTTL() time.Duration {
+	return time.Duration(s.config.IdempotencyTTL) * time.Second
+}
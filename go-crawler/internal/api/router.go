@@ -1,30 +1,52 @@
-package api
-
-import (
-	"net/http"
-	"time"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-)
-
-func (s *Server) setupRouter() http.Handler {
-	r := chi.NewRouter()
-
-	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
-
-	r.Get("/metrics", promhttp.Handler().(http.HandlerFunc))
-	r.Get("/api/health", s.handleHealthCheck)
-
-	r.Route("/api", func(r chi.Router) {
-		r.Post("/crawl", s.handleCrawlRequest)
-		r.Get("/status", s.handleStatusRequest)
-	})
-
-	return r
-}
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func (s *Server) setupRouter() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(60 * time.Second))
+
+		r.Get("/metrics", promhttp.Handler().(http.HandlerFunc))
+		r.Get("/api/health", s.handleHealthCheck)
+
+		r.Route("/api", func(r chi.Router) {
+			r.Post("/crawl", s.handleCrawlRequest)
+			r.Get("/status", s.handleStatusRequest)
+			r.Get("/jobs/{id}", s.handleJobRequest)
+			r.Post("/reprocess", s.handleReprocessRequest)
+			r.Get("/domains", s.handleDomainsRequest)
+			r.Get("/duplicates", s.handleDuplicatesRequest)
+			r.Get("/summary", s.handleSummaryRequest)
+			r.Get("/stats", s.handleStatsRequest)
+			r.Post("/recrawl", s.handleRecrawlRequest)
+			r.Post("/queue/restore", s.handleQueueRestoreRequest)
+
+			r.With(s.requireAdmin).Delete("/results", s.handleDeleteResultsRequest)
+			// Open unless ADMIN_TOKEN is set, which they then require
+			r.With(s.requireTokenIfSet).Post("/pause", s.handlePauseRequest)
+			r.With(s.requireTokenIfSet).Post("/resume", s.handleResumeRequest)
+		})
+	})
+
+	// Waits for a crawl on cache misses, bounded by PAGE_WAIT_TIMEOUT instead
+	r.Get("/api/page", s.handlePageRequest)
+
+	// Streaming endpoints may legitimately run longer than the request timeout
+	r.Get("/api/export", s.handleExportRequest)
+
+	return r
+}
@@ -1,40 +1,685 @@
-package config
-
-import (
-	"github.com/spf13/viper"
-)
-
-// Config stores all configuration for the application.
-type Config struct {
-	PostgresURL       string `mapstructure:"POSTGRES_URL"`
-	RedisAddr         string `mapstructure:"REDIS_ADDR"`
-	ServerPort        string `mapstructure:"SERVER_PORT"`
-	MaxRetries        int    `mapstructure:"MAX_RETRIES"`
-	CrawlWorkers      int    `mapstructure:"CRAWL_WORKERS"`
-	CrawlTimeout      int    `mapstructure:"CRAWL_TIMEOUT"`
-	DeduplicationDays int    `mapstructure:"DEDUPLICATION_DAYS"`
-}
-
-// Load reads configuration from file or environment variables.
-func Load() (*Config, error) {
-	viper.SetConfigFile(".env")
-	viper.SetConfigType("env")
-	viper.AutomaticEnv()
-
-	// Attempt to read the .env file, but don't fail if it's not present
-	// This allows configuration purely through environment variables in production
-	_ = viper.ReadInConfig()
-
-	// Set default values
-	viper.SetDefault("SERVER_PORT", "8080")
-	viper.SetDefault("MAX_RETRIES", 2)
-	viper.SetDefault("CRAWL_WORKERS", 10)
-	viper.SetDefault("CRAWL_TIMEOUT", 30) // in seconds
-	viper.SetDefault("DEDUPLICATION_DAYS", 2)
-
-	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, err
-	}
-	return &cfg, nil
-}
+package config
+
+import (
+	"crawler/pkg/extract"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/spf13/viper"
+)
+
+// DomainHeaders are the extra headers and cookies sent when crawling a domain.
+type DomainHeaders struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies map[string]string `json:"cookies,omitempty"`
+}
+
+// LoginFlow describes how to log in to a domain through its login form. The
+// credentials are read from the environment variables named by UsernameEnv and
+// PasswordEnv, so the flows file holds no secrets.
+type LoginFlow struct {
+	URL              string `json:"url"` // Of the login form
+	UsernameSelector string `json:"username_selector"`
+	PasswordSelector string `json:"password_selector"`
+	SubmitSelector   string `json:"submit_selector"`
+	SuccessSelector  string `json:"success_selector"` // Visible once logged in
+	UsernameEnv      string `json:"username_env"`
+	PasswordEnv      string `json:"password_env"`
+	// How long a session lasts when its cookies don't expire, in seconds;
+	// defaults to an hour
+	SessionTTL int `json:"session_ttl,omitempty"`
+	// Only on pages seen while logged out, e.g. a "Sign in" link; a crawled
+	// page matching it means the session expired. Without it, only landing
+	// on the login URL does.
+	LoggedOutSelector string `json:"logged_out_selector,omitempty"`
+
+	Username string `json:"-"`
+	Password string `json:"-"`
+}
+
+// GateMarkers identify the login or paywall gate of a domain: a page matching
+// any of the selectors, or containing any of the texts, is behind the gate.
+type GateMarkers struct {
+	Type      string   `json:"type,omitempty"` // e.g. "login"; defaults to "paywall"
+	Selectors []string `json:"selectors,omitempty"`
+	Texts     []string `json:"texts,omitempty"` // Matched case-insensitively
+}
+
+// Config stores all configuration for the application.
+type Config struct {
+	PostgresURL       string `mapstructure:"POSTGRES_URL"`
+	PostgresSchema    string `mapstructure:"POSTGRES_SCHEMA"`
+	TablePrefix       string `mapstructure:"TABLE_PREFIX"`
+	RedisAddr         string `mapstructure:"REDIS_ADDR"`
+	RedisKeyPrefix    string `mapstructure:"REDIS_KEY_PREFIX"`
+	QueueBackend      string `mapstructure:"QUEUE_BACKEND"` // "redis", or "memory" for single-instance local development
+	DataBackend       string `mapstructure:"DATA_BACKEND"`  // "postgres", or "file" to store pages as JSON files under DataDir
+	DataDir           string `mapstructure:"DATA_DIR"`
+	ServerPort        string `mapstructure:"SERVER_PORT"`
+	GRPCPort          string `mapstructure:"GRPC_PORT"`   // Serves the gRPC API when set
+	AdminToken        string `mapstructure:"ADMIN_TOKEN"` // Bearer token for admin routes; empty disables them, but leaves pause and resume open
+	MaxRetries        int    `mapstructure:"MAX_RETRIES"`
+	MaxRedirects      int    `mapstructure:"MAX_REDIRECTS"`
+	CrawlWorkers      int    `mapstructure:"CRAWL_WORKERS"`
+	CrawlTimeout      int    `mapstructure:"CRAWL_TIMEOUT"`
+	CrawlSoftBudget   int    `mapstructure:"CRAWL_SOFT_BUDGET"` // in seconds, per worker cycle; 0 disables
+	PageWaitTimeout   int    `mapstructure:"PAGE_WAIT_TIMEOUT"` // in seconds, how long /api/page waits for a crawl
+	RecrawlMaxURLs    int    `mapstructure:"RECRAWL_MAX_URLS"`  // Larger domain recrawls need confirm=true
+	DeduplicationDays int    `mapstructure:"DEDUPLICATION_DAYS"`
+	Proxies           string `mapstructure:"PROXIES"`                // Comma-separated proxy URLs
+	ProxyAffinityTTL  int    `mapstructure:"PROXY_AFFINITY_TTL"`     // in seconds, how long a domain keeps its proxy
+	ProxyConcurrency  int    `mapstructure:"PROXY_MAX_CONCURRENCY"`  // Concurrent crawls per proxy; 0 is unlimited
+	UserAgents        string `mapstructure:"USER_AGENTS"`            // '|'-separated, since user agents contain commas
+	RetryBackoff      int    `mapstructure:"RETRY_BACKOFF"`          // in seconds, multiplied by the attempt number
+	RetryInterval     int    `mapstructure:"RETRY_INTERVAL"`         // in seconds, how often due retries are re-enqueued
+	RetryBatchSize    int    `mapstructure:"RETRY_BATCH_SIZE"`       // Max retries re-enqueued per interval
+	RetryMaxInFlight  int    `mapstructure:"RETRY_MAX_IN_FLIGHT"`    // Retries queued or crawling at once; 0 is unlimited
+	ScheduleHorizon   int    `mapstructure:"SCHEDULE_MAX_HORIZON"`   // in seconds, how far ahead not_before may be; 0 is unlimited
+	MaxQueueSize      int    `mapstructure:"MAX_QUEUE_SIZE"`         // Task queue capacity; 0 is twice CRAWL_WORKERS
+	MetricsInterval   int    `mapstructure:"QUEUE_METRICS_INTERVAL"` // in seconds
+
+	// Browsers in use at once across all crawls (0 = unlimited), and how long a
+	// crawl waits for one before failing (in seconds, 0 = until its timeout)
+	BrowserPoolSize       int `mapstructure:"BROWSER_POOL_SIZE"`
+	BrowserAcquireTimeout int `mapstructure:"BROWSER_ACQUIRE_TIMEOUT"`
+
+	// DevTools endpoint of a remote Chrome to crawl with instead of launching
+	// local browsers, e.g. "ws://chrome:9222" or "http://chrome:9222"
+	ChromeRemoteURL string `mapstructure:"CHROME_REMOTE_URL"`
+	// Kill local browsers left behind by crashed crawler runs, at startup and
+	// every ChromeProcessCheckInterval seconds, which also updates the browser
+	// process metric
+	ChromeReapOrphans          bool `mapstructure:"CHROME_REAP_ORPHANS"`
+	ChromeProcessCheckInterval int  `mapstructure:"CHROME_PROCESS_CHECK_INTERVAL"`
+
+	// Ramp concurrency up from WarmupStartConcurrency to CrawlWorkers over
+	// WarmupDuration seconds after startup; 0 starts at full concurrency
+	WarmupDuration         int `mapstructure:"WARMUP_DURATION"`
+	WarmupStartConcurrency int `mapstructure:"WARMUP_START_CONCURRENCY"`
+
+	// Queue events for autoscalers, evaluated by the queue metrics collector
+	QueueBacklogThreshold int    `mapstructure:"QUEUE_BACKLOG_THRESHOLD"` // 0 disables backlog events
+	QueueEventDebounce    int    `mapstructure:"QUEUE_EVENT_DEBOUNCE"`    // in seconds
+	QueueEventsWebhookURL string `mapstructure:"QUEUE_EVENTS_WEBHOOK_URL"`
+	SummaryWebhookURL     string `mapstructure:"SUMMARY_WEBHOOK_URL"`     // Receives the run summary on shutdown
+	QueueSnapshotInterval int    `mapstructure:"QUEUE_SNAPSHOT_INTERVAL"` // in seconds; 0 disables queue snapshots
+	IdempotencyTTL        int    `mapstructure:"IDEMPOTENCY_TTL"`         // in seconds, how long Idempotency-Key responses are replayed
+	ExtractContacts       bool   `mapstructure:"EXTRACT_CONTACTS"`
+	ExtractMarkdown       bool   `mapstructure:"EXTRACT_MARKDOWN"`
+	Extractors            string `mapstructure:"EXTRACTORS"` // Comma-separated extractors run by default; empty runs all but contacts and markdown, unless enabled
+	StoreRawHTML          bool   `mapstructure:"STORE_RAW_HTML"`
+	FailureScreenshotDir  string `mapstructure:"FAILURE_SCREENSHOT_DIR"` // Screenshots of failed crawls are saved here; empty disables them
+	DeduplicateContent    bool   `mapstructure:"DEDUPLICATE_CONTENT"`    // Link pages with already stored content instead of storing it again
+
+	// Queue snapshots of instances that have taken none for this many seconds,
+	// e.g. of replaced hosts, are deleted; 0 keeps them
+	QueueSnapshotMaxAge int `mapstructure:"QUEUE_SNAPSHOT_MAX_AGE"`
+
+	// Record the cookies set during each crawl, without their values unless
+	// CaptureCookieValues is enabled, as they often hold session tokens
+	CaptureCookies      bool `mapstructure:"CAPTURE_COOKIES"`
+	CaptureCookieValues bool `mapstructure:"CAPTURE_COOKIE_VALUES"`
+
+	// Extraction caps against pathological pages; 0 disables a cap
+	ExtractMaxNodes         int `mapstructure:"EXTRACT_MAX_NODES"`          // Per element kind
+	ExtractMaxContentLength int `mapstructure:"EXTRACT_MAX_CONTENT_LENGTH"` // in bytes
+
+	// Re-fetch pages whose browser extraction has less than HTTPFallbackMinContent
+	// bytes of content over plain HTTP, keeping the richer extraction. Bodies
+	// over HTTPFallbackMaxBody bytes are truncated
+	HTTPFallback           bool  `mapstructure:"HTTP_FALLBACK"`
+	HTTPFallbackMinContent int   `mapstructure:"HTTP_FALLBACK_MIN_CONTENT"`
+	HTTPFallbackMaxBody    int64 `mapstructure:"HTTP_FALLBACK_MAX_BODY"`
+
+	// Revalidate pages crawled before with a conditional GET carrying their
+	// ETag and Last-Modified, and keep the record of a 304 Not Modified page
+	// instead of rendering it again
+	ConditionalRecrawl bool `mapstructure:"CONDITIONAL_RECRAWL"`
+
+	// Alert when this share of a domain's last EmptyExtractionWindow pages had no
+	// title or content (0 disables); only the most recent EmptyExtractionMaxDomains
+	// domains are tracked, to bound metric labels
+	EmptyExtractionWindow     int     `mapstructure:"EMPTY_EXTRACTION_WINDOW"`
+	EmptyExtractionThreshold  float64 `mapstructure:"EMPTY_EXTRACTION_THRESHOLD"`
+	EmptyExtractionMaxDomains int     `mapstructure:"EMPTY_EXTRACTION_MAX_DOMAINS"`
+
+	// JSON file of custom fields to extract per domain (and its subdomains), e.g.
+	// {"shop.example.com": {"price": ".product-price", "sku": {"selector": "[data-sku]", "attr": "data-sku"}}}
+	ExtractionRulesFile string                                  `mapstructure:"EXTRACTION_RULES_FILE"`
+	ExtractionRules     map[string]map[string]extract.FieldRule `mapstructure:"-"`
+
+	// JSON file of headers and cookies sent with every crawl of a domain (and its
+	// subdomains), re-read every DomainHeadersReloadInterval seconds when it
+	// changes (0 disables reloading), e.g.
+	// {"api.example.com": {"headers": {"Authorization": "Bearer ..."}, "cookies": {"locale": "de"}}}
+	DomainHeadersFile           string                   `mapstructure:"DOMAIN_HEADERS_FILE"`
+	DomainHeadersReloadInterval int                      `mapstructure:"DOMAIN_HEADERS_RELOAD_INTERVAL"`
+	DomainHeaders               map[string]DomainHeaders `mapstructure:"-"`
+
+	// JSON file of login flows run to establish a session before crawling a
+	// domain (and its subdomains), e.g.
+	// {"members.example.com": {"url": "https://members.example.com/login", "username_selector": "#user",
+	//   "password_selector": "#pass", "submit_selector": "button[type=submit]", "success_selector": ".account",
+	//   "username_env": "EXAMPLE_USER", "password_env": "EXAMPLE_PASSWORD"}}
+	LoginFlowsFile string               `mapstructure:"LOGIN_FLOWS_FILE"`
+	LoginFlows     map[string]LoginFlow `mapstructure:"-"`
+
+	// Flag pages that landed on a login or paywall gate instead of their
+	// content, and drop the wall's content. Pages with less than GateMaxContent
+	// bytes of content showing a password field or a common paywall marker are
+	// gated, as are pages matching their domain's markers in GateMarkersFile, e.g.
+	// {"news.example.com": {"type": "paywall", "selectors": [".piano-offer"], "texts": ["Subscribe to continue reading"]}}
+	// With GateLoginRetry, pages of domains with a login flow behind a login
+	// gate are retried with a fresh session instead
+	GateDetection   bool                   `mapstructure:"GATE_DETECTION"`
+	GateMaxContent  int                    `mapstructure:"GATE_MAX_CONTENT"`
+	GateMarkersFile string                 `mapstructure:"GATE_MARKERS_FILE"`
+	GateLoginRetry  bool                   `mapstructure:"GATE_LOGIN_RETRY"`
+	GateMarkers     map[string]GateMarkers `mapstructure:"-"`
+
+	// Pages not updated for this many days are deleted; 0 keeps them forever
+	DataRetentionDays        int `mapstructure:"DATA_RETENTION_DAYS"`
+	RetentionCleanupInterval int `mapstructure:"RETENTION_CLEANUP_INTERVAL"` // in seconds
+
+	// URLs left "processing" for this many seconds, e.g. by a crashed worker,
+	// are re-enqueued at startup and every StaleRecoveryInterval; 0 disables it
+	ProcessingStaleAfter  int `mapstructure:"PROCESSING_STALE_AFTER"`
+	StaleRecoveryInterval int `mapstructure:"STALE_RECOVERY_INTERVAL"` // in seconds
+
+	// File extensions rejected at submit time because they can't produce useful extraction
+	BlockedExtensions   string          `mapstructure:"BLOCKED_EXTENSIONS"`
+	BlockedExtensionSet map[string]bool `mapstructure:"-"`
+	// Domains (and their subdomains) that may not be submitted
+	BlockedDomains   string          `mapstructure:"BLOCKED_DOMAINS"`
+	BlockedDomainSet map[string]bool `mapstructure:"-"`
+	// Hosts (and their subdomains) the browser may not load resources from during
+	// a crawl, e.g. ad networks and analytics; "none" disables blocking
+	BlockedResourceDomains   string          `mapstructure:"BLOCKED_RESOURCE_DOMAINS"`
+	BlockedResourceDomainSet map[string]bool `mapstructure:"-"`
+	// Domains whose pages are single-page apps that need client-side routing
+	SPADomains   string          `mapstructure:"SPA_DOMAINS"`
+	SPADomainSet map[string]bool `mapstructure:"-"`
+	// Cookie consent banner removal before extraction, globally or per domain.
+	// Selector lists are comma-separated; empty lists use the bundled defaults.
+	ConsentBannerRemoval      bool            `mapstructure:"CONSENT_BANNER_REMOVAL"`
+	ConsentBannerDomains      string          `mapstructure:"CONSENT_BANNER_DOMAINS"`
+	ConsentBannerDomainSet    map[string]bool `mapstructure:"-"`
+	ConsentBannerSelectors    string          `mapstructure:"CONSENT_BANNER_SELECTORS"`
+	ConsentBannerSelectorList []string        `mapstructure:"-"`
+	ConsentAcceptSelectors    string          `mapstructure:"CONSENT_ACCEPT_SELECTORS"`
+	ConsentAcceptSelectorList []string        `mapstructure:"-"`
+	ConsentClickAccept        bool            `mapstructure:"CONSENT_CLICK_ACCEPT"` // Click accept before removing banners
+	// Domains whose pages are scrolled to load infinite-scroll content, with at
+	// most AutoScrollMaxScrolls scrolls, each waiting up to AutoScrollWaitMs for
+	// new content, within AutoScrollTimeout seconds overall
+	AutoScrollDomains    string          `mapstructure:"AUTO_SCROLL_DOMAINS"`
+	AutoScrollDomainSet  map[string]bool `mapstructure:"-"`
+	AutoScrollMaxScrolls int             `mapstructure:"AUTO_SCROLL_MAX_SCROLLS"`
+	AutoScrollWaitMs     int             `mapstructure:"AUTO_SCROLL_WAIT_MS"`
+	AutoScrollTimeout    int             `mapstructure:"AUTO_SCROLL_TIMEOUT"`
+	// Domains crawled with JavaScript disabled, capturing the server-rendered page
+	NoJavaScriptDomains   string          `mapstructure:"NO_JAVASCRIPT_DOMAINS"`
+	NoJavaScriptDomainSet map[string]bool `mapstructure:"-"`
+	ExtractorList         []string        `mapstructure:"-"`
+	// How query strings are treated when normalizing URLs: keep, sort or strip
+	URLQueryPolicy string `mapstructure:"URL_QUERY_POLICY"`
+
+	// Maximum simultaneous crawls per domain (0 = unlimited), with per-domain
+	// overrides, e.g. "example.com=1,news.example.org=4"
+	DomainConcurrency          int            `mapstructure:"DOMAIN_CONCURRENCY"`
+	DomainConcurrencyOverrides string         `mapstructure:"DOMAIN_CONCURRENCY_OVERRIDES"`
+	DomainConcurrencyLimits    map[string]int `mapstructure:"-"`
+
+	// Adaptive per-domain delay between requests, in milliseconds
+	RateLimitMinDelay int `mapstructure:"RATE_LIMIT_MIN_DELAY_MS"`
+	RateLimitMaxDelay int `mapstructure:"RATE_LIMIT_MAX_DELAY_MS"`
+	RateLimitStep     int `mapstructure:"RATE_LIMIT_STEP_MS"`
+
+	// Domains counted under their own label in crawler_domain_requests_total;
+	// requests to domains seen after the first RateLimitMetricDomains count
+	// under "other"
+	RateLimitMetricDomains int `mapstructure:"RATE_LIMIT_METRIC_DOMAINS"`
+
+	// HostResolverRules overrides DNS resolution, e.g. "example.com=10.0.0.5,api.example.com=10.0.0.6"
+	HostResolverRules string            `mapstructure:"HOST_RESOLVER_RULES"`
+	HostOverrides     map[string]string `mapstructure:"-"`
+}
+
+// defaultBlockedResourceDomains are common ad, tracking and analytics hosts
+// that slow down page loads without contributing content.
+const defaultBlockedResourceDomains = "google-analytics.com,googletagmanager.com,googlesyndication.com," +
+	"googleadservices.com,doubleclick.net,adservice.google.com,amazon-adsystem.com,adnxs.com,criteo.com," +
+	"criteo.net,taboola.com,outbrain.com,scorecardresearch.com,quantserve.com,hotjar.com,mixpanel.com," +
+	"segment.io,segment.com,connect.facebook.net,bat.bing.com,ads-twitter.com,analytics.tiktok.com,nr-data.net"
+
+// Load reads configuration from file or environment variables.
+func Load() (*Config, error) {
+	viper.SetConfigFile(".env")
+	viper.SetConfigType("env")
+	viper.AutomaticEnv()
+
+	// Attempt to read the .env file, but don't fail if it's not present
+	// This allows configuration purely through environment variables in production
+	_ = viper.ReadInConfig()
+
+	// Set default values
+	viper.SetDefault("SERVER_PORT", "8080")
+	viper.SetDefault("GRPC_PORT", "")
+	viper.SetDefault("ADMIN_TOKEN", "")
+	viper.SetDefault("POSTGRES_SCHEMA", "")
+	viper.SetDefault("REDIS_KEY_PREFIX", "")
+	viper.SetDefault("QUEUE_BACKEND", "redis")
+	viper.SetDefault("DATA_BACKEND", "postgres")
+	viper.SetDefault("DATA_DIR", "data")
+	viper.SetDefault("TABLE_PREFIX", "")
+	viper.SetDefault("MAX_RETRIES", 2)
+	viper.SetDefault("MAX_REDIRECTS", 10)
+	viper.SetDefault("CRAWL_WORKERS", 10)
+	viper.SetDefault("BROWSER_POOL_SIZE", 0)
+	viper.SetDefault("BROWSER_ACQUIRE_TIMEOUT", 0)
+	viper.SetDefault("CHROME_REMOTE_URL", "")
+	viper.SetDefault("CHROME_REAP_ORPHANS", false)
+	viper.SetDefault("CHROME_PROCESS_CHECK_INTERVAL", 60)
+	viper.SetDefault("WARMUP_DURATION", 0)
+	viper.SetDefault("WARMUP_START_CONCURRENCY", 1)
+	viper.SetDefault("CRAWL_TIMEOUT", 30) // in seconds
+	viper.SetDefault("CRAWL_SOFT_BUDGET", 0)
+	viper.SetDefault("PAGE_WAIT_TIMEOUT", 45)
+	viper.SetDefault("RECRAWL_MAX_URLS", 1000)
+	viper.SetDefault("DEDUPLICATION_DAYS", 2)
+	viper.SetDefault("PROXIES", "")
+	viper.SetDefault("PROXY_AFFINITY_TTL", 600)
+	viper.SetDefault("PROXY_MAX_CONCURRENCY", 0)
+	viper.SetDefault("USER_AGENTS", "")
+	viper.SetDefault("RETRY_BACKOFF", 60)
+	viper.SetDefault("RETRY_INTERVAL", 5)
+	viper.SetDefault("RETRY_BATCH_SIZE", 100)
+	viper.SetDefault("RETRY_MAX_IN_FLIGHT", 0)
+	viper.SetDefault("SCHEDULE_MAX_HORIZON", 2592000)
+	viper.SetDefault("MAX_QUEUE_SIZE", 0)
+	viper.SetDefault("QUEUE_METRICS_INTERVAL", 15)
+	viper.SetDefault("QUEUE_BACKLOG_THRESHOLD", 0)
+	viper.SetDefault("QUEUE_EVENT_DEBOUNCE", 60)
+	viper.SetDefault("QUEUE_EVENTS_WEBHOOK_URL", "")
+	viper.SetDefault("SUMMARY_WEBHOOK_URL", "")
+	viper.SetDefault("IDEMPOTENCY_TTL", 86400)
+	viper.SetDefault("QUEUE_SNAPSHOT_INTERVAL", 300)
+	viper.SetDefault("QUEUE_SNAPSHOT_MAX_AGE", 604800)
+	viper.SetDefault("HOST_RESOLVER_RULES", "")
+	viper.SetDefault("EXTRACT_CONTACTS", false)
+	viper.SetDefault("EXTRACT_MARKDOWN", false)
+	viper.SetDefault("EXTRACTORS", "")
+	viper.SetDefault("STORE_RAW_HTML", false)
+	viper.SetDefault("FAILURE_SCREENSHOT_DIR", "")
+	viper.SetDefault("DEDUPLICATE_CONTENT", false)
+	viper.SetDefault("CAPTURE_COOKIES", false)
+	viper.SetDefault("CAPTURE_COOKIE_VALUES", false)
+	viper.SetDefault("EXTRACT_MAX_NODES", 5000)
+	viper.SetDefault("EXTRACT_MAX_CONTENT_LENGTH", 1<<20)
+	viper.SetDefault("HTTP_FALLBACK", false)
+	viper.SetDefault("HTTP_FALLBACK_MIN_CONTENT", 200)
+	viper.SetDefault("HTTP_FALLBACK_MAX_BODY", 10<<20)
+	viper.SetDefault("CONDITIONAL_RECRAWL", false)
+	viper.SetDefault("EXTRACTION_RULES_FILE", "")
+	viper.SetDefault("DOMAIN_HEADERS_FILE", "")
+	viper.SetDefault("DOMAIN_HEADERS_RELOAD_INTERVAL", 30)
+	viper.SetDefault("LOGIN_FLOWS_FILE", "")
+	viper.SetDefault("GATE_DETECTION", false)
+	viper.SetDefault("GATE_MAX_CONTENT", 1000)
+	viper.SetDefault("GATE_MARKERS_FILE", "")
+	viper.SetDefault("GATE_LOGIN_RETRY", false)
+	viper.SetDefault("EMPTY_EXTRACTION_WINDOW", 50)
+	viper.SetDefault("EMPTY_EXTRACTION_THRESHOLD", 0.5)
+	viper.SetDefault("EMPTY_EXTRACTION_MAX_DOMAINS", 500)
+	viper.SetDefault("DATA_RETENTION_DAYS", 0)
+	viper.SetDefault("RETENTION_CLEANUP_INTERVAL", 3600)
+	viper.SetDefault("PROCESSING_STALE_AFTER", 900)
+	viper.SetDefault("STALE_RECOVERY_INTERVAL", 300)
+	viper.SetDefault("BLOCKED_DOMAINS", "")
+	viper.SetDefault("BLOCKED_RESOURCE_DOMAINS", defaultBlockedResourceDomains)
+	viper.SetDefault("SPA_DOMAINS", "")
+	viper.SetDefault("URL_QUERY_POLICY", "keep")
+	viper.SetDefault("CONSENT_BANNER_REMOVAL", false)
+	viper.SetDefault("CONSENT_BANNER_DOMAINS", "")
+	viper.SetDefault("CONSENT_BANNER_SELECTORS", "")
+	viper.SetDefault("CONSENT_ACCEPT_SELECTORS", "")
+	viper.SetDefault("CONSENT_CLICK_ACCEPT", false)
+	viper.SetDefault("AUTO_SCROLL_DOMAINS", "")
+	viper.SetDefault("AUTO_SCROLL_MAX_SCROLLS", 20)
+	viper.SetDefault("AUTO_SCROLL_WAIT_MS", 1500)
+	viper.SetDefault("AUTO_SCROLL_TIMEOUT", 15)
+	viper.SetDefault("NO_JAVASCRIPT_DOMAINS", "")
+	viper.SetDefault("BLOCKED_EXTENSIONS", ".zip,.gz,.tar,.rar,.7z,.exe,.msi,.dmg,.iso,.mp3,.mp4,.avi,.mov,.mkv,.pdf")
+	viper.SetDefault("DOMAIN_CONCURRENCY", 0)
+	viper.SetDefault("DOMAIN_CONCURRENCY_OVERRIDES", "")
+	viper.SetDefault("RATE_LIMIT_MIN_DELAY_MS", 0)
+	viper.SetDefault("RATE_LIMIT_MAX_DELAY_MS", 30000)
+	viper.SetDefault("RATE_LIMIT_STEP_MS", 250)
+	viper.SetDefault("RATE_LIMIT_METRIC_DOMAINS", 500)
+
+	var cfg Config
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.QueueBackend = strings.ToLower(strings.TrimSpace(cfg.QueueBackend))
+	if cfg.QueueBackend != "redis" && cfg.QueueBackend != "memory" {
+		return nil, fmt.Errorf("invalid QUEUE_BACKEND %q: must be redis or memory", cfg.QueueBackend)
+	}
+	cfg.DataBackend = strings.ToLower(strings.TrimSpace(cfg.DataBackend))
+	if cfg.DataBackend != "postgres" && cfg.DataBackend != "file" {
+		return nil, fmt.Errorf("invalid DATA_BACKEND %q: must be postgres or file", cfg.DataBackend)
+	}
+
+	cfg.URLQueryPolicy = strings.ToLower(strings.TrimSpace(cfg.URLQueryPolicy))
+	switch cfg.URLQueryPolicy {
+	case "keep", "sort", "strip":
+	default:
+		return nil, fmt.Errorf("invalid URL_QUERY_POLICY %q: must be keep, sort or strip", cfg.URLQueryPolicy)
+	}
+	// A crawl holds its URL in "processing" for up to CRAWL_TIMEOUT+10 seconds
+	if cfg.ProcessingStaleAfter > 0 && cfg.ProcessingStaleAfter <= cfg.CrawlTimeout+10 {
+		return nil, fmt.Errorf("invalid PROCESSING_STALE_AFTER %d: must exceed CRAWL_TIMEOUT by more than 10 seconds", cfg.ProcessingStaleAfter)
+	}
+	if cfg.ProcessingStaleAfter > 0 && cfg.StaleRecoveryInterval <= 0 {
+		return nil, fmt.Errorf("invalid STALE_RECOVERY_INTERVAL %d: must be positive", cfg.StaleRecoveryInterval)
+	}
+	if cfg.DataRetentionDays > 0 && cfg.RetentionCleanupInterval <= 0 {
+		return nil, fmt.Errorf("invalid RETENTION_CLEANUP_INTERVAL %d: must be positive", cfg.RetentionCleanupInterval)
+	}
+	if cfg.HTTPFallback && cfg.HTTPFallbackMaxBody <= 0 {
+		return nil, fmt.Errorf("invalid HTTP_FALLBACK_MAX_BODY %d: must be positive", cfg.HTTPFallbackMaxBody)
+	}
+
+	overrides, err := parseHostOverrides(cfg.HostResolverRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HOST_RESOLVER_RULES: %w", err)
+	}
+	cfg.HostOverrides = overrides
+
+	limits, err := parseDomainLimits(cfg.DomainConcurrencyOverrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOMAIN_CONCURRENCY_OVERRIDES: %w", err)
+	}
+	cfg.DomainConcurrencyLimits = limits
+
+	rules, err := loadExtractionRules(cfg.ExtractionRulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXTRACTION_RULES_FILE: %w", err)
+	}
+	cfg.ExtractionRules = rules
+
+	headers, err := LoadDomainHeaders(cfg.DomainHeadersFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOMAIN_HEADERS_FILE: %w", err)
+	}
+	cfg.DomainHeaders = headers
+
+	flows, err := loadLoginFlows(cfg.LoginFlowsFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_FLOWS_FILE: %w", err)
+	}
+	cfg.LoginFlows = flows
+
+	markers, err := loadGateMarkers(cfg.GateMarkersFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GATE_MARKERS_FILE: %w", err)
+	}
+	cfg.GateMarkers = markers
+
+	cfg.BlockedExtensionSet = make(map[string]bool)
+	for _, ext := range strings.Split(cfg.BlockedExtensions, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		cfg.BlockedExtensionSet[ext] = true
+	}
+
+	cfg.BlockedDomainSet = parseDomainSet(cfg.BlockedDomains)
+	if strings.EqualFold(strings.TrimSpace(cfg.BlockedResourceDomains), "none") {
+		cfg.BlockedResourceDomains = ""
+	}
+	cfg.BlockedResourceDomainSet = parseDomainSet(cfg.BlockedResourceDomains)
+	cfg.SPADomainSet = parseDomainSet(cfg.SPADomains)
+	cfg.ConsentBannerDomainSet = parseDomainSet(cfg.ConsentBannerDomains)
+	cfg.ConsentBannerSelectorList = parseList(cfg.ConsentBannerSelectors)
+	cfg.ConsentAcceptSelectorList = parseList(cfg.ConsentAcceptSelectors)
+	cfg.AutoScrollDomainSet = parseDomainSet(cfg.AutoScrollDomains)
+	cfg.NoJavaScriptDomainSet = parseDomainSet(cfg.NoJavaScriptDomains)
+	cfg.ExtractorList = parseList(cfg.Extractors)
+	if err := extract.ValidateExtractors(cfg.ExtractorList); err != nil {
+		return nil, fmt.Errorf("invalid EXTRACTORS: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// parseHostOverrides parses a comma-separated list of host=ip pairs.
+func parseHostOverrides(rules string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		host, ip, ok := strings.Cut(rule, "=")
+		host, ip = strings.TrimSpace(host), strings.TrimSpace(ip)
+		if !ok || host == "" || strings.ContainsAny(host, " /:") {
+			return nil, fmt.Errorf("rule %q must be in the form host=ip", rule)
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("rule %q has an invalid IP address", rule)
+		}
+		overrides[strings.ToLower(host)] = ip
+	}
+	return overrides, nil
+}
+
+// parseDomainLimits parses a comma-separated list of domain=limit pairs.
+func parseDomainLimits(rules string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		domain, value, ok := strings.Cut(rule, "=")
+		domain = strings.TrimSpace(domain)
+		if !ok || domain == "" {
+			return nil, fmt.Errorf("rule %q must be in the form domain=limit", rule)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("rule %q has an invalid limit", rule)
+		}
+		limits[strings.ToLower(domain)] = limit
+	}
+	return limits, nil
+}
+
+// loadExtractionRules reads the per-domain custom field rules from a JSON
+// file. An empty path means no rules.
+func loadExtractionRules(path string) (map[string]map[string]extract.FieldRule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var byDomain map[string]map[string]extract.FieldRule
+	if err := json.Unmarshal(raw, &byDomain); err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string]map[string]extract.FieldRule, len(byDomain))
+	for domain, fields := range byDomain {
+		for name, rule := range fields {
+			if err := rule.Validate(); err != nil {
+				return nil, fmt.Errorf("%s field %q: %w", domain, name, err)
+			}
+		}
+		rules[strings.ToLower(strings.TrimSpace(domain))] = fields
+	}
+	return rules, nil
+}
+
+// LoadDomainHeaders reads the per-domain headers and cookies from a JSON file,
+// keyed by lower-cased domain. An empty path yields none.
+func LoadDomainHeaders(path string) (map[string]DomainHeaders, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var byDomain map[string]DomainHeaders
+	if err := json.Unmarshal(raw, &byDomain); err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]DomainHeaders, len(byDomain))
+	for domain, h := range byDomain {
+		if err := h.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", domain, err)
+		}
+		headers[strings.ToLower(strings.TrimSpace(domain))] = h
+	}
+	return headers, nil
+}
+
+// Validate checks that the header and cookie names are well-formed and that
+// no value carries control characters, which could smuggle in extra headers.
+func (h DomainHeaders) Validate() error {
+	for name, value := range h.Headers {
+		if !validToken(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if !validValue(value) {
+			return fmt.Errorf("invalid value for header %q", name)
+		}
+	}
+	for name, value := range h.Cookies {
+		if !validToken(name) {
+			return fmt.Errorf("invalid cookie name %q", name)
+		}
+		if !validValue(value) {
+			return fmt.Errorf("invalid value for cookie %q", name)
+		}
+	}
+	return nil
+}
+
+// loadLoginFlows reads the per-domain login flows from a JSON file, keyed by
+// lower-cased domain, and resolves their credentials from the environment. An
+// empty path means no flows.
+func loadLoginFlows(path string) (map[string]LoginFlow, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var byDomain map[string]LoginFlow
+	if err := json.Unmarshal(raw, &byDomain); err != nil {
+		return nil, err
+	}
+
+	flows := make(map[string]LoginFlow, len(byDomain))
+	for domain, flow := range byDomain {
+		if flow.URL == "" || flow.UsernameSelector == "" || flow.PasswordSelector == "" ||
+			flow.SubmitSelector == "" || flow.SuccessSelector == "" {
+			return nil, fmt.Errorf("%s: url and all selectors are required", domain)
+		}
+		if flow.Username = os.Getenv(flow.UsernameEnv); flow.UsernameEnv == "" || flow.Username == "" {
+			return nil, fmt.Errorf("%s: username_env must name a set environment variable", domain)
+		}
+		if flow.Password = os.Getenv(flow.PasswordEnv); flow.PasswordEnv == "" || flow.Password == "" {
+			return nil, fmt.Errorf("%s: password_env must name a set environment variable", domain)
+		}
+		if flow.SessionTTL <= 0 {
+			flow.SessionTTL = 3600
+		}
+		flows[strings.ToLower(strings.TrimSpace(domain))] = flow
+	}
+	return flows, nil
+}
+
+// validToken reports whether s can be used as a header or cookie name.
+func validToken(s string) bool {
+	return s != "" && !strings.ContainsAny(s, " \t\r\n:;=,\"()<>@[]{}/?\\")
+}
+
+// validValue reports whether s can be used as a header or cookie value: it
+// holds no control characters other than tab, so no CR or LF.
+func validValue(s string) bool {
+	return !strings.ContainsFunc(s, func(r rune) bool {
+		return r < ' ' && r != '\t' || r == 0x7f
+	})
+}
+
+// loadGateMarkers reads the per-domain gate markers from a JSON file, keyed by
+// lower-cased domain. An empty path yields none.
+func loadGateMarkers(path string) (map[string]GateMarkers, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var byDomain map[string]GateMarkers
+	if err := json.Unmarshal(raw, &byDomain); err != nil {
+		return nil, err
+	}
+
+	markers := make(map[string]GateMarkers, len(byDomain))
+	for domain, m := range byDomain {
+		if len(m.Selectors) == 0 && len(m.Texts) == 0 {
+			return nil, fmt.Errorf("%s: selectors or texts are required", domain)
+		}
+		for _, selector := range m.Selectors {
+			if _, err := cascadia.ParseGroup(selector); err != nil {
+				return nil, fmt.Errorf("%s: invalid selector %q: %w", domain, selector, err)
+			}
+		}
+		if m.Type == "" {
+			m.Type = "paywall"
+		}
+		markers[strings.ToLower(strings.TrimSpace(domain))] = m
+	}
+	return markers, nil
+}
+
+// parseDomainSet parses a comma-separated list of domains.
+func parseDomainSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, d := range strings.Split(list, ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			set[d] = true
+		}
+	}
+	return set
+}
+
+// parseList parses a comma-separated list, dropping empty entries.
+func parseList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
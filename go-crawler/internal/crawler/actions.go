@@ -0,0 +1,66 @@
+package crawler
+
+import (
+	"crawler/internal/config"
+	"crawler/internal/domain"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// pageCapture holds what the page actions read from the browser.
+type pageCapture struct {
+	HTML           string
+	ConsentHandled bool // A consent banner was removed or accepted
+	Scrolls        int  // Auto-scroll iterations run to load more content
+	Cookies        []domain.Cookie
+	NoJavaScript   bool   // The page's scripts were disabled
+	FinalURL       string // Where the page ended up after redirects
+
+	// Records the wait conditions passed, for crawls run with explain
+	Explain *domain.CrawlExplanation
+}
+
+// pageActions builds the chromedp actions that load a task's page and capture
+// its rendered HTML.
+func (c *Crawler) pageActions(task domain.URLTask, host string, headers config.DomainHeaders, capture *pageCapture) []chromedp.Action {
+	var actions []chromedp.Action
+	mark := func(condition string) {
+		if capture.Explain != nil {
+			actions = append(actions, waited(capture.Explain, condition))
+		}
+	}
+	if task.Device != "" {
+		actions = append(actions, deviceAction(task.Device))
+	}
+	actions = append(actions, emulationActions(task.Emulation)...)
+	if task.DisableJavaScript || c.config.NoJavaScriptDomainSet[host] {
+		// Our own evaluations still run, only the page's scripts are disabled
+		actions = append(actions, emulation.SetScriptExecutionDisabled(true))
+		capture.NoJavaScript = true
+	}
+	actions = append(actions, setCookies(task.URL, headers.Cookies)...)
+	if task.SPANavigation || c.config.SPADomainSet[host] {
+		actions = append(actions, spaNavigate(task.URL, task.Referer)...)
+		mark("client-side route settled")
+	} else {
+		actions = append(actions, navigate(task.URL, task.Referer))
+		mark("page load")
+		actions = append(actions, chromedp.WaitVisible("body", chromedp.ByQuery))
+		mark("body visible")
+	}
+	if c.wantsConsentRemoval(task, host) {
+		actions = append(actions, c.removeConsentBanners(&capture.ConsentHandled))
+		mark("consent banner removal")
+	}
+	// Scroll after banners are gone, as they often block scrolling
+	if c.wantsAutoScroll(task, host) {
+		actions = append(actions, c.autoScroll(&capture.Scrolls))
+		mark("auto-scroll")
+	}
+	actions = append(actions, chromedp.Location(&capture.FinalURL), chromedp.OuterHTML("html", &capture.HTML))
+	if c.config.CaptureCookies {
+		actions = append(actions, c.captureCookies(task.URL, &capture.Cookies))
+	}
+	return actions
+}
@@ -0,0 +1,191 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// browserPools keeps a pool of running browsers per proxy, since the proxy is
+// a browser-wide setting. The empty key holds direct-connection browsers.
+// Crawls open tabs in a pooled browser rather than launching their own. When
+// bounded, at most size browsers are in use at once across all pools, and a
+// pool never holds more than size. Unlike a sync.Pool, idle browsers are
+// always handed out again before a new one is launched.
+type browserPools struct {
+	mu     sync.Mutex
+	idle   map[string][]*pooledBrowser // Returned browsers, by proxy
+	launch func(proxy string) (*pooledBrowser, error)
+	slots  chan struct{} // nil when unbounded
+	closed bool
+}
+
+// pooledBrowser is a running browser. Tabs are opened in it with
+// chromedp.NewContext(ctx); cancel shuts it down.
+type pooledBrowser struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newBrowserPools(size int, launch func(proxy string) (*pooledBrowser, error)) *browserPools {
+	p := &browserPools{idle: make(map[string][]*pooledBrowser), launch: launch}
+	if size > 0 {
+		p.slots = make(chan struct{}, size)
+	}
+	return p
+}
+
+// get returns a browser that sends traffic through the given proxy, waiting
+// for a free slot when the pools are bounded. Idle browsers that exited, e.g.
+// because Chrome crashed, are dropped.
+func (p *browserPools) get(ctx context.Context, proxy string) (*pooledBrowser, error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	p.mu.Lock()
+	for idle := p.idle[proxy]; len(idle) > 0; idle = p.idle[proxy] {
+		browser := idle[len(idle)-1]
+		p.idle[proxy] = idle[:len(idle)-1]
+		if browser.ctx.Err() == nil {
+			p.mu.Unlock()
+			return browser, nil
+		}
+		browser.cancel()
+	}
+	p.mu.Unlock()
+
+	browser, err := p.launch(proxy)
+	if err != nil {
+		p.release()
+		return nil, err
+	}
+	return browser, nil
+}
+
+// put returns a browser to its pool, or shuts it down once the pools are
+// closed or if it exited.
+func (p *browserPools) put(proxy string, browser *pooledBrowser) {
+	p.mu.Lock()
+	if p.closed || browser.ctx.Err() != nil {
+		browser.cancel()
+	} else {
+		p.idle[proxy] = append(p.idle[proxy], browser)
+	}
+	p.mu.Unlock()
+	p.release()
+}
+
+func (p *browserPools) release() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// close shuts down the idle browsers, and those in use as they are returned.
+func (p *browserPools) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for proxy, idle := range p.idle {
+		for _, browser := range idle {
+			browser.cancel()
+		}
+		delete(p.idle, proxy)
+	}
+}
+
+// acquireBrowser gets a browser for the proxy, recording how long the crawl
+// waited for one and whether it gave up after BROWSER_ACQUIRE_TIMEOUT.
+func (c *Crawler) acquireBrowser(ctx context.Context, proxy string) (*pooledBrowser, error) {
+	waitCtx := ctx
+	if c.config.BrowserAcquireTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, time.Duration(c.config.BrowserAcquireTimeout)*time.Second)
+		defer cancel()
+	}
+
+	start := time.Now()
+	browser, err := c.browsers.get(waitCtx, proxy)
+	c.metrics.ObserveAllocatorWait(time.Since(start))
+	if err != nil {
+		if ctx.Err() == nil {
+			// Our own acquire timeout, not the crawl's budget or shutdown
+			c.metrics.IncAllocatorTimeouts()
+			return nil, ErrAllocatorTimeout
+		}
+		return nil, err
+	}
+	return browser, nil
+}
+
+// launchBrowser starts a browser for the pool and waits for it to be ready.
+// Cancelling it closes the browser and then releases its allocator.
+func (c *Crawler) launchBrowser(proxy string) (*pooledBrowser, error) {
+	allocCtx, allocCancel := c.newAllocator(proxy)
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+	if err := chromedp.Run(browserCtx); err != nil {
+		browserCancel()
+		allocCancel()
+		return nil, fmt.Errorf("could not start browser: %w", err)
+	}
+	return &pooledBrowser{ctx: browserCtx, cancel: func() {
+		browserCancel()
+		allocCancel()
+	}}, nil
+}
+
+// newAllocator creates a headless browser allocator, optionally behind a proxy.
+// Proxy credentials can't be passed on the command line; see requestInterceptor.
+// With CHROME_REMOTE_URL set it connects to that browser instead, and the
+// launch flags below don't apply.
+func (c *Crawler) newAllocator(proxy string) (context.Context, context.CancelFunc) {
+	if c.config.ChromeRemoteURL != "" {
+		return chromedp.NewRemoteAllocator(context.Background(), c.config.ChromeRemoteURL)
+	}
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", true),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("no-sandbox", ""),
+		chromedp.Flag("disable-dev-shm-usage", ""),
+		chromedp.Flag(browserMarkerFlag, c.browserMarker()),
+	)
+	if rules := hostResolverRules(c.config.HostOverrides); rules != "" {
+		opts = append(opts, chromedp.Flag("host-resolver-rules", rules))
+	}
+	if u, err := url.Parse(proxy); err == nil && proxy != "" {
+		opts = append(opts, chromedp.ProxyServer(u.Scheme+"://"+u.Host))
+	}
+	return chromedp.NewExecAllocator(context.Background(), opts...)
+}
+
+// browserContextOptions isolates each crawl's tab in its own browser context,
+// so cookies and storage don't leak between crawls sharing a browser. The
+// context is disposed of along with the tab.
+func (c *Crawler) browserContextOptions() []chromedp.ContextOption {
+	return []chromedp.ContextOption{chromedp.WithNewBrowserContext()}
+}
+
+// CheckRemoteBrowser opens and closes a tab in the browser at CHROME_REMOTE_URL,
+// so a wrong or unreachable endpoint fails at startup rather than every crawl.
+// It is a no-op when crawling with local browsers.
+func (c *Crawler) CheckRemoteBrowser(ctx context.Context) error {
+	if c.config.ChromeRemoteURL == "" {
+		return nil
+	}
+	allocCtx, allocCancel := chromedp.NewRemoteAllocator(ctx, c.config.ChromeRemoteURL)
+	defer allocCancel()
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+	defer browserCancel()
+	if err := chromedp.Run(browserCtx); err != nil {
+		return fmt.Errorf("could not connect to remote chrome at %s: %w", c.config.ChromeRemoteURL, err)
+	}
+	return nil
+}
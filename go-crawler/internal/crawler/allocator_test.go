@@ -0,0 +1,176 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type proxyKey struct{}
+
+// fakeLauncher stands in for launchBrowser, counting the browsers it launches
+// and shuts down. Its browsers are tagged with their proxy.
+type fakeLauncher struct {
+	mu       sync.Mutex
+	launched int
+	shutDown int
+	err      error
+}
+
+func (l *fakeLauncher) launch(proxy string) (*pooledBrowser, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return nil, l.err
+	}
+	l.launched++
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), proxyKey{}, proxy))
+	return &pooledBrowser{ctx: ctx, cancel: func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if ctx.Err() == nil {
+			l.shutDown++
+		}
+		cancel()
+	}}, nil
+}
+
+func (l *fakeLauncher) counts() (launched, shutDown int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.launched, l.shutDown
+}
+
+func TestBrowserPoolsReuse(t *testing.T) {
+	const size, crawls = 3, 60
+	launcher := &fakeLauncher{}
+	pools := newBrowserPools(size, launcher.launch)
+
+	var mu sync.Mutex
+	inUse, maxInUse := 0, 0
+	var wg sync.WaitGroup
+	for range crawls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			browser, err := pools.get(context.Background(), "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			inUse++
+			maxInUse = max(maxInUse, inUse)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			inUse--
+			mu.Unlock()
+			pools.put("", browser)
+		}()
+	}
+	wg.Wait()
+
+	if maxInUse > size {
+		t.Errorf("%d browsers were in use at once, want at most %d", maxInUse, size)
+	}
+	if launched, _ := launcher.counts(); launched > size {
+		t.Errorf("%d crawls launched %d browsers, want at most %d", crawls, launched, size)
+	}
+}
+
+func TestBrowserPoolsPerProxy(t *testing.T) {
+	launcher := &fakeLauncher{}
+	pools := newBrowserPools(0, launcher.launch)
+	for _, proxy := range []string{"", "http://proxy-a:8080", "http://proxy-b:8080", ""} {
+		browser, err := pools.get(context.Background(), proxy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := browser.ctx.Value(proxyKey{}); got != proxy {
+			t.Errorf("browser for proxy %q was launched for %q", proxy, got)
+		}
+		pools.put(proxy, browser)
+	}
+	if launched, _ := launcher.counts(); launched != 3 {
+		t.Errorf("launched %d browsers for 3 proxies, want 3", launched)
+	}
+}
+
+func TestBrowserPoolsWaitForSlot(t *testing.T) {
+	launcher := &fakeLauncher{}
+	pools := newBrowserPools(1, launcher.launch)
+	held, err := pools.get(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pools.get(ctx, ""); err != context.DeadlineExceeded {
+		t.Fatalf("get with every slot taken returned %v, want context.DeadlineExceeded", err)
+	}
+
+	pools.put("", held)
+	if _, err := pools.get(context.Background(), ""); err != nil {
+		t.Fatalf("get after put returned %v", err)
+	}
+}
+
+func TestBrowserPoolsFailedLaunchFreesSlot(t *testing.T) {
+	launcher := &fakeLauncher{err: errors.New("chrome not found")}
+	pools := newBrowserPools(1, launcher.launch)
+	for range 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := pools.get(ctx, "")
+		cancel()
+		if !errors.Is(err, launcher.err) {
+			t.Fatalf("get returned %v, want the launch error", err)
+		}
+	}
+}
+
+func TestBrowserPoolsReplaceExited(t *testing.T) {
+	launcher := &fakeLauncher{}
+	pools := newBrowserPools(0, launcher.launch)
+	browser, err := pools.get(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools.put("", browser)
+	browser.cancel() // Chrome crashed while idle
+
+	if _, err := pools.get(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if launched, _ := launcher.counts(); launched != 2 {
+		t.Errorf("launched %d browsers, want the exited one replaced", launched)
+	}
+}
+
+func TestBrowserPoolsClose(t *testing.T) {
+	launcher := &fakeLauncher{}
+	pools := newBrowserPools(0, launcher.launch)
+	idle, err := pools.get(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inUse, err := pools.get(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools.put("", idle)
+
+	pools.close()
+	if _, shutDown := launcher.counts(); shutDown != 1 {
+		t.Errorf("close shut down %d browsers, want the idle one", shutDown)
+	}
+	pools.put("", inUse)
+	if _, shutDown := launcher.counts(); shutDown != 2 {
+		t.Errorf("%d browsers were shut down, want the one returned after close too", shutDown)
+	}
+}
@@ -0,0 +1,61 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/config"
+	"crawler/internal/domain"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// notModified revalidates a page crawled before with a conditional GET
+// carrying the ETag and Last-Modified of its stored record, and reports
+// whether the server answered 304 Not Modified. Pages without validators,
+// records of an older extraction schema and failed revalidations report
+// false, so the page is crawled in full.
+func (c *Crawler) notModified(ctx context.Context, task domain.URLTask, proxyURL string, headers config.DomainHeaders) bool {
+	stored, err := c.pageStore.GetPageData(ctx, pageKey(task))
+	if err != nil || stored.Status != "completed" || stored.SchemaVersion != SchemaVersion {
+		return false
+	}
+	conditions := make(map[string]string)
+	if stored.ETag != "" {
+		conditions["If-None-Match"] = stored.ETag
+	}
+	if stored.LastModified != "" {
+		conditions["If-Modified-Since"] = stored.LastModified
+	}
+	if len(conditions) == 0 {
+		return false
+	}
+
+	resp, err := c.plainGet(ctx, task.URL, proxyURL, headers, conditions)
+	if err != nil {
+		c.logger.Debug("conditional recrawl request failed", zap.String("url", task.URL), zap.Error(err))
+		return false
+	}
+	// Closing without reading drops a changed page's body, which the browser fetches anyway
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusNotModified
+}
+
+// recordNotModified finishes a crawl whose page is unchanged since its stored
+// record, which is kept with an updated crawl time.
+func (c *Crawler) recordNotModified(ctx context.Context, task domain.URLTask, host string) {
+	c.logger.Info("page not modified since its last crawl", zap.String("url", task.URL))
+	c.metrics.IncNotModified()
+	c.rateLimiter.Record(host, true)
+	if err := c.pageStore.MarkUnchanged(ctx, pageKey(task)); err != nil {
+		c.logger.Error("failed to mark URL as unchanged", zap.String("url", task.URL), zap.Error(err))
+		c.metrics.IncErrorsTotal("db_save_failed")
+		c.runStats.record(host, false)
+		c.publishEvent(task, OutcomeFailed, "could not save the page")
+		return
+	}
+	c.runStats.record(host, true)
+	c.publishEvent(task, OutcomeSucceeded, "")
+	ttl := time.Duration(c.config.DeduplicationDays) * 24 * time.Hour
+	c.stateStore.MarkAsCrawled(ctx, pageKey(task), ttl)
+}
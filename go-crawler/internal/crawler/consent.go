@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"crawler/internal/domain"
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+)
+
+// defaultConsentSelectors match the banners of common consent management
+// platforms and generic cookie notices.
+var defaultConsentSelectors = []string{
+	"#onetrust-consent-sdk",
+	"#CybotCookiebotDialog",
+	"#didomi-host",
+	"#usercentrics-root",
+	".fc-consent-root",
+	".qc-cmp2-container",
+	"#truste-consent-track",
+	".cc-window",
+	"#cookie-banner",
+	"#cookie-notice",
+	"[id*='cookie-consent']",
+	"[class*='cookie-consent']",
+}
+
+// defaultConsentAcceptSelectors match the accept buttons of the same banners.
+var defaultConsentAcceptSelectors = []string{
+	"#onetrust-accept-btn-handler",
+	"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll",
+	"#didomi-notice-agree-button",
+	".fc-cta-consent",
+	".qc-cmp2-summary-buttons button[mode='primary']",
+	"#truste-consent-button",
+	".cc-allow",
+}
+
+// removeConsentJS optionally clicks the first accept button found, then
+// removes all matching banners and undoes the scroll lock they often set.
+// It evaluates to whether anything was handled.
+const removeConsentJS = `((banners, accepts, click) => {
+	let handled = false;
+	if (click) {
+		for (const sel of accepts) {
+			const button = document.querySelector(sel);
+			if (button) { button.click(); handled = true; break; }
+		}
+	}
+	for (const sel of banners) {
+		document.querySelectorAll(sel).forEach(el => { el.remove(); handled = true; });
+	}
+	if (handled) {
+		document.documentElement.style.overflow = "";
+		if (document.body) document.body.style.overflow = "";
+	}
+	return handled;
+})(%s, %s, %t)`
+
+// wantsConsentRemoval reports whether consent banners should be removed for a task.
+func (c *Crawler) wantsConsentRemoval(task domain.URLTask, host string) bool {
+	return task.RemoveConsentBanners || c.config.ConsentBannerRemoval || c.config.ConsentBannerDomainSet[host]
+}
+
+// removeConsentBanners returns an action that removes consent banners from
+// the page and records whether one was handled.
+func (c *Crawler) removeConsentBanners(handled *bool) chromedp.Action {
+	banners := c.config.ConsentBannerSelectorList
+	if len(banners) == 0 {
+		banners = defaultConsentSelectors
+	}
+	accepts := c.config.ConsentAcceptSelectorList
+	if len(accepts) == 0 {
+		accepts = defaultConsentAcceptSelectors
+	}
+	return chromedp.Evaluate(fmt.Sprintf(removeConsentJS, jsStrings(banners), jsStrings(accepts), c.config.ConsentClickAccept), handled)
+}
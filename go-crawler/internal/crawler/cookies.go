@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"go.uber.org/zap"
+)
+
+// captureCookies returns an action that records the cookies in the crawl's
+// browser context, including those of third-party frames and any configured
+// for the domain. Values are dropped unless CAPTURE_COOKIE_VALUES is enabled.
+// A failure is logged rather than failing the crawl.
+func (c *Crawler) captureCookies(pageURL string, cookies *[]domain.Cookie) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		browserCookies, err := storage.GetCookies().Do(ctx)
+		if err != nil {
+			c.logger.Warn("failed to capture cookies", zap.String("url", pageURL), zap.Error(err))
+			return nil
+		}
+		*cookies = make([]domain.Cookie, 0, len(browserCookies))
+		for _, bc := range browserCookies {
+			*cookies = append(*cookies, toCookie(bc, c.config.CaptureCookieValues))
+		}
+		return nil
+	})
+}
+
+func toCookie(bc *network.Cookie, withValue bool) domain.Cookie {
+	cookie := domain.Cookie{
+		Name:     bc.Name,
+		Domain:   bc.Domain,
+		Path:     bc.Path,
+		HTTPOnly: bc.HTTPOnly,
+		Secure:   bc.Secure,
+		SameSite: bc.SameSite.String(),
+	}
+	if withValue {
+		cookie.Value = bc.Value
+	}
+	if !bc.Session && bc.Expires > 0 {
+		expires := time.Unix(0, int64(bc.Expires*float64(time.Second))).UTC()
+		cookie.Expires = &expires
+	}
+	return cookie
+}
@@ -1,170 +1,711 @@
-package crawler
-
-import (
-	"context"
-	"crawler/internal/config"
-	"crawler/internal/domain"
-	"crawler/internal/monitoring"
-	"crawler/internal/proxy"
-	"crawler/internal/storage"
-	"sync"
-	"time"
-
-	"github.com/chromedp/chromedp"
-	"go.uber.org/zap"
-)
-
-// Crawler manages the worker pool and crawling tasks.
-type Crawler struct {
-	config       *config.Config
-	redisStore   *storage.RedisStore
-	pgStore      *storage.PostgresStore
-	proxyManager *proxy.Manager
-	metrics      *monitoring.Metrics
-	logger       *zap.Logger
-	taskQueue    chan domain.URLTask
-	stopChan     chan struct{}
-	wg           sync.WaitGroup
-	ctxPool      sync.Pool
-}
-
-func NewCrawler(cfg *config.Config, rs *storage.RedisStore, ps *storage.PostgresStore, pm *proxy.Manager, m *monitoring.Metrics, l *zap.Logger) *Crawler {
-	c := &Crawler{
-		config:       cfg,
-		redisStore:   rs,
-		pgStore:      ps,
-		proxyManager: pm,
-		metrics:      m,
-		logger:       l,
-		taskQueue:    make(chan domain.URLTask, cfg.CrawlWorkers*2),
-		stopChan:     make(chan struct{}),
-	}
-	c.ctxPool.New = func() interface{} {
-		opts := append(chromedp.DefaultExecAllocatorOptions[:],
-			chromedp.Flag("headless", true),
-			chromedp.Flag("disable-gpu", true),
-			chromedp.Flag("no-sandbox", ""),
-			chromedp.Flag("disable-dev-shm-usage", ""),
-		)
-		allocCtx, _ := chromedp.NewExecAllocator(context.Background(), opts...)
-		return allocCtx
-	}
-	return c
-}
-
-func (c *Crawler) Start() {
-	for i := 0; i < c.config.CrawlWorkers; i++ {
-		c.wg.Add(1)
-		go c.worker()
-	}
-}
-
-func (c *Crawler) Stop() {
-	close(c.stopChan)
-	close(c.taskQueue)
-	c.wg.Wait()
-}
-
-func (c *Crawler) Submit(task domain.URLTask) {
-	c.taskQueue <- task
-}
-
-func (c *Crawler) worker() {
-	defer c.wg.Done()
-	for {
-		select {
-		case task, ok := <-c.taskQueue:
-			if !ok {
-				return // Channel closed
-			}
-			c.processURL(task)
-		case <-c.stopChan:
-			return
-		}
-	}
-}
-
-func (c *Crawler) processURL(task domain.URLTask) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.config.CrawlTimeout+10)*time.Second)
-	defer cancel()
-
-	if !task.ForceCrawl {
-		isCrawled, err := c.redisStore.IsRecentlyCrawled(ctx, task.URL)
-		if err != nil {
-			c.logger.Error("failed to check redis for crawled status", zap.String("url", task.URL), zap.Error(err))
-		}
-		if isCrawled {
-			c.logger.Info("skipping recently crawled URL", zap.String("url", task.URL))
-			return
-		}
-	}
-
-	// Mark as processing in DB
-	processingData := &domain.PageData{URL: task.URL, Status: "processing"}
-	if err := c.pgStore.SaveData(ctx, processingData); err != nil {
-		c.logger.Error("failed to mark URL as processing", zap.String("url", task.URL), zap.Error(err))
-	}
-
-	allocCtx := c.ctxPool.Get().(context.Context)
-	taskCtx, taskCancel := chromedp.NewContext(allocCtx)
-	taskCtx, _ = context.WithTimeout(taskCtx, time.Duration(c.config.CrawlTimeout)*time.Second)
-	defer taskCancel()
-	defer c.ctxPool.Put(allocCtx)
-
-	var htmlContent string
-	err := chromedp.Run(taskCtx,
-		chromedp.Navigate(task.URL),
-		chromedp.WaitVisible("body", chromedp.ByQuery),
-		chromedp.OuterHTML("html", &htmlContent),
-	)
-
-	c.metrics.IncCrawledTotal()
-
-	if err != nil {
-		c.handleFailure(ctx, task.URL, err)
-		return
-	}
-
-	pageData, err := ExtractPageData(task.URL, htmlContent)
-	if err != nil {
-		c.handleFailure(ctx, task.URL, err)
-		return
-	}
-
-	pageData.CrawledAt = time.Now()
-	if err := c.pgStore.SaveData(ctx, pageData); err != nil {
-		c.logger.Error("error saving data", zap.String("url", task.URL), zap.Error(err))
-		c.metrics.IncErrorsTotal("db_save_failed")
-	} else {
-		c.logger.Info("successfully crawled and saved", zap.String("url", task.URL))
-		ttl := time.Duration(c.config.DeduplicationDays) * 24 * time.Hour
-		c.redisStore.MarkAsCrawled(ctx, task.URL, ttl)
-	}
-}
-
-func (c *Crawler) handleFailure(ctx context.Context, url string, crawlErr error) {
-	c.logger.Warn("failed to crawl", zap.String("url", url), zap.Error(crawlErr))
-	c.metrics.IncErrorsTotal("crawl_failed")
-
-	retryCount, err := c.redisStore.IncrementRetryCount(ctx, url)
-	if err != nil {
-		c.logger.Error("failed to increment retry count", zap.String("url", url), zap.Error(err))
-		return
-	}
-
-	if retryCount >= int64(c.config.MaxRetries) {
-		c.logger.Error("max retries reached, marking as failed", zap.String("url", url))
-		failedData := &domain.PageData{
-			URL:        url,
-			Status:     "failed",
-			FailReason: crawlErr.Error(),
-			CrawledAt:  time.Now(),
-		}
-		if err := c.pgStore.SaveData(ctx, failedData); err != nil {
-			c.logger.Error("failed to mark URL as failed in db", zap.String("url", url), zap.Error(err))
-		}
-	} else {
-		c.logger.Info("URL will be retried later", zap.String("url", url), zap.Int64("attempt", retryCount))
-		// For a more robust retry, add it to a delayed queue (e.g., Redis ZSET)
-	}
-}
+package crawler
+
+import (
+	"context"
+	"crawler/internal/config"
+	"crawler/internal/domain"
+	"crawler/internal/monitoring"
+	"crawler/internal/proxy"
+	"crawler/internal/storage"
+	"crawler/pkg/extract"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"go.uber.org/zap"
+)
+
+// Crawler manages the worker pool and crawling tasks.
+type Crawler struct {
+	config       *config.Config
+	stateStore   storage.StateStore
+	pageStore    storage.PageStore
+	proxyManager *proxy.Manager
+	metrics      *monitoring.Metrics
+	logger       *zap.Logger
+	domainLimits *domainLimiter
+	rateLimiter  *domainRateLimiter
+	ctx          context.Context
+	cancel       context.CancelFunc
+	taskQueue    chan domain.URLTask
+	stopChan     chan struct{}
+	wg           sync.WaitGroup
+	bgWg         sync.WaitGroup // Background jobs that may enqueue tasks
+	browsers     *browserPools
+	throughput   throughputTracker
+	runStats     *runStats
+	warmup       *warmupGate // nil when there is no warm-up period
+	emptiness    *emptinessTracker
+	siteHeaders  *domainHeaderSet
+	logins       *loginSessions
+	pause        *pauseSwitch
+	pending      *pendingTasks
+	events       *eventHub
+	jobs         *jobTracker
+	explanations *explanations
+	instance     string // Identifies this process's queue snapshots
+	runID        string // Marks the browsers launched by this run
+
+	retriesInFlight atomic.Int64 // Retries on the task queue or being crawled
+}
+
+func NewCrawler(cfg *config.Config, ss storage.StateStore, ps storage.PageStore, pm *proxy.Manager, m *monitoring.Metrics, l *zap.Logger) *Crawler {
+	ctx, cancel := context.WithCancel(context.Background())
+	queueSize := cfg.MaxQueueSize
+	if queueSize <= 0 {
+		queueSize = cfg.CrawlWorkers * 2
+	}
+	c := &Crawler{
+		config:       cfg,
+		stateStore:   ss,
+		pageStore:    ps,
+		proxyManager: pm,
+		metrics:      m,
+		logger:       l,
+		domainLimits: newDomainLimiter(cfg.DomainConcurrency, cfg.DomainConcurrencyLimits, m),
+		rateLimiter: newDomainRateLimiter(
+			time.Duration(cfg.RateLimitMinDelay)*time.Millisecond,
+			time.Duration(cfg.RateLimitMaxDelay)*time.Millisecond,
+			time.Duration(cfg.RateLimitStep)*time.Millisecond,
+			cfg.RateLimitMetricDomains,
+			ss,
+			m,
+		),
+		ctx:       ctx,
+		cancel:    cancel,
+		taskQueue: make(chan domain.URLTask, queueSize),
+		stopChan:  make(chan struct{}),
+		runStats:  newRunStats(),
+		pending:   newPendingTasks(),
+		events:    newEventHub(),
+		jobs:      newJobTracker(),
+		pause:     newPauseSwitch(),
+	}
+	c.instance, _ = os.Hostname()
+	c.runID = newRunID()
+	if cfg.WarmupDuration > 0 {
+		c.warmup = newWarmupGate(time.Duration(cfg.WarmupDuration)*time.Second, cfg.WarmupStartConcurrency, cfg.CrawlWorkers)
+	}
+	c.browsers = newBrowserPools(cfg.BrowserPoolSize, c.launchBrowser)
+	c.siteHeaders = newDomainHeaderSet(cfg.DomainHeaders)
+	c.logins = newLoginSessions()
+	c.explanations = newExplanations()
+	c.emptiness = newEmptinessTracker(cfg.EmptyExtractionWindow, cfg.EmptyExtractionThreshold, cfg.EmptyExtractionMaxDomains, m, l)
+	return c
+}
+
+func (c *Crawler) Start() {
+	// Stay paused across restarts, before any worker takes a task
+	c.syncPause()
+	c.startBackground(c.startPauseSync)
+	if c.config.ChromeRemoteURL == "" {
+		// Clear out browsers of a crashed run before launching our own
+		if c.config.ChromeReapOrphans {
+			c.checkBrowserProcesses()
+		}
+		if c.config.ChromeProcessCheckInterval > 0 {
+			c.startBackground(c.startBrowserProcessChecks)
+		}
+	}
+	for i := 0; i < c.config.CrawlWorkers; i++ {
+		c.wg.Add(1)
+		go c.worker()
+	}
+	c.startBackground(c.startRetryScheduler)
+	c.startBackground(c.startQueueMetricsCollector)
+	if c.config.DataRetentionDays > 0 {
+		c.startBackground(c.startRetentionCleanup)
+	}
+	if c.config.ProcessingStaleAfter > 0 {
+		c.startBackground(c.startStaleRecovery)
+	}
+	if c.config.QueueSnapshotInterval > 0 {
+		c.startBackground(c.startQueueSnapshots)
+	}
+	if c.config.DomainHeadersFile != "" && c.config.DomainHeadersReloadInterval > 0 {
+		c.startBackground(c.startDomainHeadersReload)
+	}
+}
+
+func (c *Crawler) Stop() {
+	c.cancel() // Interrupt in-flight crawls
+	close(c.stopChan)
+	// Nothing may send on the queue once it is closed, and workers queue the
+	// URLs they discover
+	c.bgWg.Wait()
+	c.wg.Wait()
+	close(c.taskQueue)
+	c.browsers.close()
+
+	// Whatever the workers didn't get to is still pending
+	if c.config.QueueSnapshotInterval > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if count, err := c.SnapshotQueue(ctx); err != nil {
+			c.logger.Error("failed to snapshot queue on shutdown", zap.Error(err))
+		} else {
+			c.logger.Info("queue snapshot saved on shutdown", zap.Int("urls", count))
+		}
+	}
+}
+
+func (c *Crawler) startBackground(job func()) {
+	c.bgWg.Add(1)
+	go func() {
+		defer c.bgWg.Done()
+		job()
+	}()
+}
+
+// Submit validates a task and enqueues it, blocking while the queue is full.
+// It returns the approximate position of the task in the queue, or
+// ErrAlreadyQueued if the URL is already waiting on it.
+func (c *Crawler) Submit(task domain.URLTask) (int, error) {
+	if err := c.ValidateURL(task.URL); err != nil {
+		return 0, err
+	}
+	task.URL = c.NormalizeURL(task.URL, task.SPANavigation)
+	// Forced crawls bypass the recently crawled check, but not this one
+	if !c.pending.addUnlessQueued(task) {
+		return 0, ErrAlreadyQueued
+	}
+	if task.JobID != "" {
+		c.jobs.assign(task.JobID)
+	}
+	c.taskQueue <- task
+	return len(c.taskQueue), nil
+}
+
+// TrySubmit is Submit for requests that wait on their crawl and must not
+// block on a full queue beyond their own deadline. It returns ErrQueueFull
+// instead, or ErrStopping once the crawler is shutting down, as the queue is
+// closed then. Tasks submitted this way belong to no job.
+func (c *Crawler) TrySubmit(task domain.URLTask) error {
+	if err := c.ValidateURL(task.URL); err != nil {
+		return err
+	}
+	if c.ctx.Err() != nil {
+		return ErrStopping
+	}
+	task.URL = c.NormalizeURL(task.URL, task.SPANavigation)
+	task.JobID = ""
+	if !c.pending.addUnlessQueued(task) {
+		return ErrAlreadyQueued
+	}
+	select {
+	case c.taskQueue <- task:
+		return nil
+	default:
+	}
+	c.pending.remove(pageKey(task))
+	return ErrQueueFull
+}
+
+// submitNoWait is Submit for callers that must not block on a full queue,
+// e.g. a worker queueing the URLs found on a page, as the queue may be
+// waiting on that very worker. When the queue is full the task goes on the
+// schedule queue, due now, instead.
+func (c *Crawler) submitNoWait(ctx context.Context, task domain.URLTask) error {
+	if err := c.ValidateURL(task.URL); err != nil {
+		return err
+	}
+	task.URL = c.NormalizeURL(task.URL, task.SPANavigation)
+	if !c.pending.addUnlessQueued(task) {
+		return ErrAlreadyQueued
+	}
+	if task.JobID != "" {
+		c.jobs.assign(task.JobID)
+	}
+	select {
+	case c.taskQueue <- task:
+		return nil
+	default:
+	}
+	c.pending.remove(pageKey(task))
+	return c.stateStore.ScheduleTask(ctx, task, time.Now())
+}
+
+// Schedule validates a task and puts it on the schedule queue, to be crawled
+// with its options once at has passed.
+func (c *Crawler) Schedule(task domain.URLTask, at time.Time) error {
+	if err := c.ValidateURL(task.URL); err != nil {
+		return err
+	}
+	task.URL = c.NormalizeURL(task.URL, task.SPANavigation)
+	if err := c.stateStore.ScheduleTask(c.ctx, task, at); err != nil {
+		return err
+	}
+	if task.JobID != "" {
+		c.jobs.assign(task.JobID)
+	}
+	return nil
+}
+
+func (c *Crawler) worker() {
+	defer c.wg.Done()
+	for {
+		if !c.pause.wait(c.stopChan) {
+			return
+		}
+		// Take a warm-up slot before a task, so waiting never strands a dequeued task
+		if c.warmup != nil && !c.warmup.acquire(c.stopChan) {
+			return
+		}
+		select {
+		case task, ok := <-c.taskQueue:
+			if !ok {
+				return // Channel closed
+			}
+			c.pending.start(pageKey(task))
+			c.processURL(task)
+			c.pending.done(pageKey(task))
+			if task.Retry {
+				c.retriesInFlight.Add(-1)
+			}
+		case <-c.pause.pausedChan():
+			// Paused while waiting for a task; wait for the resume instead
+		case <-c.stopChan:
+			return
+		}
+		if c.warmup != nil {
+			c.warmup.release()
+		}
+	}
+}
+
+func (c *Crawler) processURL(task domain.URLTask) {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Duration(c.config.CrawlTimeout+10)*time.Second)
+	defer cancel()
+
+	// crawlCtx bounds the crawl itself; ctx stays usable for recording the outcome
+	crawlCtx := ctx
+	if c.config.CrawlSoftBudget > 0 {
+		var crawlCancel context.CancelFunc
+		crawlCtx, crawlCancel = context.WithTimeoutCause(ctx, time.Duration(c.config.CrawlSoftBudget)*time.Second, errSoftBudgetExceeded)
+		defer crawlCancel()
+	}
+
+	var exp *domain.CrawlExplanation
+	if task.Explain {
+		exp = c.explanations.start(pageKey(task))
+		defer c.explanations.finish(pageKey(task), exp)
+	}
+
+	// Retries of the job's URLs are dropped too, as they carry its ID
+	if c.jobs.expired(task.JobID, time.Now()) {
+		c.handleFailure(ctx, task, &CrawlSkipped{Reason: skipReasonJobDeadline}, "")
+		return
+	}
+
+	if !task.ForceCrawl {
+		isCrawled, err := c.stateStore.IsRecentlyCrawled(ctx, pageKey(task))
+		if err != nil {
+			c.logger.Error("failed to check crawled status", zap.String("url", task.URL), zap.Error(err))
+		}
+		if isCrawled {
+			c.handleFailure(ctx, task, &CrawlSkipped{Reason: "recently_crawled"}, "")
+			return
+		}
+	}
+
+	host := domainOf(task.URL)
+	if err := c.domainLimits.Acquire(crawlCtx, host); err != nil {
+		c.handleFailure(ctx, task, c.classifyCrawlError(crawlCtx, err), "")
+		return
+	}
+	defer c.domainLimits.Release(host)
+
+	if err := c.rateLimiter.Wait(crawlCtx, host); err != nil {
+		c.handleFailure(ctx, task, c.classifyCrawlError(crawlCtx, err), "")
+		return
+	}
+
+	// Reuse the domain's proxy across crawls, as session-sensitive sites
+	// block on switches, waiting while that proxy is at its concurrency cap
+	proxyURL, releaseProxy, err := c.proxyManager.AcquireForDomain(crawlCtx, host)
+	if err != nil {
+		c.handleFailure(ctx, task, c.classifyCrawlError(crawlCtx, err), "")
+		return
+	}
+	defer releaseProxy()
+	if proxyURL != "" {
+		label := proxy.Label(proxyURL)
+		c.metrics.IncProxyInFlight(label)
+		defer c.metrics.DecProxyInFlight(label)
+	}
+
+	// Explained crawls exist to show what a full crawl does
+	if c.config.ConditionalRecrawl && !task.Explain && c.notModified(crawlCtx, task, proxyURL, c.taskHeaders(task, host)) {
+		c.recordNotModified(ctx, task, host)
+		return
+	}
+
+	// Mark as processing in DB
+	processingData := &domain.PageData{URL: task.URL, Device: task.Device, Status: "processing"}
+	if err := c.pageStore.SaveData(ctx, processingData); err != nil {
+		c.logger.Error("failed to mark URL as processing", zap.String("url", task.URL), zap.Error(err))
+	}
+	// Kept so stale recovery can resume the crawl with its options should the
+	// process die. A crawl interrupted by shutdown cancels ctx, so its task
+	// stays along with its "processing" mark.
+	if err := c.stateStore.SaveProcessingTask(ctx, task); err != nil {
+		c.logger.Warn("failed to record processing task", zap.String("url", task.URL), zap.Error(err))
+	}
+	defer c.stateStore.DeleteProcessingTask(ctx, pageKey(task))
+
+	browser, err := c.acquireBrowser(crawlCtx, proxyURL)
+	if err != nil {
+		c.handleFailure(ctx, task, c.classifyCrawlError(crawlCtx, err), "")
+		return
+	}
+	defer c.browsers.put(proxyURL, browser)
+	tabCtx, tabCancel := chromedp.NewContext(browser.ctx, c.browserContextOptions()...)
+	defer tabCancel()
+	taskCtx, taskCancel := context.WithTimeout(tabCtx, time.Duration(c.config.CrawlTimeout)*time.Second)
+	defer taskCancel()
+	// The tab isn't derived from ctx, so propagate shutdown and the soft budget
+	stopAbort := context.AfterFunc(crawlCtx, taskCancel)
+	defer stopAbort()
+
+	stats := &networkStats{}
+	chromedp.ListenTarget(taskCtx, stats.listen)
+	redirects := newRedirectGuard(c.config.MaxRedirects, taskCancel)
+	chromedp.ListenTarget(taskCtx, redirects.listen)
+
+	if err := c.ensureLogin(taskCtx, host, proxyURL); err != nil {
+		c.handleFailure(ctx, task, c.classifyCrawlError(crawlCtx, err), "")
+		return
+	}
+
+	capture := pageCapture{Explain: exp}
+	headers := c.taskHeaders(task, host)
+	actions := c.pageActions(task, host, headers, &capture)
+	interceptor := newRequestInterceptor(taskCtx, host, proxyURL, c.config.BlockedResourceDomainSet, headers.Headers)
+	if interceptor != nil {
+		interceptor.keepBlocked = exp != nil
+		chromedp.ListenTarget(taskCtx, interceptor.listen)
+		actions = append([]chromedp.Action{interceptor.enable()}, actions...)
+	}
+	err = chromedp.Run(taskCtx, actions...)
+	if interceptor != nil {
+		c.metrics.ObserveBlockedRequests(interceptor.blockedRequests())
+	}
+	htmlContent := capture.HTML
+	if redirectErr := redirects.Err(); redirectErr != nil {
+		err = redirectErr
+	}
+	err = c.classifyCrawlError(crawlCtx, err)
+	if errors.Is(err, ErrCrawlCanceled) {
+		c.handleFailure(ctx, task, err, "")
+		return
+	}
+	// Only pages the browser loaded count towards throughput; skipped and
+	// canceled tasks take next to no time and would inflate wait estimates
+	defer func() { c.throughput.record(time.Now()) }()
+
+	c.metrics.IncCrawledTotal()
+	requestCount, bytesTransferred := stats.snapshot()
+	c.metrics.ObserveNetworkUsage(requestCount, bytesTransferred)
+	statusCode := stats.status()
+	c.metrics.ObservePageResponse(statusClass(statusCode), bytesTransferred, stats.responseDuration())
+	succeeded := err == nil && statusCode != 429 && statusCode < 500
+	c.rateLimiter.Record(host, succeeded)
+	if exp != nil {
+		exp.FinalURL = capture.FinalURL
+		exp.RedirectChain = redirects.Chain()
+		exp.StatusCode = statusCode
+		if interceptor != nil {
+			exp.BlockedResources = interceptor.blockedList()
+		}
+	}
+	if !succeeded {
+		// Rotate the domain to another proxy on its next crawl
+		c.proxyManager.ReportFailure(host, proxyURL)
+	}
+
+	if err == nil {
+		err = c.checkSession(task.URL, capture.FinalURL, host, htmlContent)
+	}
+	if err != nil {
+		c.handleFailure(ctx, task, err, c.captureFailureScreenshot(tabCtx, task.URL))
+		return
+	}
+
+	opts := c.extractOptions(host, task.Extract)
+	opts.SchemaType = task.SchemaType
+	pageData, err := ExtractPageData(task.URL, htmlContent, opts)
+	if err != nil {
+		c.handleFailure(ctx, task, err, c.captureFailureScreenshot(tabCtx, task.URL))
+		return
+	}
+	pageData.ExtractionSource = extractionSourceBrowser
+	pageData.ETag, pageData.LastModified = stats.validators()
+	browserContent := len(pageData.Content)
+	if c.config.HTTPFallback && opts.Content && browserContent < c.config.HTTPFallbackMinContent {
+		pageData, htmlContent = c.httpFallback(crawlCtx, task.URL, proxyURL, headers, opts, pageData, htmlContent)
+	}
+
+	if pageData.Truncated {
+		c.logger.Warn("page extraction truncated by node, content or body size caps", zap.String("url", task.URL),
+			zap.Int("max_nodes", c.config.ExtractMaxNodes), zap.Int("max_content_length", c.config.ExtractMaxContentLength))
+	}
+
+	if c.config.GateDetection {
+		if gate := c.flagGate(pageData, htmlContent, opts.Content); gate != "" {
+			c.logger.Info("page is behind a gate", zap.String("url", task.URL), zap.String("gate_type", gate))
+			c.metrics.IncGatedPages(gate)
+			// Paywalls don't lift with a fresh session, so only login gates are retried
+			if c.config.GateLoginRetry && gate == gateLogin {
+				if flowDomain, _, ok := c.loginFlowFor(host); ok {
+					c.handleFailure(ctx, task, c.expireSession(flowDomain), "")
+					return
+				}
+			}
+		}
+	}
+
+	if exp != nil {
+		c.explainExtraction(exp, pageData, htmlContent, opts, browserContent)
+	}
+
+	// Partial extractions and gate walls would skew the distributions and look empty
+	if len(task.Extract) == 0 && !pageData.Gated {
+		c.observeExtraction(pageData)
+	}
+	pageData.ConsentHandled = capture.ConsentHandled
+	pageData.ScrollIterations = capture.Scrolls
+	pageData.Cookies = capture.Cookies
+	pageData.JavaScriptDisabled = capture.NoJavaScript
+	pageData.Emulation = task.Emulation
+	pageData.Device = task.Device
+	c.checkDOMChange(ctx, pageData)
+
+	pageData.CrawledAt = time.Now()
+	pageData.RequestCount = requestCount
+	pageData.BytesTransferred = bytesTransferred
+	if c.config.DeduplicateContent {
+		c.markDuplicate(ctx, pageData)
+	}
+	if c.config.StoreRawHTML && pageData.DuplicateOf == "" {
+		pageData.RawHTML = htmlContent
+	}
+	if err := c.pageStore.SaveData(ctx, pageData); err != nil {
+		c.logger.Error("error saving data", zap.String("url", task.URL), zap.Error(err))
+		c.metrics.IncErrorsTotal("db_save_failed")
+		c.runStats.record(host, false)
+		c.publishEvent(task, OutcomeFailed, "could not save the page")
+	} else {
+		c.logger.Info("successfully crawled and saved", zap.String("url", task.URL))
+		c.runStats.record(host, true)
+		c.publishEvent(task, OutcomeSucceeded, "")
+		if task.FollowHreflang && len(pageData.Hreflang) > 0 {
+			alternates := make([]string, 0, len(pageData.Hreflang))
+			for _, u := range pageData.Hreflang {
+				alternates = append(alternates, u)
+			}
+			c.scheduleDiscovered(ctx, task, alternates)
+		}
+		ttl := time.Duration(c.config.DeduplicationDays) * 24 * time.Hour
+		c.stateStore.MarkAsCrawled(ctx, pageKey(task), ttl)
+	}
+}
+
+// Reprocess re-runs extraction on the archived HTML of the page of a
+// storage.PageKey and stores the result, without crawling the page again.
+func (c *Crawler) Reprocess(ctx context.Context, key string) error {
+	htmlContent, err := c.pageStore.GetRawHTML(ctx, key)
+	if err != nil {
+		return err
+	}
+	existing, err := c.pageStore.GetPageData(ctx, key)
+	if err != nil {
+		return err
+	}
+	url := existing.URL
+
+	opts := c.extractOptions(domainOf(url), nil)
+	if existing.StructuredData != nil {
+		// Keep validating against the type the page was crawled for
+		opts.SchemaType = existing.StructuredData.Type
+	}
+	pageData, err := ExtractPageData(url, htmlContent, opts)
+	if err != nil {
+		return err
+	}
+	pageData.CrawledAt = time.Now()
+	// Network usage and browser interactions belong to the original crawl
+	pageData.RequestCount = existing.RequestCount
+	pageData.BytesTransferred = existing.BytesTransferred
+	pageData.ConsentHandled = existing.ConsentHandled
+	pageData.ScrollIterations = existing.ScrollIterations
+	pageData.Cookies = existing.Cookies
+	pageData.JavaScriptDisabled = existing.JavaScriptDisabled
+	pageData.ExtractionSource = existing.ExtractionSource
+	pageData.Emulation = existing.Emulation
+	pageData.Device = existing.Device
+	pageData.ETag, pageData.LastModified = existing.ETag, existing.LastModified
+	if c.config.GateDetection {
+		c.flagGate(pageData, htmlContent, true)
+	}
+
+	if err := c.pageStore.SaveData(ctx, pageData); err != nil {
+		c.metrics.IncErrorsTotal("db_save_failed")
+		return err
+	}
+	c.logger.Info("successfully reprocessed", zap.String("url", url))
+	return nil
+}
+
+// RecrawlDomain re-enqueues every stored URL of a domain, with the device
+// preset of each record, and returns how many were enqueued. URLs already
+// queued are left as they are, and those no longer accepted, e.g. after their
+// domain was blocked, are skipped. Once the task queue is full the rest go on
+// the schedule queue, which feeds it as it drains, so large domains don't
+// block the caller.
+func (c *Crawler) RecrawlDomain(ctx context.Context, host string) (int, error) {
+	keys, err := c.pageStore.DomainURLs(ctx, host)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.stateStore.UnmarkCrawled(ctx, keys); err != nil {
+		return 0, err
+	}
+	enqueued := 0
+	for _, key := range keys {
+		url, device := storage.SplitPageKey(key)
+		if c.ValidateURL(url) != nil {
+			continue
+		}
+		err := c.submitNoWait(ctx, domain.URLTask{URL: url, Device: device})
+		if errors.Is(err, ErrAlreadyQueued) {
+			continue
+		}
+		if err != nil {
+			return enqueued, err
+		}
+		enqueued++
+	}
+	return enqueued, nil
+}
+
+// ReprocessBelowVersion reprocesses up to limit archived pages whose records
+// were extracted with a schema older than version, and returns how many were
+// reprocessed. Pages that fail are logged and skipped.
+func (c *Crawler) ReprocessBelowVersion(ctx context.Context, version, limit int) (int, error) {
+	keys, err := c.pageStore.URLsBelowSchemaVersion(ctx, version, limit)
+	if err != nil {
+		return 0, err
+	}
+	reprocessed := 0
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return reprocessed, err
+		}
+		if err := c.Reprocess(ctx, key); err != nil {
+			c.logger.Warn("failed to reprocess outdated page", zap.String("url", key), zap.Error(err))
+			continue
+		}
+		reprocessed++
+	}
+	return reprocessed, nil
+}
+
+// handleFailure schedules a retry of a failed crawl, or marks the URL as failed
+// once it is out of retries. screenshot references the failed page, if taken.
+func (c *Crawler) handleFailure(ctx context.Context, task domain.URLTask, crawlErr error, screenshot string) {
+	url, key := task.URL, pageKey(task)
+	var skipped *CrawlSkipped
+	switch {
+	case errors.As(crawlErr, &skipped):
+		c.logger.Info("skipping URL", zap.String("url", url), zap.String("reason", skipped.Reason))
+		c.metrics.IncCrawlSkipped(skipped.Reason)
+		c.runStats.recordSkipped(domainOf(url))
+		c.publishEvent(task, OutcomeSkipped, skipped.Reason)
+		if err := c.pageStore.MarkSkipped(ctx, key, skipped.Reason); err != nil {
+			c.logger.Error("failed to mark URL as skipped", zap.String("url", url), zap.Error(err))
+		}
+		return
+	case errors.Is(crawlErr, ErrCrawlCanceled):
+		// Our own shutdown interrupted the crawl; the URL isn't marked as
+		// crawled, so it can be resubmitted without having used up a retry.
+		c.logger.Info("crawl canceled, not counting as failure", zap.String("url", url))
+		return
+	case errors.Is(crawlErr, ErrCrawlTimeout):
+		c.logger.Warn("crawl timed out", zap.String("url", url), zap.Error(crawlErr))
+		c.metrics.IncErrorsTotal("crawl_timeout")
+	default:
+		c.logger.Warn("failed to crawl", zap.String("url", url), zap.Error(crawlErr))
+		c.metrics.IncErrorsTotal("crawl_failed")
+	}
+	c.runStats.record(domainOf(url), false)
+
+	retryCount, err := c.stateStore.IncrementRetryCount(ctx, key)
+	if err != nil {
+		c.logger.Error("failed to increment retry count", zap.String("url", url), zap.Error(err))
+		return
+	}
+
+	if retryCount >= int64(c.config.MaxRetries) {
+		c.logger.Error("max retries reached, marking as failed", zap.String("url", url))
+		failedData := &domain.PageData{
+			URL:            url,
+			Device:         task.Device,
+			Status:         "failed",
+			FailReason:     crawlErr.Error(),
+			FailScreenshot: screenshot,
+			CrawledAt:      time.Now(),
+		}
+		if err := c.pageStore.SaveData(ctx, failedData); err != nil {
+			c.logger.Error("failed to mark URL as failed in db", zap.String("url", url), zap.Error(err))
+		}
+		c.publishEvent(task, OutcomeFailed, crawlErr.Error())
+	} else {
+		retryAt := time.Now().Add(time.Duration(retryCount*int64(c.config.RetryBackoff)) * time.Second)
+		if err := c.stateStore.ScheduleRetry(ctx, task, retryAt); err != nil {
+			c.logger.Error("failed to schedule retry", zap.String("url", url), zap.Error(err))
+			return
+		}
+		if err := c.pageStore.RecordFailReason(ctx, key, crawlErr.Error(), screenshot); err != nil {
+			c.logger.Error("failed to record fail reason", zap.String("url", url), zap.Error(err))
+		}
+		c.logger.Info("URL will be retried later", zap.String("url", url), zap.Int64("attempt", retryCount), zap.Time("retry_at", retryAt))
+		c.publishEvent(task, OutcomeRetrying, crawlErr.Error())
+	}
+}
+
+// observeExtraction logs and records how much was extracted from a page, so
+// a broken selector or site change shows up as a drop in the distributions.
+func (c *Crawler) observeExtraction(data *domain.PageData) {
+	c.logger.Debug("extracted page data",
+		zap.String("url", data.URL),
+		zap.Int("images", len(data.Images)),
+		zap.Int("headers", len(data.Headers)),
+		zap.Int("meta_tags", len(data.MetaTags)),
+		zap.Int("keywords", len(data.Keywords)),
+		zap.Int("content_length", len(data.Content)),
+	)
+	c.metrics.ObserveExtraction(len(data.Images), len(data.Headers), len(data.MetaTags), len(data.Keywords), len(data.Content))
+	c.emptiness.record(domainOf(data.URL), data.Title == "" || data.Content == "")
+}
+
+func (c *Crawler) extractOptions(host string, extractors []string) extract.Options {
+	opts := extract.DefaultOptions()
+	opts.Contacts = c.config.ExtractContacts
+	opts.Markdown = c.config.ExtractMarkdown
+	opts.MaxNodes = c.config.ExtractMaxNodes
+	opts.MaxContentLength = c.config.ExtractMaxContentLength
+	opts.Fields = c.extractionRules(host)
+	if len(extractors) == 0 {
+		extractors = c.config.ExtractorList
+	}
+	if len(extractors) > 0 {
+		// Validated on submission and at startup
+		opts, _ = opts.Only(extractors)
+		// Naming contacts doesn't bypass the EXTRACT_CONTACTS opt-in
+		opts.Contacts = opts.Contacts && c.config.ExtractContacts
+	}
+	return opts
+}
@@ -0,0 +1,31 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"crawler/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// markDuplicate links a page to the earliest stored page of its device preset
+// with the same content under another URL, such as a mirror or a URL
+// differing only in parameters, and drops its content so it isn't stored
+// twice. Stores hand the content back to a duplicate when the page it points
+// to changes or is deleted.
+func (c *Crawler) markDuplicate(ctx context.Context, data *domain.PageData) {
+	if data.ContentHash == "" {
+		return
+	}
+	canonical, err := c.pageStore.CanonicalURL(ctx, data.ContentHash, storage.PageKey(data.URL, data.Device))
+	if err != nil {
+		if err.Error() != "not_found" {
+			c.logger.Warn("failed to look up duplicate content", zap.String("url", data.URL), zap.Error(err))
+			c.metrics.IncErrorsTotal("duplicate_lookup_failed")
+		}
+		return
+	}
+	c.logger.Debug("page duplicates stored content", zap.String("url", data.URL), zap.String("duplicate_of", canonical))
+	data.DuplicateOf = canonical
+	data.Content = ""
+}
@@ -0,0 +1,65 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/device"
+)
+
+// devicePresets are the devices a crawl can emulate, by the name used in
+// crawl requests. Each sets the viewport, device scale factor, user agent and
+// touch support together. The desktop preset takes the browser's own user
+// agent, so it never falls behind the browser's version.
+var devicePresets = map[string]device.Info{
+	"desktop": {
+		Name:   "Desktop",
+		Width:  1920,
+		Height: 1080,
+		Scale:  1,
+	},
+	"iphone":           device.IPhone15.Device(),
+	"iphone-landscape": device.IPhone15landscape.Device(),
+	"ipad":             device.IPadPro11.Device(),
+	"ipad-landscape":   device.IPadPro11landscape.Device(),
+	"android":          device.Pixel5.Device(),
+}
+
+// ValidateDevice checks the device preset of a crawl request; empty means the
+// browser's own settings.
+func ValidateDevice(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := devicePresets[name]; !ok {
+		names := make([]string, 0, len(devicePresets))
+		for n := range devicePresets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown device %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// deviceAction emulates a device preset. Like the other emulation overrides
+// it must run before navigation.
+func deviceAction(name string) chromedp.Action {
+	info := devicePresets[name]
+	if info.UserAgent != "" {
+		return chromedp.Emulate(info)
+	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, _, _, userAgent, _, err := browser.GetVersion().Do(ctx)
+		if err != nil {
+			return err
+		}
+		// Headless Chrome names itself in its user agent, which sites block
+		info.UserAgent = strings.Replace(userAgent, "HeadlessChrome/", "Chrome/", 1)
+		return chromedp.Emulate(info).Do(ctx)
+	})
+}
@@ -0,0 +1,77 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/monitoring"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// domainLimiter caps the number of simultaneous crawls against a single domain.
+type domainLimiter struct {
+	defaultLimit int
+	overrides    map[string]int
+	metrics      *monitoring.Metrics
+	mu           sync.Mutex
+	sems         map[string]chan struct{}
+}
+
+func newDomainLimiter(defaultLimit int, overrides map[string]int, m *monitoring.Metrics) *domainLimiter {
+	return &domainLimiter{
+		defaultLimit: defaultLimit,
+		overrides:    overrides,
+		metrics:      m,
+		sems:         make(map[string]chan struct{}),
+	}
+}
+
+// semaphore returns the semaphore for a domain, or nil if the domain is unlimited.
+func (l *domainLimiter) semaphore(domain string) chan struct{} {
+	limit, ok := l.overrides[domain]
+	if !ok {
+		limit = l.defaultLimit
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sem, ok := l.sems[domain]
+	if !ok {
+		sem = make(chan struct{}, limit)
+		l.sems[domain] = sem
+	}
+	return sem
+}
+
+// Acquire blocks until a crawl slot for the domain is free or ctx is done.
+func (l *domainLimiter) Acquire(ctx context.Context, domain string) error {
+	if sem := l.semaphore(domain); sem != nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	l.metrics.IncDomainInFlight(domain)
+	return nil
+}
+
+// Release frees a slot previously obtained with Acquire.
+func (l *domainLimiter) Release(domain string) {
+	l.metrics.DecDomainInFlight(domain)
+	if sem := l.semaphore(domain); sem != nil {
+		<-sem
+	}
+}
+
+// domainOf returns the lower-cased host name of a URL.
+func domainOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
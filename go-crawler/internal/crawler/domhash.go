@@ -0,0 +1,28 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"crawler/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// checkDOMChange compares a page's DOM structure hash with the one stored for
+// its device preset and reports a change, which often means a redesign that
+// may break extraction.
+func (c *Crawler) checkDOMChange(ctx context.Context, data *domain.PageData) {
+	if data.DOMHash == "" {
+		return // Not extracted for this crawl
+	}
+	previous, err := c.pageStore.GetDOMHash(ctx, storage.PageKey(data.URL, data.Device))
+	if err != nil {
+		c.logger.Warn("failed to get previous DOM hash", zap.String("url", data.URL), zap.Error(err))
+		return
+	}
+	if previous != "" && previous != data.DOMHash {
+		c.logger.Warn("page DOM structure changed", zap.String("url", data.URL),
+			zap.String("previous_dom_hash", previous), zap.String("dom_hash", data.DOMHash))
+		c.metrics.IncDOMStructureChanges()
+	}
+}
@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"crawler/internal/monitoring"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// minEmptinessSamples is how many pages a domain needs in its window before
+// its empty extraction rate can raise a spike, so one bad page can't.
+const minEmptinessSamples = 10
+
+// emptinessTracker keeps a rolling per-domain rate of pages whose extraction
+// came back empty, which usually means the site changed its markup. Only the
+// most recently crawled domains are tracked, bounding the metric's labels.
+type emptinessTracker struct {
+	mu         sync.Mutex
+	window     int
+	threshold  float64 // 0 disables spike detection
+	maxDomains int
+	domains    map[string]*domainEmptiness
+	metrics    *monitoring.Metrics
+	logger     *zap.Logger
+}
+
+type domainEmptiness struct {
+	outcomes []bool // Ring buffer of the last window pages, true when empty
+	next     int
+	empty    int
+	lastSeen time.Time
+	spiking  bool
+}
+
+func newEmptinessTracker(window int, threshold float64, maxDomains int, m *monitoring.Metrics, l *zap.Logger) *emptinessTracker {
+	return &emptinessTracker{
+		window:     max(window, 1),
+		threshold:  threshold,
+		maxDomains: max(maxDomains, 1),
+		domains:    make(map[string]*domainEmptiness),
+		metrics:    m,
+		logger:     l,
+	}
+}
+
+// record adds a page outcome to the domain's window and reports a spike when
+// the empty rate crosses the threshold. A spike is reported once, until the
+// rate drops back below the threshold.
+func (t *emptinessTracker) record(domain string, empty bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.domains[domain]
+	if !ok {
+		if len(t.domains) >= t.maxDomains {
+			t.evictOldest()
+		}
+		d = &domainEmptiness{outcomes: make([]bool, 0, t.window)}
+		t.domains[domain] = d
+	}
+	d.lastSeen = time.Now()
+
+	if len(d.outcomes) < t.window {
+		d.outcomes = append(d.outcomes, empty)
+	} else {
+		if d.outcomes[d.next] {
+			d.empty--
+		}
+		d.outcomes[d.next] = empty
+		d.next = (d.next + 1) % t.window
+	}
+	if empty {
+		d.empty++
+	}
+
+	rate := float64(d.empty) / float64(len(d.outcomes))
+	t.metrics.SetEmptyExtractionRate(domain, rate)
+
+	if t.threshold <= 0 || len(d.outcomes) < min(minEmptinessSamples, t.window) {
+		return
+	}
+	switch {
+	case rate >= t.threshold && !d.spiking:
+		d.spiking = true
+		t.metrics.IncEmptyExtractionSpikes()
+		t.logger.Warn("empty extraction rate spiked, selectors may no longer match",
+			zap.String("domain", domain), zap.Float64("rate", rate), zap.Int("pages", len(d.outcomes)))
+	case rate < t.threshold && d.spiking:
+		d.spiking = false
+		t.logger.Info("empty extraction rate recovered", zap.String("domain", domain), zap.Float64("rate", rate))
+	}
+}
+
+// evictOldest stops tracking the least recently crawled domain.
+func (t *emptinessTracker) evictOldest() {
+	var oldest string
+	for domain, d := range t.domains {
+		if oldest == "" || d.lastSeen.Before(t.domains[oldest].lastSeen) {
+			oldest = domain
+		}
+	}
+	delete(t.domains, oldest)
+	t.metrics.DeleteEmptyExtractionRate(oldest)
+}
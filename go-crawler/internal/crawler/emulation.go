@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"crawler/internal/domain"
+	"errors"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// ValidateEmulation checks the emulation settings of a crawl request.
+func ValidateEmulation(e *domain.Emulation) error {
+	if e == nil {
+		return nil
+	}
+	if g := e.Geolocation; g != nil {
+		if g.Latitude < -90 || g.Latitude > 90 || g.Longitude < -180 || g.Longitude > 180 {
+			return errors.New("geolocation is out of range")
+		}
+		if g.Accuracy < 0 {
+			return errors.New("geolocation accuracy must not be negative")
+		}
+	}
+	if e.Timezone != "" {
+		if _, err := time.LoadLocation(e.Timezone); err != nil {
+			return errors.New("unknown timezone " + e.Timezone)
+		}
+	}
+	return nil
+}
+
+// emulationActions returns the actions that apply a task's geolocation,
+// timezone and locale overrides. They must run before navigation.
+func emulationActions(e *domain.Emulation) []chromedp.Action {
+	if e == nil {
+		return nil
+	}
+	var actions []chromedp.Action
+	if g := e.Geolocation; g != nil {
+		accuracy := g.Accuracy
+		if accuracy == 0 {
+			accuracy = 100
+		}
+		actions = append(actions,
+			browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation}),
+			emulation.SetGeolocationOverride().WithLatitude(g.Latitude).WithLongitude(g.Longitude).WithAccuracy(accuracy),
+		)
+	}
+	if e.Timezone != "" {
+		actions = append(actions, emulation.SetTimezoneOverride(e.Timezone))
+	}
+	if e.Locale != "" {
+		actions = append(actions, emulation.SetLocaleOverride().WithLocale(e.Locale))
+	}
+	return actions
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrCrawlCanceled is returned when a crawl is interrupted by the crawler
+	// shutting down. It is not a crawl failure and must not consume a retry.
+	ErrCrawlCanceled = errors.New("crawl canceled")
+	// ErrCrawlTimeout is returned when a crawl exceeds its configured timeout.
+	ErrCrawlTimeout = errors.New("crawl timed out")
+	// URL validation errors returned by ValidateURL and Submit.
+	ErrInvalidURL       = errors.New("invalid URL")
+	ErrInvalidScheme    = errors.New("URL scheme must be http or https")
+	ErrURLTooLong       = errors.New("URL is too long")
+	ErrPrivateAddress   = errors.New("URL points to a private or loopback address")
+	ErrBlockedDomain    = errors.New("URL domain is blocked")
+	ErrBlockedExtension = errors.New("URL has a blocked file extension")
+	// ErrRedirectLoop is returned when a page redirects back to a URL already in
+	// its redirect chain, or redirects more often than MAX_REDIRECTS allows.
+	ErrRedirectLoop = errors.New("redirect loop detected")
+	// ErrAllocatorTimeout is returned when no browser frees up within
+	// BROWSER_ACQUIRE_TIMEOUT. The crawl is retried like any other failure.
+	ErrAllocatorTimeout = errors.New("timed out waiting for a browser")
+	// ErrAlreadyQueued is returned by Submit for a URL that is already waiting
+	// on the task queue, even when the crawl is forced.
+	ErrAlreadyQueued = errors.New("URL is already queued")
+	// ErrQueueFull is returned by TrySubmit when the task queue has no room.
+	ErrQueueFull = errors.New("crawl queue is full")
+	// ErrStopping is returned by TrySubmit once the crawler is shutting down.
+	ErrStopping = errors.New("crawler is shutting down")
+	// ErrCrawlSkipped is matched by every CrawlSkipped.
+	ErrCrawlSkipped = errors.New("crawl skipped")
+)
+
+// CrawlSkipped is returned when a URL is deliberately not crawled, e.g.
+// because it was crawled recently. A skip is neither a success nor a failure:
+// it doesn't use up a retry and the URL is not re-queued.
+type CrawlSkipped struct {
+	Reason string // Metric label, e.g. "recently_crawled"
+}
+
+func (e *CrawlSkipped) Error() string {
+	return "crawl skipped: " + e.Reason
+}
+
+func (e *CrawlSkipped) Is(target error) bool {
+	return target == ErrCrawlSkipped
+}
+
+// errSoftBudgetExceeded is the cancellation cause of a crawl preempted by the
+// worker's soft per-cycle budget.
+var errSoftBudgetExceeded = errors.New("soft crawl budget exceeded")
+
+// classifyCrawlError distinguishes our own shutdown (the crawler's root
+// context being canceled) from a genuine timeout of the crawl itself,
+// including preemption by the soft budget of crawlCtx.
+func (c *Crawler) classifyCrawlError(crawlCtx context.Context, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case context.Cause(crawlCtx) == errSoftBudgetExceeded:
+		c.metrics.IncSoftBudgetExceeded()
+		return fmt.Errorf("%w: %s of %ds", ErrCrawlTimeout, errSoftBudgetExceeded, c.config.CrawlSoftBudget)
+	case c.ctx.Err() != nil && errors.Is(err, context.Canceled):
+		return ErrCrawlCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrCrawlTimeout
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"crawler/internal/domain"
+	"sync"
+	"time"
+)
+
+// Crawl outcomes reported as CrawlEvents.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed" // Out of retries
+	OutcomeRetrying  = "retrying"
+	OutcomeSkipped   = "skipped"
+)
+
+// CrawlEvent is the outcome of a crawl attempt, streamed to subscribers.
+type CrawlEvent struct {
+	URL     string
+	Domain  string
+	Outcome string
+	Reason  string // The error of a failed or retried crawl, or why it was skipped
+	At      time.Time
+}
+
+// eventSubscriberBuffer is how many events a subscriber can fall behind by
+// before further events to it are dropped.
+const eventSubscriberBuffer = 256
+
+// eventHub fans crawl events out to subscribers. Publishing never blocks the
+// workers: a subscriber whose buffer is full misses events instead.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan CrawlEvent]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan CrawlEvent]struct{})}
+}
+
+func (h *eventHub) publish(event CrawlEvent) (dropped int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- event:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// Subscribe returns a channel of crawl events from now on, and a function that
+// unsubscribes and closes it.
+func (c *Crawler) Subscribe() (<-chan CrawlEvent, func()) {
+	ch := make(chan CrawlEvent, eventSubscriberBuffer)
+	c.events.mu.Lock()
+	c.events.subs[ch] = struct{}{}
+	c.events.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.events.mu.Lock()
+			delete(c.events.subs, ch)
+			c.events.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// publishEvent reports the outcome of a crawl to its job and to subscribers.
+func (c *Crawler) publishEvent(task domain.URLTask, outcome, reason string) {
+	event := CrawlEvent{URL: task.URL, Domain: domainOf(task.URL), Outcome: outcome, Reason: reason, At: time.Now()}
+	c.jobs.record(task.JobID, outcome, reason, event.At)
+	if dropped := c.events.publish(event); dropped > 0 {
+		c.metrics.AddCrawlEventsDropped(dropped)
+	}
+}
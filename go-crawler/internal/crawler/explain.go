@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"crawler/pkg/extract"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+)
+
+// explainTTL bounds how long the explanation of a finished crawl waits to be
+// picked up, e.g. by a request that timed out waiting for it.
+const explainTTL = 5 * time.Minute
+
+// explainMaxBlocked caps the blocked requests listed in an explanation.
+const explainMaxBlocked = 100
+
+// explanations holds the explanations of crawls run with Explain until the
+// requests that asked for them pick them up. An entry is registered when the
+// crawl starts and finished when it ends, so a reader that sees the crawl's
+// final status can wait for the report.
+type explanations struct {
+	mu    sync.Mutex
+	byURL map[string]*explanationEntry
+}
+
+type explanationEntry struct {
+	exp      *domain.CrawlExplanation
+	done     chan struct{}
+	finished time.Time
+}
+
+func newExplanations() *explanations {
+	return &explanations{byURL: make(map[string]*explanationEntry)}
+}
+
+// start registers the explanation of a crawl of url, replacing any earlier
+// one, and drops finished ones nobody picked up.
+func (e *explanations) start(url string) *domain.CrawlExplanation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for u, entry := range e.byURL {
+		if !entry.finished.IsZero() && time.Since(entry.finished) > explainTTL {
+			delete(e.byURL, u)
+		}
+	}
+	entry := &explanationEntry{exp: &domain.CrawlExplanation{}, done: make(chan struct{})}
+	e.byURL[url] = entry
+	return entry.exp
+}
+
+// finish marks the explanation of url complete.
+func (e *explanations) finish(url string, exp *domain.CrawlExplanation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if entry, ok := e.byURL[url]; ok && entry.exp == exp {
+		entry.finished = time.Now()
+		close(entry.done)
+	}
+}
+
+// Explanation waits for the crawl run with Explain of the page of url, a
+// storage.PageKey, to finish and returns its explanation, which is then
+// forgotten. It returns nil if no such crawl ran, or ctx ends first.
+func (c *Crawler) Explanation(ctx context.Context, url string) *domain.CrawlExplanation {
+	c.explanations.mu.Lock()
+	entry, ok := c.explanations.byURL[url]
+	c.explanations.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+		return nil
+	}
+
+	c.explanations.mu.Lock()
+	defer c.explanations.mu.Unlock()
+	if c.explanations.byURL[url] == entry {
+		delete(c.explanations.byURL, url)
+	}
+	return entry.exp
+}
+
+// waited returns an action that records in the explanation that the crawl
+// got past a wait condition.
+func waited(exp *domain.CrawlExplanation, condition string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		exp.Waits = append(exp.Waits, condition)
+		return nil
+	})
+}
+
+// explainSelectors reports how many elements the selector of each custom
+// field rule matches in html, in field name order.
+func explainSelectors(html string, rules map[string]extract.FieldRule) []domain.SelectorMatch {
+	if len(rules) == 0 {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	matches := make([]domain.SelectorMatch, 0, len(rules))
+	for name, rule := range rules {
+		matches = append(matches, domain.SelectorMatch{
+			Field:    name,
+			Selector: rule.Selector,
+			Matches:  doc.Find(rule.Selector).Length(),
+		})
+	}
+	slices.SortFunc(matches, func(a, b domain.SelectorMatch) int { return strings.Compare(a.Field, b.Field) })
+	return matches
+}
+
+// explainExtraction adds what extraction decided about a page to its crawl's
+// explanation, warning about results that commonly need tuning.
+func (c *Crawler) explainExtraction(exp *domain.CrawlExplanation, data *domain.PageData, html string, opts extract.Options, browserContent int) {
+	exp.ExtractionSource = data.ExtractionSource
+	exp.Selectors = explainSelectors(html, opts.Fields)
+	for _, m := range exp.Selectors {
+		if m.Matches == 0 {
+			exp.Warnings = append(exp.Warnings, fmt.Sprintf("selector of field %q matched nothing", m.Field))
+		}
+	}
+	if exp.StatusCode >= 400 {
+		exp.Warnings = append(exp.Warnings, fmt.Sprintf("page responded with status %d", exp.StatusCode))
+	}
+	if c.config.HTTPFallback && opts.Content && browserContent < c.config.HTTPFallbackMinContent {
+		if data.ExtractionSource == extractionSourceHTTP {
+			exp.Warnings = append(exp.Warnings, fmt.Sprintf("browser extracted %d bytes of content; the plain HTTP fetch extracted more and was used", browserContent))
+		} else {
+			exp.Warnings = append(exp.Warnings, fmt.Sprintf("browser extracted %d bytes of content and the plain HTTP fetch didn't do better", browserContent))
+		}
+	}
+	if opts.Content && data.Content == "" && !data.Gated {
+		exp.Warnings = append(exp.Warnings, "no content was extracted")
+	}
+	if data.Truncated {
+		exp.Warnings = append(exp.Warnings, "extraction was truncated by the node, content or body size caps")
+	}
+	if data.Gated {
+		exp.Warnings = append(exp.Warnings, fmt.Sprintf("page is behind a %s gate, so its content was dropped", data.GateType))
+	}
+}
@@ -1,60 +1,112 @@
-package crawler
-
-import (
-	"crawler/internal/domain"
-	"strings"
-
-	"github.com/PuerkitoBio/goquery"
-)
-
-// ExtractPageData parses HTML content and extracts relevant data.
-func ExtractPageData(url, htmlContent string) (*domain.PageData, error) {
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
-	if err != nil {
-		return nil, err
-	}
-
-	data := &domain.PageData{
-		URL:      url,
-		Title:    doc.Find("title").First().Text(),
-		MetaTags: make(map[string]string),
-		Images:   []string{},
-		Headers:  []string{},
-		Status:   "completed",
-	}
-
-	// Extract Meta Tags
-	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
-		name, _ := s.Attr("name")
-		property, _ := s.Attr("property")
-		content, _ := s.Attr("content")
-		key := name
-		if property != "" {
-			key = property
-		}
-		if key != "" && content != "" {
-			data.MetaTags[key] = content
-		}
-	})
-
-	// Extract Headers
-	doc.Find("h1, h2, h3").Each(func(i int, s *goquery.Selection) {
-		data.Headers = append(data.Headers, s.Text())
-	})
-
-	// Extract Images
-	doc.Find("img").Each(func(i int, s *goquery.Selection) {
-		src, exists := s.Attr("src")
-		if exists && src != "" {
-			data.Images = append(data.Images, src)
-		}
-	})
-
-	// Extract clean body text content
-	doc.Find("script, style").Each(func(i int, s *goquery.Selection) {
-		s.Remove()
-	})
-	data.Content = strings.TrimSpace(doc.Find("body").Text())
-
-	return data, nil
-}
+package crawler
+
+import (
+	"crawler/internal/domain"
+	"crawler/pkg/extract"
+	"io"
+	"strings"
+)
+
+// SchemaVersion is the version of the extracted data schema, stored with every
+// record. Bump it when PageData fields are added or change meaning, so
+// consumers can branch on it and older records can be reprocessed.
+const SchemaVersion = 20
+
+// ExtractPageData parses HTML content and extracts relevant data.
+func ExtractPageData(url, htmlContent string, opts extract.Options) (*domain.PageData, error) {
+	return ExtractPageDataFrom(url, strings.NewReader(htmlContent), opts)
+}
+
+// ExtractPageDataFrom is like ExtractPageData, parsing the HTML as it is read.
+func ExtractPageDataFrom(url string, htmlReader io.Reader, opts extract.Options) (*domain.PageData, error) {
+	extracted, err := extract.ExtractWithOptions(url, htmlReader, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.PageData{
+		URL:         url,
+		Title:       extracted.Title,
+		Content:     extracted.Content,
+		Markdown:    extracted.Markdown,
+		Headers:     extracted.Headers,
+		MetaTags:    extracted.MetaTags,
+		Keywords:    extracted.Keywords,
+		PublishedAt: extracted.PublishedAt,
+		ModifiedAt:  extracted.ModifiedAt,
+		Images:      extracted.Images,
+		Hreflang:    extracted.Hreflang,
+		Feeds:       extracted.Feeds,
+		Links:       extracted.Links,
+		Microdata:   microdataItems(extracted.Microdata),
+		DOMHash:     extracted.DOMHash,
+		ContentHash: extracted.ContentHash,
+		Emails:      extracted.Emails,
+		Phones:      extracted.Phones,
+		Status:      "completed",
+		Truncated:   extracted.Truncated,
+
+		CustomFields:   extracted.CustomFields,
+		StructuredData: structuredData(extracted.StructuredData),
+		SchemaVersion:  SchemaVersion,
+	}, nil
+}
+
+// microdataItems converts extracted microdata items, nested ones included,
+// to their stored form.
+func microdataItems(items []*extract.MicrodataItem) []*domain.MicrodataItem {
+	if items == nil {
+		return nil
+	}
+	converted := make([]*domain.MicrodataItem, len(items))
+	for i, item := range items {
+		converted[i] = microdataItem(item)
+	}
+	return converted
+}
+
+func microdataItem(item *extract.MicrodataItem) *domain.MicrodataItem {
+	converted := &domain.MicrodataItem{Type: item.Type, ID: item.ID, Properties: make(map[string][]any, len(item.Properties))}
+	for name, values := range item.Properties {
+		out := make([]any, len(values))
+		for i, value := range values {
+			if nested, ok := value.(*extract.MicrodataItem); ok {
+				out[i] = microdataItem(nested)
+			} else {
+				out[i] = value
+			}
+		}
+		converted.Properties[name] = out
+	}
+	return converted
+}
+
+// structuredData converts an extracted JSON-LD item report to its stored form.
+func structuredData(data *extract.StructuredData) *domain.StructuredData {
+	if data == nil {
+		return nil
+	}
+	return &domain.StructuredData{
+		Type:    data.Type,
+		Item:    data.Item,
+		Matches: data.Matches,
+		Missing: data.Missing,
+		Valid:   data.Valid,
+	}
+}
+
+// extractionRules returns the custom field rules of host, falling back to
+// those of its closest parent domain.
+func (c *Crawler) extractionRules(host string) map[string]extract.FieldRule {
+	for host != "" {
+		if rules, ok := c.config.ExtractionRules[host]; ok {
+			return rules
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return nil
+		}
+		host = parent
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"context"
+	"crawler/internal/config"
+	"crawler/internal/domain"
+	"crawler/pkg/extract"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"go.uber.org/zap"
+)
+
+// Values of PageData.ExtractionSource.
+const (
+	extractionSourceBrowser = "browser"
+	extractionSourceHTTP    = "http"
+)
+
+// httpFallback re-fetches a page whose browser extraction came back nearly
+// empty over plain HTTP, without running JavaScript, and returns whichever
+// extraction has more content along with the HTML it came from. Server-side
+// rendered content is sometimes hidden or removed by the page's scripts, or by
+// a script error. The browser result wins ties and fallback errors.
+func (c *Crawler) httpFallback(ctx context.Context, pageURL, proxyURL string, headers config.DomainHeaders, opts extract.Options, browserData *domain.PageData, browserHTML string) (*domain.PageData, string) {
+	fallbackData, html, err := c.fetchPlainPage(ctx, pageURL, proxyURL, headers, opts)
+	if err == nil {
+		if len(fallbackData.Content) <= len(browserData.Content) {
+			c.metrics.IncHTTPFallbacks(extractionSourceBrowser)
+			return browserData, browserHTML
+		}
+		c.logger.Info("plain HTTP fetch extracted more than the browser", zap.String("url", pageURL),
+			zap.Int("browser_content_length", len(browserData.Content)), zap.Int("http_content_length", len(fallbackData.Content)))
+		c.metrics.IncHTTPFallbacks(extractionSourceHTTP)
+		fallbackData.ExtractionSource = extractionSourceHTTP
+		return fallbackData, html
+	}
+	c.logger.Warn("plain HTTP fallback failed", zap.String("url", pageURL), zap.Error(err))
+	c.metrics.IncHTTPFallbacks("error")
+	return browserData, browserHTML
+}
+
+// fetchPlainPage fetches a page with a plain HTTP client, through the same
+// proxy and host overrides and with the same custom headers and cookies as the
+// browser, and extracts it as the body is read. At most HTTP_FALLBACK_MAX_BODY
+// bytes are read; a larger body is extracted from its truncated HTML, which is
+// flagged on the result, and the rest of it is never downloaded. The HTML is
+// only returned when it is stored or checked for gates.
+func (c *Crawler) fetchPlainPage(ctx context.Context, pageURL, proxyURL string, headers config.DomainHeaders, opts extract.Options) (*domain.PageData, string, error) {
+	resp, err := c.plainGet(ctx, pageURL, proxyURL, headers, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("plain HTTP fetch returned status %d", resp.StatusCode)
+	}
+	if err := decodeBody(resp); err != nil {
+		return nil, "", err
+	}
+
+	// The HTML is copied aside while parsing only when something needs it
+	var html strings.Builder
+	limit := c.config.HTTPFallbackMaxBody
+	body := io.LimitReader(resp.Body, limit)
+	if c.config.StoreRawHTML || c.config.GateDetection {
+		body = io.TeeReader(body, &html)
+	}
+	pageData, err := ExtractPageDataFrom(pageURL, body, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	// One byte past the limit tells a truncated body; closing it drops the rest
+	if n, _ := io.ReadFull(resp.Body, make([]byte, 1)); n > 0 {
+		pageData.Truncated = true
+		c.metrics.IncHTTPFallbackTruncated()
+		c.logger.Warn("plain HTTP response truncated at the size limit", zap.String("url", pageURL),
+			zap.Int64("max_body", limit), zap.Int64("content_length", resp.ContentLength))
+	}
+	pageData.ETag = resp.Header.Get("ETag")
+	pageData.LastModified = resp.Header.Get("Last-Modified")
+	return pageData, html.String(), nil
+}
+
+// plainGet requests a page with a plain HTTP client, through the same proxy
+// and host overrides and with the same custom headers and cookies as the
+// browser, adding the extra headers given. The caller must close the
+// response body.
+func (c *Crawler) plainGet(ctx context.Context, pageURL, proxyURL string, headers config.DomainHeaders, extra map[string]string) (*http.Response, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if len(c.config.HostOverrides) > 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if host, port, err := net.SplitHostPort(addr); err == nil {
+				if ip, ok := c.config.HostOverrides[host]; ok {
+					addr = net.JoinHostPort(ip, port)
+				}
+			}
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(c.config.CrawlTimeout) * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > c.config.MaxRedirects {
+				return ErrRedirectLoop
+			}
+			return nil
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.proxyManager.GetUserAgent())
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	// Asking ourselves turns off the transport's transparent gzip decoding,
+	// so decodeBody handles both
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	for name, value := range headers.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range headers.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	for name, value := range extra {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		transport.CloseIdleConnections()
+		return nil, err
+	}
+	// The client is of this request only, so its connection goes with the body
+	resp.Body = &closeIdleBody{ReadCloser: resp.Body, transport: transport}
+	return resp, nil
+}
+
+// decodeBody replaces a response body with its decoded content, following
+// its Content-Encoding. Some sites serve brotli whatever was asked for.
+func decodeBody(resp *http.Response) error {
+	var decoded io.Reader
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("invalid gzip response body: %w", err)
+		}
+		decoded = zr
+	case "br":
+		decoded = brotli.NewReader(resp.Body)
+	default:
+		return fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	resp.Body = &decodedBody{Reader: decoded, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decodedBody reads a decoder of an encoded response body, closing the body.
+type decodedBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	return b.body.Close()
+}
+
+// closeIdleBody closes the idle connections of a single-use transport once
+// its response body is closed.
+type closeIdleBody struct {
+	io.ReadCloser
+	transport *http.Transport
+}
+
+func (b *closeIdleBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.transport.CloseIdleConnections()
+	return err
+}
@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crawler/internal/config"
+	"crawler/internal/proxy"
+	"crawler/pkg/extract"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+const encodedPage = `<html><head><title>Encoded page</title></head><body><p>Served compressed.</p></body></html>`
+
+func TestFetchPlainPageEncodings(t *testing.T) {
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"":     nil,
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"br":   func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+	}
+	for encoding, newEncoder := range encoders {
+		t.Run("encoding "+encoding, func(t *testing.T) {
+			body := []byte(encodedPage)
+			if newEncoder != nil {
+				var buf bytes.Buffer
+				w := newEncoder(&buf)
+				if _, err := w.Write(body); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				body = buf.Bytes()
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.Header.Get("Accept-Encoding"), "br") {
+					t.Errorf("Accept-Encoding = %q, want brotli included", r.Header.Get("Accept-Encoding"))
+				}
+				if encoding != "" {
+					w.Header().Set("Content-Encoding", encoding)
+				}
+				w.Header().Set("Content-Type", "text/html")
+				w.Write(body)
+			}))
+			defer srv.Close()
+
+			c := &Crawler{
+				config:       &config.Config{CrawlTimeout: 10, MaxRedirects: 5, HTTPFallbackMaxBody: 1 << 20},
+				proxyManager: proxy.NewManager(),
+			}
+			data, _, err := c.fetchPlainPage(context.Background(), srv.URL, "", config.DomainHeaders{}, extract.DefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.Title != "Encoded page" || !strings.Contains(data.Content, "Served compressed.") {
+				t.Fatalf("got title %q and content %q, want the decoded page", data.Title, data.Content)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyUnsupported(t *testing.T) {
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"zstd"}},
+		Body:   io.NopCloser(strings.NewReader("")),
+	}
+	if err := decodeBody(resp); err == nil {
+		t.Fatal("zstd body decoded, want an unsupported encoding error")
+	}
+}
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"crawler/internal/config"
+	"crawler/internal/domain"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Gate types found by the built-in heuristics.
+const (
+	gateLogin   = "login"
+	gatePaywall = "paywall"
+)
+
+// Common paywall markers. Sites often ship them on open pages too, so they only
+// count on pages with little content.
+const paywallSelectors = `[class*="paywall"], [id*="paywall"], [class*="subscriber-only"], [class*="premium-content"]`
+
+var paywallTexts = []string{
+	"subscribe to continue",
+	"subscribe to read",
+	"subscribers only",
+	"already a subscriber",
+}
+
+// gateMarkersFor returns the gate markers configured for host or its closest
+// parent domain.
+func (c *Crawler) gateMarkersFor(host string) (config.GateMarkers, bool) {
+	for host != "" {
+		if markers, ok := c.config.GateMarkers[host]; ok {
+			return markers, true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+	}
+	return config.GateMarkers{}, false
+}
+
+// flagGate checks whether a page landed on a login or paywall gate instead of
+// its content. A gated page is flagged and its content, which is the wall's,
+// dropped. The built-in heuristics only apply when the content was extracted,
+// as they depend on its length. It returns the gate type, if any.
+func (c *Crawler) flagGate(data *domain.PageData, htmlContent string, contentExtracted bool) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return ""
+	}
+	short := contentExtracted && len(data.Content) < c.config.GateMaxContent
+	gate := c.detectGate(doc, domainOf(data.URL), short)
+	if gate == "" {
+		return ""
+	}
+	data.Gated, data.GateType = true, gate
+	data.Content, data.ContentHash, data.Markdown = "", "", ""
+	return gate
+}
+
+func (c *Crawler) detectGate(doc *goquery.Document, host string, short bool) string {
+	markers, hasMarkers := c.gateMarkersFor(host)
+	if !hasMarkers && !short {
+		return ""
+	}
+	doc.Find("script, style, noscript, template").Remove()
+	text := strings.ToLower(doc.Find("body").Text())
+
+	if hasMarkers {
+		for _, selector := range markers.Selectors {
+			if doc.Find(selector).Length() > 0 {
+				return markers.Type
+			}
+		}
+		for _, t := range markers.Texts {
+			if strings.Contains(text, strings.ToLower(t)) {
+				return markers.Type
+			}
+		}
+	}
+	if !short {
+		return ""
+	}
+	if doc.Find(`input[type="password"]`).Length() > 0 {
+		return gateLogin
+	}
+	if doc.Find(paywallSelectors).Length() > 0 {
+		return gatePaywall
+	}
+	for _, t := range paywallTexts {
+		if strings.Contains(text, t) {
+			return gatePaywall
+		}
+	}
+	return ""
+}
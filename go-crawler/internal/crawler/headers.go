@@ -0,0 +1,146 @@
+package crawler
+
+import (
+	"crawler/internal/config"
+	"crawler/internal/domain"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"go.uber.org/zap"
+)
+
+// domainHeaderSet holds the per-domain headers and cookies of
+// DOMAIN_HEADERS_FILE, swapped atomically when the file is reloaded.
+type domainHeaderSet struct {
+	byDomain atomic.Pointer[map[string]config.DomainHeaders]
+	modTime  time.Time // Of the loaded file; only used by the reload job
+}
+
+func newDomainHeaderSet(initial map[string]config.DomainHeaders) *domainHeaderSet {
+	s := &domainHeaderSet{}
+	s.byDomain.Store(&initial)
+	return s
+}
+
+// forHost returns the headers and cookies of host, falling back to those of
+// its closest parent domain.
+func (s *domainHeaderSet) forHost(host string) config.DomainHeaders {
+	byDomain := *s.byDomain.Load()
+	for host != "" {
+		if h, ok := byDomain[host]; ok {
+			return h
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+	}
+	return config.DomainHeaders{}
+}
+
+// startDomainHeadersReload re-reads DOMAIN_HEADERS_FILE whenever its
+// modification time changes. A file that fails to load is logged and the
+// previous headers are kept.
+func (c *Crawler) startDomainHeadersReload() {
+	path := c.config.DomainHeadersFile
+	if info, err := os.Stat(path); err == nil {
+		c.siteHeaders.modTime = info.ModTime()
+	}
+	ticker := time.NewTicker(time.Duration(c.config.DomainHeadersReloadInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			c.logger.Warn("failed to stat domain headers file", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		if info.ModTime().Equal(c.siteHeaders.modTime) {
+			continue
+		}
+		byDomain, err := config.LoadDomainHeaders(path)
+		if err != nil {
+			c.logger.Error("failed to reload domain headers, keeping the previous ones", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		c.siteHeaders.modTime = info.ModTime()
+		c.siteHeaders.byDomain.Store(&byDomain)
+		c.logger.Info("reloaded domain headers", zap.String("path", path), zap.Int("domains", len(byDomain)))
+	}
+}
+
+// taskHeaders merges the configured headers and cookies of the task's domain
+// with the task's own, which take precedence.
+func (c *Crawler) taskHeaders(task domain.URLTask, host string) config.DomainHeaders {
+	configured := c.siteHeaders.forHost(host)
+	merged := config.DomainHeaders{
+		Headers: make(map[string]string, len(configured.Headers)+len(task.Headers)),
+		Cookies: make(map[string]string, len(configured.Cookies)+len(task.Cookies)),
+	}
+	for _, headers := range []map[string]string{configured.Headers, task.Headers} {
+		for name, value := range headers {
+			merged.Headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	for _, cookies := range []map[string]string{configured.Cookies, task.Cookies} {
+		for name, value := range cookies {
+			merged.Cookies[name] = value
+		}
+	}
+	if len(merged.Headers) > 0 || len(merged.Cookies) > 0 {
+		c.logger.Debug("sending custom headers", zap.String("url", task.URL),
+			zap.Any("headers", redactHeaders(merged.Headers)), zap.Strings("cookies", cookieNames(merged.Cookies)))
+	}
+	return merged
+}
+
+// setCookies returns the actions that store cookies for the page's URL
+// before it is loaded.
+func setCookies(pageURL string, cookies map[string]string) []chromedp.Action {
+	actions := make([]chromedp.Action, 0, len(cookies))
+	for name, value := range cookies {
+		actions = append(actions, network.SetCookie(name, value).WithURL(pageURL))
+	}
+	return actions
+}
+
+// sensitiveHeaderParts mark header names whose values must not be logged.
+var sensitiveHeaderParts = []string{"auth", "cookie", "token", "secret", "key", "session", "signature"}
+
+// redactHeaders returns a copy of headers safe to log, with the values of
+// credential-like headers replaced.
+func redactHeaders(headers map[string]string) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		lower := strings.ToLower(name)
+		for _, part := range sensitiveHeaderParts {
+			if strings.Contains(lower, part) {
+				value = "[redacted]"
+				break
+			}
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// cookieNames lists the names of cookies, whose values are never logged.
+func cookieNames(cookies map[string]string) []string {
+	names := make([]string, 0, len(cookies))
+	for name := range cookies {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,26 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// scheduleDiscovered queues URLs found on a crawled page with the options of
+// the page's task. They neither follow alternates of their own nor count
+// towards its job.
+func (c *Crawler) scheduleDiscovered(ctx context.Context, parent domain.URLTask, urls []string) {
+	for _, u := range urls {
+		if u = c.NormalizeURL(u, parent.SPANavigation); u == parent.URL {
+			continue
+		}
+		task := parent
+		task.URL, task.Referer = u, parent.URL
+		task.ForceCrawl, task.FollowHreflang, task.JobID, task.Retry = false, false, "", false
+		if err := c.submitNoWait(ctx, task); err != nil && !errors.Is(err, ErrAlreadyQueued) {
+			c.logger.Warn("failed to queue discovered URL", zap.String("url", u), zap.String("from", parent.URL), zap.Error(err))
+		}
+	}
+}
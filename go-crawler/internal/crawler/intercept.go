@@ -0,0 +1,145 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// requestInterceptor pauses the browser's requests through the Fetch domain
+// to abort those to blocked hosts, such as ad networks and trackers, to add
+// the crawl's custom headers to requests for the page's own host, and to
+// answer proxy authentication challenges with the credentials from the proxy
+// URL, since Chrome ignores credentials in --proxy-server. Every paused
+// request must be continued or failed, so a single listener handles all.
+type requestInterceptor struct {
+	ctx      context.Context
+	pageHost string // Never blocked, so a crawl can't block its own page
+	blocked  map[string]bool
+	headers  map[string]string // Only sent to pageHost, so credentials don't leak to third parties
+	auth     bool
+	username string
+	password string
+
+	blockedCount atomic.Int64
+
+	// URLs of blocked requests, only kept for crawls run with explain
+	keepBlocked bool
+	mu          sync.Mutex
+	blockedURLs []string
+}
+
+// newRequestInterceptor returns an interceptor for a crawl of pageHost, or
+// nil if there are no hosts to block, no headers to add and the proxy has no
+// credentials.
+func newRequestInterceptor(ctx context.Context, pageHost, proxy string, blocked map[string]bool, headers map[string]string) *requestInterceptor {
+	i := &requestInterceptor{ctx: ctx, pageHost: pageHost, blocked: blocked, headers: headers}
+	if u, err := url.Parse(proxy); err == nil && u.User != nil {
+		i.auth = true
+		i.username = u.User.Username()
+		i.password, _ = u.User.Password()
+	}
+	if !i.auth && len(blocked) == 0 && len(headers) == 0 {
+		return nil
+	}
+	return i
+}
+
+// enable returns the action that turns on request interception.
+func (i *requestInterceptor) enable() chromedp.Action {
+	return fetch.Enable().WithHandleAuthRequests(i.auth)
+}
+
+func (i *requestInterceptor) listen(ev interface{}) {
+	switch ev := ev.(type) {
+	case *fetch.EventRequestPaused:
+		if i.isBlocked(ev.Request.URL) {
+			i.blockedCount.Add(1)
+			if i.keepBlocked {
+				i.mu.Lock()
+				if len(i.blockedURLs) < explainMaxBlocked {
+					i.blockedURLs = append(i.blockedURLs, ev.Request.URL)
+				}
+				i.mu.Unlock()
+			}
+			go i.run(fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient))
+			return
+		}
+		if len(i.headers) > 0 && domainOf(ev.Request.URL) == i.pageHost {
+			go i.run(fetch.ContinueRequest(ev.RequestID).WithHeaders(i.withHeaders(ev.Request.Headers)))
+			return
+		}
+		go i.run(fetch.ContinueRequest(ev.RequestID))
+	case *fetch.EventAuthRequired:
+		resp := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+		if i.auth && ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+			resp = &fetch.AuthChallengeResponse{
+				Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+				Username: i.username,
+				Password: i.password,
+			}
+		}
+		go i.run(fetch.ContinueWithAuth(ev.RequestID, resp))
+	}
+}
+
+// isBlocked reports whether the request's host or one of its parent domains
+// is blocked.
+func (i *requestInterceptor) isBlocked(rawURL string) bool {
+	host := domainOf(rawURL)
+	if host == i.pageHost {
+		return false
+	}
+	for host != "" {
+		if i.blocked[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return false
+		}
+		host = parent
+	}
+	return false
+}
+
+// withHeaders returns a request's headers with the custom headers added,
+// replacing any the browser set under the same name.
+func (i *requestInterceptor) withHeaders(original network.Headers) []*fetch.HeaderEntry {
+	entries := make([]*fetch.HeaderEntry, 0, len(original)+len(i.headers))
+	for name, value := range original {
+		if _, ok := i.headers[http.CanonicalHeaderKey(name)]; ok {
+			continue
+		}
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+	}
+	for name, value := range i.headers {
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	return entries
+}
+
+// blockedRequests returns how many requests were aborted.
+func (i *requestInterceptor) blockedRequests() int {
+	return int(i.blockedCount.Load())
+}
+
+// blockedList returns the URLs of the blocked requests, when kept.
+func (i *requestInterceptor) blockedList() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]string(nil), i.blockedURLs...)
+}
+
+// run executes an action from an event listener, which must not block.
+func (i *requestInterceptor) run(action chromedp.Action) {
+	_ = chromedp.Run(i.ctx, action)
+}
@@ -0,0 +1,149 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+)
+
+// Job states.
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobPartial   = "partial" // The deadline passed before every URL was crawled
+)
+
+// skipReasonJobDeadline marks the URLs dropped because their job's deadline passed.
+const skipReasonJobDeadline = "job_deadline"
+
+// jobRetention is how long a job's status stays available after it ends.
+const jobRetention = time.Hour
+
+// JobStatus is the progress of a crawl submission with a deadline. URLs queued
+// when the deadline passes are abandoned instead of crawled; crawls already
+// under way still finish.
+type JobStatus struct {
+	ID         string     `json:"id"`
+	State      string     `json:"state"`
+	Deadline   time.Time  `json:"deadline"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	URLs       int        `json:"urls"` // Accepted for crawling
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	Skipped    int        `json:"skipped"`
+	Abandoned  int        `json:"abandoned"`
+	Pending    int        `json:"pending"` // Queued, being crawled or awaiting a retry
+}
+
+// jobTracker follows the URLs of jobs until they are crawled, by the job ID
+// their tasks carry through retries.
+type jobTracker struct {
+	mu   sync.Mutex
+	jobs map[string]*JobStatus
+}
+
+func newJobTracker() *jobTracker {
+	return &jobTracker{jobs: make(map[string]*JobStatus)}
+}
+
+func (t *jobTracker) create(id string, deadline, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for jobID, job := range t.jobs {
+		if end := job.endedAt(now); end != nil && now.Sub(*end) > jobRetention {
+			delete(t.jobs, jobID)
+		}
+	}
+	t.jobs[id] = &JobStatus{ID: id, Deadline: deadline, CreatedAt: now}
+}
+
+// assign counts a URL accepted for a job.
+func (t *jobTracker) assign(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if job, ok := t.jobs[id]; ok {
+		job.URLs++
+		job.Pending++
+	}
+}
+
+// expired reports whether id is a job whose deadline has passed.
+func (t *jobTracker) expired(id string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.jobs[id]
+	return ok && now.After(job.Deadline)
+}
+
+// record counts the final outcome of a crawl towards its job, if any.
+// Retries keep the URL pending.
+func (t *jobTracker) record(id, outcome, reason string, now time.Time) {
+	if outcome == OutcomeRetrying || id == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.jobs[id]
+	if !ok {
+		return
+	}
+	switch {
+	case outcome == OutcomeSucceeded:
+		job.Succeeded++
+	case outcome == OutcomeFailed:
+		job.Failed++
+	case reason == skipReasonJobDeadline:
+		job.Abandoned++
+	default:
+		job.Skipped++
+	}
+	job.Pending--
+	if job.Pending == 0 && job.FinishedAt == nil {
+		job.FinishedAt = &now
+	}
+}
+
+func (t *jobTracker) get(id string, now time.Time) (JobStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.jobs[id]
+	if !ok {
+		return JobStatus{}, false
+	}
+	status := *job
+	switch {
+	case job.Pending == 0 && job.Abandoned == 0:
+		status.State = JobCompleted
+	case now.After(job.Deadline):
+		status.State = JobPartial
+		if status.FinishedAt == nil {
+			status.FinishedAt = &status.Deadline
+		}
+	default:
+		status.State = JobRunning
+	}
+	return status, true
+}
+
+// endedAt returns when the job completed or its deadline passed, or nil while
+// it is running.
+func (j *JobStatus) endedAt(now time.Time) *time.Time {
+	if j.Pending == 0 && j.FinishedAt != nil {
+		return j.FinishedAt
+	}
+	if now.After(j.Deadline) {
+		return &j.Deadline
+	}
+	return nil
+}
+
+// CreateJob starts tracking a crawl submission whose URLs must be crawled by
+// deadline. Its tasks carry the job's ID.
+func (c *Crawler) CreateJob(id string, deadline time.Time) {
+	c.jobs.create(id, deadline, time.Now())
+}
+
+// Job returns the status of a job, if it is known to this instance.
+func (c *Crawler) Job(id string) (JobStatus, bool) {
+	return c.jobs.get(id, time.Now())
+}
@@ -0,0 +1,193 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/config"
+	"crawler/internal/domain"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrLoginFailed is returned when a domain's login flow doesn't reach its
+	// success indicator. The crawl is retried like any other failure.
+	ErrLoginFailed = errors.New("login failed")
+	// ErrSessionExpired is returned when a crawl using a cached session lands
+	// on the login form. The session is dropped, so the retry logs in again.
+	ErrSessionExpired = errors.New("login session expired")
+)
+
+// loginSession holds the cookies of a logged-in session for reuse across a
+// domain's crawls. Its lock is held while logging in, so concurrent crawls
+// wait for one login instead of each running their own.
+type loginSession struct {
+	mu      sync.Mutex
+	cookies []*network.CookieParam
+	expires time.Time
+}
+
+// loginSessions maps the domains of LOGIN_FLOWS_FILE to their sessions.
+type loginSessions struct {
+	mu       sync.Mutex
+	byDomain map[string]*loginSession
+}
+
+func newLoginSessions() *loginSessions {
+	return &loginSessions{byDomain: make(map[string]*loginSession)}
+}
+
+func (s *loginSessions) get(flowDomain string) *loginSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.byDomain[flowDomain]
+	if !ok {
+		session = &loginSession{}
+		s.byDomain[flowDomain] = session
+	}
+	return session
+}
+
+// loginFlowFor returns the login flow of host, falling back to that of its
+// closest parent domain, along with the domain it is configured for.
+func (c *Crawler) loginFlowFor(host string) (string, config.LoginFlow, bool) {
+	for host != "" {
+		if flow, ok := c.config.LoginFlows[host]; ok {
+			return host, flow, true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+	}
+	return "", config.LoginFlow{}, false
+}
+
+// ensureLogin makes the browser of taskCtx logged in to host, if it has a
+// login flow: the cached session's cookies are set while it lasts, otherwise
+// the flow is run in a separate tab, so the crawl's own tab and its network
+// listeners only see the crawled page. The tab goes through the same proxy,
+// headers and resource blocking as a crawl of the login page.
+func (c *Crawler) ensureLogin(taskCtx context.Context, host, proxyURL string) error {
+	flowDomain, flow, ok := c.loginFlowFor(host)
+	if !ok {
+		return nil
+	}
+	session := c.logins.get(flowDomain)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if time.Now().Before(session.expires) {
+		return chromedp.Run(taskCtx, network.SetCookies(session.cookies))
+	}
+
+	// Start the browser, so the login tab opens in it rather than its own
+	if err := chromedp.Run(taskCtx); err != nil {
+		return err
+	}
+	loginCtx, cancel := chromedp.NewContext(taskCtx)
+	defer cancel()
+	loginHost := domainOf(flow.URL)
+	headers := c.taskHeaders(domain.URLTask{URL: flow.URL}, loginHost)
+	actions := setCookies(flow.URL, headers.Cookies)
+	if interceptor := newRequestInterceptor(loginCtx, loginHost, proxyURL, c.config.BlockedResourceDomainSet, headers.Headers); interceptor != nil {
+		chromedp.ListenTarget(loginCtx, interceptor.listen)
+		actions = append([]chromedp.Action{interceptor.enable()}, actions...)
+	}
+
+	c.logger.Info("logging in", zap.String("domain", flowDomain), zap.String("login_url", flow.URL))
+	var browserCookies []*network.Cookie
+	err := chromedp.Run(loginCtx, append(actions,
+		chromedp.Navigate(flow.URL),
+		chromedp.WaitVisible(flow.UsernameSelector, chromedp.ByQuery),
+		chromedp.SendKeys(flow.UsernameSelector, flow.Username, chromedp.ByQuery),
+		chromedp.SendKeys(flow.PasswordSelector, flow.Password, chromedp.ByQuery),
+		chromedp.Click(flow.SubmitSelector, chromedp.ByQuery),
+		chromedp.WaitVisible(flow.SuccessSelector, chromedp.ByQuery),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			browserCookies, err = storage.GetCookies().Do(ctx)
+			return err
+		}),
+	)...)
+	if err != nil {
+		c.metrics.IncErrorsTotal("login_failed")
+		if taskCtx.Err() != nil {
+			return err // Left to classifyCrawlError
+		}
+		return fmt.Errorf("%w for %s: %v", ErrLoginFailed, flowDomain, err)
+	}
+
+	session.cookies = make([]*network.CookieParam, 0, len(browserCookies))
+	session.expires = time.Now().Add(time.Duration(flow.SessionTTL) * time.Second)
+	for _, bc := range browserCookies {
+		session.cookies = append(session.cookies, &network.CookieParam{
+			Name: bc.Name, Value: bc.Value, Domain: bc.Domain, Path: bc.Path,
+			Secure: bc.Secure, HTTPOnly: bc.HTTPOnly, SameSite: bc.SameSite,
+		})
+		if !bc.Session && bc.Expires > 0 {
+			if expires := time.Unix(0, int64(bc.Expires*float64(time.Second))); expires.Before(session.expires) {
+				session.expires = expires
+			}
+		}
+	}
+	c.logger.Info("logged in", zap.String("domain", flowDomain), zap.Int("cookies", len(session.cookies)),
+		zap.Time("session_expires", session.expires))
+	return nil
+}
+
+// checkSession reports ErrSessionExpired and drops the cached session when a
+// crawled page of a domain with a login flow, other than the login page itself,
+// was redirected to the login page or matches the flow's logged-out selector.
+// Pages that merely have a login box, such as in their header, don't count.
+func (c *Crawler) checkSession(pageURL, finalURL, host, htmlContent string) error {
+	flowDomain, flow, ok := c.loginFlowFor(host)
+	if !ok || sameLoginPage(pageURL, flow.URL) {
+		return nil
+	}
+	if finalURL != "" && sameLoginPage(finalURL, flow.URL) {
+		return c.expireSession(flowDomain)
+	}
+	if flow.LoggedOutSelector == "" {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil || doc.Find(flow.LoggedOutSelector).Length() == 0 {
+		return nil
+	}
+	return c.expireSession(flowDomain)
+}
+
+// sameLoginPage reports whether pageURL is the login URL, ignoring its query
+// and fragment, such as the "?next=" of a redirect to the login form.
+func sameLoginPage(pageURL, loginURL string) bool {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	login, err := url.Parse(loginURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(page.Host, login.Host) && strings.TrimSuffix(page.Path, "/") == strings.TrimSuffix(login.Path, "/")
+}
+
+// expireSession drops the cached session of a login flow's domain, so the next
+// crawl logs in again, and returns the ErrSessionExpired to retry with.
+func (c *Crawler) expireSession(flowDomain string) error {
+	session := c.logins.get(flowDomain)
+	session.mu.Lock()
+	session.expires = time.Time{}
+	session.mu.Unlock()
+	return fmt.Errorf("%w for %s", ErrSessionExpired, flowDomain)
+}
@@ -0,0 +1,31 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// navigate loads url in the current tab, sending referrer as the Referer of
+// the navigation request when it is set.
+func navigate(url, referrer string) chromedp.Action {
+	if referrer == "" {
+		return chromedp.Navigate(url)
+	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// RunResponse waits for the navigation to finish loading, like chromedp.Navigate
+		_, err := chromedp.RunResponse(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			_, _, errorText, _, err := page.Navigate(url).WithReferrer(referrer).Do(ctx)
+			if err != nil {
+				return err
+			}
+			if errorText != "" {
+				return fmt.Errorf("page load error %s", errorText)
+			}
+			return nil
+		}))
+		return err
+	})
+}
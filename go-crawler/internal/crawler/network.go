@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// networkStats accumulates request counts and transferred bytes from the
+// browser's network events during a single crawl.
+type networkStats struct {
+	mu         sync.Mutex
+	requests   int
+	bytes      int64
+	statusCode int64 // HTTP status of the main document response
+
+	// Validators of the main document response, for conditional recrawls
+	etag         string
+	lastModified string
+
+	// Time from the main document request, including redirects, to its response
+	docRequestID network.RequestID
+	docStart     time.Time
+	responseTime time.Duration
+}
+
+// listen is registered via chromedp.ListenTarget and is invoked for every
+// event emitted by the target.
+func (n *networkStats) listen(ev interface{}) {
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		n.mu.Lock()
+		n.requests++
+		if n.docRequestID == "" && e.Type == network.ResourceTypeDocument && e.Timestamp != nil {
+			n.docRequestID = e.RequestID
+			n.docStart = e.Timestamp.Time()
+		}
+		n.mu.Unlock()
+	case *network.EventLoadingFinished:
+		n.mu.Lock()
+		n.bytes += int64(e.EncodedDataLength)
+		n.mu.Unlock()
+	case *network.EventResponseReceived:
+		if e.Type != network.ResourceTypeDocument {
+			return
+		}
+		n.mu.Lock()
+		if n.statusCode == 0 { // Ignore documents of iframes loaded later
+			n.statusCode = e.Response.Status
+			n.etag = headerValue(e.Response.Headers, "ETag")
+			n.lastModified = headerValue(e.Response.Headers, "Last-Modified")
+			if e.RequestID == n.docRequestID && e.Timestamp != nil {
+				n.responseTime = e.Timestamp.Time().Sub(n.docStart)
+			}
+		}
+		n.mu.Unlock()
+	}
+}
+
+func (n *networkStats) snapshot() (int, int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.requests, n.bytes
+}
+
+func (n *networkStats) status() int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.statusCode
+}
+
+// validators returns the ETag and Last-Modified of the main document
+// response, empty when it sent none.
+func (n *networkStats) validators() (string, string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.etag, n.lastModified
+}
+
+// headerValue returns the value of a response header, whose name the browser
+// reports in the case the server sent it.
+func headerValue(headers network.Headers, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			s, _ := value.(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// responseDuration returns how long the main document took to respond, or 0
+// if it never did.
+func (n *networkStats) responseDuration() time.Duration {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.responseTime
+}
+
+// statusClass buckets an HTTP status into a low-cardinality metric label,
+// e.g. "2xx", or "none" when no response was received.
+func statusClass(code int64) string {
+	if code < 100 || code > 599 {
+		return "none"
+	}
+	return fmt.Sprintf("%dxx", code/100)
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Query normalization policies, set with URL_QUERY_POLICY.
+const (
+	QueryPolicyKeep  = "keep"  // Query strings are significant and kept as given
+	QueryPolicySort  = "sort"  // Parameters are sorted, so ?b=2&a=1 and ?a=1&b=2 are the same page
+	QueryPolicyStrip = "strip" // Query strings are dropped entirely
+)
+
+// NormalizeURL returns the canonical form of a URL used for deduplication and
+// storage, so variants of the same document are crawled once. Dot segments
+// and repeated slashes are removed from the path. Fragments are dropped since
+// they never reach the server, except client-side routes ("#/path", "#!path")
+// when spa is set. Unparseable URLs are returned as is.
+func (c *Crawler) NormalizeURL(rawURL string, spa bool) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	// Cleaned in its escaped form, so encoded slashes stay segment content
+	if escaped := u.EscapedPath(); strings.HasPrefix(escaped, "/") {
+		if cleaned := cleanPath(escaped); cleaned != escaped {
+			if unescaped, err := url.PathUnescape(cleaned); err == nil {
+				u.Path, u.RawPath = unescaped, cleaned
+			}
+		}
+	}
+
+	isRoute := strings.HasPrefix(u.Fragment, "/") || strings.HasPrefix(u.Fragment, "!")
+	if !isRoute || (!spa && !c.config.SPADomainSet[u.Hostname()]) {
+		u.Fragment, u.RawFragment = "", ""
+	}
+
+	switch c.config.URLQueryPolicy {
+	case QueryPolicyStrip:
+		u.RawQuery = ""
+	case QueryPolicySort:
+		// Encode sorts by key and keeps the order of repeated keys
+		u.RawQuery = u.Query().Encode()
+	}
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// cleanPath applies path.Clean to an absolute path, keeping the trailing slash
+// of a directory, which "." and ".." segments at the end also denote.
+func cleanPath(p string) string {
+	cleaned := path.Clean(p)
+	if cleaned != "/" && (strings.HasSuffix(p, "/") || strings.HasSuffix(p, "/.") || strings.HasSuffix(p, "/..")) {
+		cleaned += "/"
+	}
+	return cleaned
+}
@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"crawler/internal/config"
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		policy string
+		spa    bool
+		want   string
+	}{
+		{"lower-cased scheme and host", "HTTPS://Example.COM/Path", "", false, "https://example.com/Path"},
+		{"default port", "http://example.com:80/a", "", false, "http://example.com/a"},
+		{"other port", "http://example.com:8080/a", "", false, "http://example.com:8080/a"},
+		{"empty path", "https://example.com", "", false, "https://example.com/"},
+		{"surrounding space", "  https://example.com/a  ", "", false, "https://example.com/a"},
+
+		{"fragment", "https://example.com/a#section", "", false, "https://example.com/a"},
+		{"empty fragment", "https://example.com/a#", "", false, "https://example.com/a"},
+		{"route fragment without spa", "https://example.com/#/inbox", "", false, "https://example.com/"},
+		{"route fragment with spa", "https://example.com/#/inbox", "", true, "https://example.com/#/inbox"},
+		{"hashbang with spa", "https://example.com/#!inbox", "", true, "https://example.com/#!inbox"},
+		{"plain fragment with spa", "https://example.com/a#section", "", true, "https://example.com/a"},
+
+		{"query kept", "https://example.com/a?b=2&a=1", QueryPolicyKeep, false, "https://example.com/a?b=2&a=1"},
+		{"query sorted", "https://example.com/a?b=2&a=1", QueryPolicySort, false, "https://example.com/a?a=1&b=2"},
+		{"repeated keys keep their order", "https://example.com/a?b=2&a=1&b=1", QueryPolicySort, false, "https://example.com/a?a=1&b=2&b=1"},
+		{"query stripped", "https://example.com/a?page=1", QueryPolicyStrip, false, "https://example.com/a"},
+		{"empty query", "https://example.com/a?", QueryPolicyKeep, false, "https://example.com/a"},
+
+		{"dot segment", "https://example.com/a/./b", "", false, "https://example.com/a/b"},
+		{"parent segment", "https://example.com/a/../b", "", false, "https://example.com/b"},
+		{"parent above root", "https://example.com/../a", "", false, "https://example.com/a"},
+		{"repeated slashes", "https://example.com//a//b", "", false, "https://example.com/a/b"},
+		{"trailing slash kept", "https://example.com/a/b/", "", false, "https://example.com/a/b/"},
+		{"trailing dot segment", "https://example.com/a/b/.", "", false, "https://example.com/a/b/"},
+		{"trailing parent segment", "https://example.com/a/b/..", "", false, "https://example.com/a/"},
+		{"root parent segment", "https://example.com/a/..", "", false, "https://example.com/"},
+		{"encoded slash kept", "https://example.com/a%2Fb/./c", "", false, "https://example.com/a%2Fb/c"},
+		{"cleaned path with query", "https://example.com/a/../b?x=1", QueryPolicyKeep, false, "https://example.com/b?x=1"},
+
+		{"relative URL unchanged", "/a/../b", "", false, "/a/../b"},
+		{"unparseable URL unchanged", "http://[::1", "", false, "http://[::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Crawler{config: &config.Config{URLQueryPolicy: tt.policy}}
+			if got := c.NormalizeURL(tt.raw, tt.spa); got != tt.want {
+				t.Errorf("NormalizeURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeURLSPADomain(t *testing.T) {
+	c := &Crawler{config: &config.Config{SPADomainSet: map[string]bool{"app.example.com": true}}}
+	if got, want := c.NormalizeURL("https://app.example.com/#/inbox", false), "https://app.example.com/#/inbox"; got != want {
+		t.Errorf("route of an SPA domain: got %q, want %q", got, want)
+	}
+	if got, want := c.NormalizeURL("https://example.com/#/inbox", false), "https://example.com/"; got != want {
+		t.Errorf("route of another domain: got %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeURLRelativeLinks(t *testing.T) {
+	c := &Crawler{config: &config.Config{URLQueryPolicy: QueryPolicySort}}
+	base, _ := url.Parse("https://example.com/docs/guide/?b=2&a=1")
+	tests := []struct {
+		href string
+		want string
+	}{
+		{"#section", "https://example.com/docs/guide/?a=1&b=2"},
+		{"?a=1&b=2#top", "https://example.com/docs/guide/?a=1&b=2"},
+		{"./", "https://example.com/docs/guide/"},
+		{"../", "https://example.com/docs/"},
+		{"intro", "https://example.com/docs/guide/intro"},
+		{".//intro/./", "https://example.com/docs/guide/intro/"},
+		{"/docs/../faq", "https://example.com/faq"},
+	}
+	for _, tt := range tests {
+		ref, err := url.Parse(tt.href)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.NormalizeURL(base.ResolveReference(ref).String(), false); got != tt.want {
+			t.Errorf("link %q: got %q, want %q", tt.href, got, tt.want)
+		}
+	}
+}
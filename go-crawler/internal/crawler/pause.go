@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pausePollInterval is how often each instance picks up the fleet-wide
+// paused flag from the state store.
+const pausePollInterval = 5 * time.Second
+
+// pauseSwitch stops workers from taking tasks while paused. Queued tasks stay
+// on the queue, and crawls already running finish.
+type pauseSwitch struct {
+	mu      sync.Mutex
+	paused  chan struct{} // Closed while paused
+	running chan struct{} // Closed while running
+}
+
+func newPauseSwitch() *pauseSwitch {
+	running := make(chan struct{})
+	close(running)
+	return &pauseSwitch{paused: make(chan struct{}), running: running}
+}
+
+// set pauses or resumes, reporting whether that changed the state.
+func (p *pauseSwitch) set(paused bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if paused == p.isPausedLocked() {
+		return false
+	}
+	if paused {
+		close(p.paused)
+		p.running = make(chan struct{})
+	} else {
+		close(p.running)
+		p.paused = make(chan struct{})
+	}
+	return true
+}
+
+func (p *pauseSwitch) isPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.isPausedLocked()
+}
+
+func (p *pauseSwitch) isPausedLocked() bool {
+	select {
+	case <-p.paused:
+		return true
+	default:
+		return false
+	}
+}
+
+// pausedChan returns a channel that is closed once paused.
+func (p *pauseSwitch) pausedChan() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// wait blocks while paused. It returns false if stop is closed first.
+func (p *pauseSwitch) wait(stop <-chan struct{}) bool {
+	p.mu.Lock()
+	running := p.running
+	p.mu.Unlock()
+	select {
+	case <-running:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// Pause stops every instance's workers from taking new tasks until Resume.
+// The flag is kept in the state store, so it survives restarts.
+func (c *Crawler) Pause(ctx context.Context) error {
+	if err := c.stateStore.SetPaused(ctx, true); err != nil {
+		return err
+	}
+	if c.pause.set(true) {
+		c.logger.Warn("crawling paused")
+	}
+	return nil
+}
+
+// Resume lets workers take tasks again after Pause.
+func (c *Crawler) Resume(ctx context.Context) error {
+	if err := c.stateStore.SetPaused(ctx, false); err != nil {
+		return err
+	}
+	if c.pause.set(false) {
+		c.logger.Info("crawling resumed")
+	}
+	return nil
+}
+
+// syncPause applies the paused flag from the state store. On errors the
+// current state is kept.
+func (c *Crawler) syncPause() {
+	paused, err := c.stateStore.IsPaused(c.ctx)
+	if err != nil {
+		c.logger.Warn("failed to read paused state", zap.Error(err))
+		return
+	}
+	if c.pause.set(paused) {
+		if paused {
+			c.logger.Warn("crawling paused")
+		} else {
+			c.logger.Info("crawling resumed")
+		}
+	}
+}
+
+// startPauseSync follows pauses and resumes made through other instances.
+func (c *Crawler) startPauseSync() {
+	ticker := time.NewTicker(pausePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+			c.syncPause()
+		}
+	}
+}
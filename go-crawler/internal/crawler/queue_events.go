@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	queueStateNormal  = "normal"
+	queueStateEmpty   = "queue_empty"
+	queueStateBacklog = "queue_backlog"
+)
+
+// QueueEvent is the payload sent to the queue events webhook.
+type QueueEvent struct {
+	Event     string    `json:"event"`
+	QueueSize int       `json:"queue_size"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// queueWatcher detects the queue draining or crossing the backlog threshold.
+// A new state only fires once it has held for the debounce period, so a queue
+// hovering around a boundary doesn't flap.
+type queueWatcher struct {
+	threshold    int
+	debounce     time.Duration
+	current      string
+	pending      string
+	pendingSince time.Time
+}
+
+func (w *queueWatcher) observe(size int, now time.Time) (event string, fire bool) {
+	state := queueStateNormal
+	switch {
+	case size == 0:
+		state = queueStateEmpty
+	case w.threshold > 0 && size >= w.threshold:
+		state = queueStateBacklog
+	}
+
+	if state == w.current {
+		w.pending = ""
+		return "", false
+	}
+	if state != w.pending {
+		w.pending, w.pendingSince = state, now
+	}
+	if now.Sub(w.pendingSince) < w.debounce {
+		return "", false
+	}
+
+	w.current, w.pending = state, ""
+	return state, state != queueStateNormal
+}
+
+// emitQueueEvent logs the event, counts it and posts it to the webhook if configured.
+func (c *Crawler) emitQueueEvent(event string, size int) {
+	c.logger.Info("queue event", zap.String("event", event), zap.Int("queue_size", size))
+	c.metrics.IncQueueEvents(event)
+
+	if c.config.QueueEventsWebhookURL == "" {
+		return
+	}
+	payload, _ := json.Marshal(QueueEvent{Event: event, QueueSize: size, Timestamp: time.Now()})
+	if err := postWebhook(c.ctx, c.config.QueueEventsWebhookURL, payload); err != nil {
+		c.logger.Error("failed to send queue event webhook", zap.String("event", event), zap.Error(err))
+	}
+}
+
+// postWebhook sends a JSON payload to url with a short timeout.
+func postWebhook(ctx context.Context, url string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
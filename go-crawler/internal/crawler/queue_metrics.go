@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// startQueueMetricsCollector periodically reports the size of the task queue
+// and the state of the delayed retry queue, and emits queue events when the
+// queue drains or crosses the backlog threshold.
+func (c *Crawler) startQueueMetricsCollector() {
+	ticker := time.NewTicker(time.Duration(c.config.MetricsInterval) * time.Second)
+	defer ticker.Stop()
+
+	watcher := &queueWatcher{
+		threshold: c.config.QueueBacklogThreshold,
+		debounce:  time.Duration(c.config.QueueEventDebounce) * time.Second,
+		current:   queueStateEmpty, // The queue starts out empty; don't announce it
+	}
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+			size := len(c.taskQueue)
+			c.metrics.SetQueueSize(size)
+			c.metrics.SetEffectiveConcurrency(c.effectiveConcurrency())
+			if event, fire := watcher.observe(size, time.Now()); fire {
+				c.emitQueueEvent(event, size)
+			}
+
+			depth, oldest, err := c.stateStore.RetryQueueStats(c.ctx)
+			if err != nil {
+				c.logger.Error("failed to collect retry queue stats", zap.Error(err))
+				continue
+			}
+			var oldestAge time.Duration
+			if depth > 0 {
+				oldestAge = time.Since(oldest)
+			}
+			c.metrics.SetRetryQueueStats(depth, oldestAge)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/monitoring"
+	"crawler/internal/storage"
+	"sync"
+	"time"
+)
+
+// domainRateLimiter spaces out requests to the same domain. The delay adapts
+// per domain (AIMD): it doubles when a domain starts failing or throttling us,
+// and shrinks by a fixed step on each success, within [minDelay, maxDelay].
+// Request slots are reserved in the state store, so pacing survives restarts
+// and holds across instances, falling back to memory if the store fails.
+type domainRateLimiter struct {
+	minDelay time.Duration
+	maxDelay time.Duration
+	step     time.Duration
+	store    storage.StateStore
+	metrics  *monitoring.Metrics
+	mu       sync.Mutex
+	domains  map[string]*domainPace
+
+	// Domains with their own request metric label, up to maxLabeled, bounding
+	// the metric's series
+	labeled    map[string]bool
+	maxLabeled int
+}
+
+type domainPace struct {
+	delay       time.Duration
+	lastRequest time.Time
+}
+
+func newDomainRateLimiter(minDelay, maxDelay, step time.Duration, maxLabeled int, ss storage.StateStore, m *monitoring.Metrics) *domainRateLimiter {
+	return &domainRateLimiter{
+		minDelay:   minDelay,
+		maxDelay:   maxDelay,
+		step:       step,
+		store:      ss,
+		metrics:    m,
+		domains:    make(map[string]*domainPace),
+		labeled:    make(map[string]bool),
+		maxLabeled: maxLabeled,
+	}
+}
+
+// pace returns the state for a domain. Callers must hold l.mu.
+func (l *domainRateLimiter) pace(domain string) *domainPace {
+	p, ok := l.domains[domain]
+	if !ok {
+		p = &domainPace{delay: l.minDelay}
+		l.domains[domain] = p
+	}
+	return p
+}
+
+// Wait blocks until the domain's current delay has elapsed since the previous
+// request to it, reserving the slot so concurrent callers are spaced out too.
+func (l *domainRateLimiter) Wait(ctx context.Context, domain string) error {
+	wait := time.Until(l.reserve(ctx, domain))
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve returns the time of the domain's next request slot, reserved in the
+// state store or, when that fails, in memory. Slots are always recorded in
+// memory, so a fallback still spaces requests after the last shared slot.
+func (l *domainRateLimiter) reserve(ctx context.Context, domain string) time.Time {
+	l.mu.Lock()
+	delay := l.pace(domain).delay
+	l.mu.Unlock()
+
+	shared, err := l.store.ReserveDomainSlot(ctx, domain, delay, l.maxDelay)
+	if err != nil {
+		l.metrics.IncErrorsTotal("rate_limit_store_failed")
+	}
+	l.metrics.SetRateLimitShared(err == nil)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.metrics.IncDomainRequests(l.metricLabel(domain))
+	p := l.pace(domain)
+	next := shared
+	if err != nil {
+		next = p.lastRequest.Add(p.delay)
+		if now := time.Now(); next.Before(now) {
+			next = now
+		}
+	}
+	if next.After(p.lastRequest) {
+		p.lastRequest = next
+	}
+	return next
+}
+
+// metricLabel returns the request metric label of a domain: the domain while
+// fewer than maxLabeled have one, otherwise "other". Callers must hold l.mu.
+func (l *domainRateLimiter) metricLabel(domain string) string {
+	if !l.labeled[domain] {
+		if len(l.labeled) >= l.maxLabeled {
+			return "other"
+		}
+		l.labeled[domain] = true
+	}
+	return domain
+}
+
+// Record adjusts the domain's delay based on the outcome of a crawl.
+func (l *domainRateLimiter) Record(domain string, success bool) {
+	l.mu.Lock()
+	p := l.pace(domain)
+	if success {
+		p.delay -= l.step
+	} else {
+		p.delay = max(p.delay*2, l.step)
+	}
+	p.delay = min(max(p.delay, l.minDelay), l.maxDelay)
+	delay := p.delay
+	l.mu.Unlock()
+
+	l.metrics.SetDomainDelay(domain, delay)
+}
@@ -0,0 +1,132 @@
+package crawler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// browserMarkerFlag is passed to every browser we launch, with the crawler's
+// PID and run ID as its value, so browsers left behind by a crashed run can be
+// told apart from our own, from those of other crawlers on the same host, and
+// from unrelated Chrome processes.
+const browserMarkerFlag = "go-crawler-owner"
+
+// browserProcess is a running browser launched with browserMarkerFlag.
+type browserProcess struct {
+	pid      int
+	ownerPID int
+	runID    string
+}
+
+// newRunID returns a random identifier for this crawler run.
+func newRunID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// browserMarker is the value of browserMarkerFlag for this run.
+func (c *Crawler) browserMarker() string {
+	return strconv.Itoa(os.Getpid()) + "." + c.runID
+}
+
+// orphaned reports whether p was launched by an earlier run that is no longer
+// alive. Its PID may have been reused by this process, as in a restarted
+// container where the crawler is always PID 1.
+func (c *Crawler) orphaned(p browserProcess) bool {
+	if p.runID == c.runID {
+		return false
+	}
+	if p.ownerPID == os.Getpid() {
+		return true
+	}
+	_, err := os.Stat(filepath.Join("/proc", strconv.Itoa(p.ownerPID)))
+	return os.IsNotExist(err)
+}
+
+// listBrowserProcesses finds the processes started with browserMarkerFlag by
+// reading /proc, so it only works on Linux.
+func listBrowserProcesses() ([]browserProcess, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	prefix := "--" + browserMarkerFlag + "="
+	var procs []browserProcess
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join("/proc", e.Name(), "cmdline"))
+		if err != nil {
+			continue // Exited meanwhile, or not ours to read
+		}
+		for _, arg := range strings.Split(string(cmdline), "\x00") {
+			marker, ok := strings.CutPrefix(arg, prefix)
+			if !ok {
+				continue
+			}
+			owner, runID, _ := strings.Cut(marker, ".")
+			ownerPID, _ := strconv.Atoi(owner)
+			procs = append(procs, browserProcess{pid: pid, ownerPID: ownerPID, runID: runID})
+			break
+		}
+	}
+	return procs, nil
+}
+
+// checkBrowserProcesses records how many of our browsers are running and,
+// with CHROME_REAP_ORPHANS, kills those of earlier runs. Only the browser's
+// main process carries the marker; its renderers exit along with it.
+func (c *Crawler) checkBrowserProcesses() {
+	procs, err := listBrowserProcesses()
+	if err != nil {
+		c.logger.Warn("failed to list browser processes", zap.Error(err))
+		return
+	}
+	own := 0
+	for _, p := range procs {
+		if p.runID == c.runID {
+			own++
+			continue
+		}
+		if !c.config.ChromeReapOrphans || !c.orphaned(p) {
+			continue
+		}
+		proc, err := os.FindProcess(p.pid)
+		if err == nil {
+			err = proc.Kill()
+		}
+		if err != nil {
+			c.logger.Warn("failed to kill orphaned browser", zap.Int("pid", p.pid), zap.Error(err))
+			continue
+		}
+		c.logger.Info("killed orphaned browser", zap.Int("pid", p.pid), zap.Int("owner_pid", p.ownerPID))
+		c.metrics.IncChromeOrphansReaped()
+	}
+	c.metrics.SetChromeProcesses(own)
+}
+
+// startBrowserProcessChecks runs checkBrowserProcesses every
+// CHROME_PROCESS_CHECK_INTERVAL seconds.
+func (c *Crawler) startBrowserProcessChecks() {
+	ticker := time.NewTicker(time.Duration(c.config.ChromeProcessCheckInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+			c.checkBrowserProcesses()
+		}
+	}
+}
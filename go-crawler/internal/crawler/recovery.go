@@ -0,0 +1,77 @@
+package crawler
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recoveryBatchSize bounds how many stale URLs a single reset statement
+// handles.
+const recoveryBatchSize = 500
+
+// startStaleRecovery re-enqueues URLs stuck in "processing" at startup and
+// then every STALE_RECOVERY_INTERVAL seconds.
+func (c *Crawler) startStaleRecovery() {
+	c.recoverStale()
+	ticker := time.NewTicker(time.Duration(c.config.StaleRecoveryInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+			c.recoverStale()
+		}
+	}
+}
+
+// recoverStale finds the URLs that have been "processing" for longer than
+// PROCESSING_STALE_AFTER, which no crawl can take, so their worker must have
+// died mid-crawl. Their tasks are put on the schedule queue, due now, to be
+// crawled again with their options without using up a retry, and then marked
+// as interrupted. URLs that couldn't be queued stay "processing" for the next
+// sweep.
+func (c *Crawler) recoverStale() {
+	cutoff := time.Now().Add(-time.Duration(c.config.ProcessingStaleAfter) * time.Second)
+	total := 0
+	for c.ctx.Err() == nil {
+		urls, err := c.pageStore.StaleProcessing(c.ctx, cutoff, recoveryBatchSize)
+		if err != nil {
+			c.logger.Error("failed to find stale processing URLs", zap.Error(err))
+			break
+		}
+		now := time.Now()
+		enqueued := make([]string, 0, len(urls))
+		for _, url := range urls {
+			task, err := c.stateStore.ProcessingTask(c.ctx, url)
+			if err != nil {
+				c.logger.Warn("failed to get the task of a stale processing URL", zap.String("url", url), zap.Error(err))
+			}
+			task.Retry = false
+			if err := c.stateStore.ScheduleTask(c.ctx, task, now); err != nil {
+				c.logger.Error("failed to re-enqueue stale processing URL", zap.String("url", url), zap.Error(err))
+				continue
+			}
+			if err := c.stateStore.DeleteProcessingTask(c.ctx, url); err != nil {
+				c.logger.Warn("failed to delete the task of a stale processing URL", zap.String("url", url), zap.Error(err))
+			}
+			enqueued = append(enqueued, url)
+		}
+		if len(enqueued) > 0 {
+			if err := c.pageStore.ResetStaleProcessing(c.ctx, enqueued, cutoff); err != nil {
+				c.logger.Error("failed to reset stale processing URLs", zap.Error(err))
+				break
+			}
+		}
+		c.metrics.AddStaleRecovered(len(enqueued))
+		total += len(enqueued)
+		if len(enqueued) < len(urls) || len(urls) < recoveryBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		c.logger.Warn("re-enqueued URLs left in processing", zap.Int("count", total), zap.Time("cutoff", cutoff))
+	}
+}
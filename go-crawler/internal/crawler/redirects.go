@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// redirectGuard follows the redirect chain of the main document and aborts
+// the crawl when a URL repeats or the chain exceeds the configured limit.
+type redirectGuard struct {
+	maxRedirects int
+	abort        context.CancelFunc
+	mu           sync.Mutex
+	requestID    network.RequestID
+	chain        []string
+	err          error
+}
+
+func newRedirectGuard(maxRedirects int, abort context.CancelFunc) *redirectGuard {
+	return &redirectGuard{maxRedirects: maxRedirects, abort: abort}
+}
+
+func (g *redirectGuard) listen(ev interface{}) {
+	e, ok := ev.(*network.EventRequestWillBeSent)
+	if !ok || e.Type != network.ResourceTypeDocument {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err != nil {
+		return
+	}
+	// Redirects of a request keep its ID; the first document request is the page itself
+	if g.requestID == "" {
+		g.requestID = e.RequestID
+		g.chain = []string{e.Request.URL}
+		return
+	}
+	if e.RequestID != g.requestID || e.RedirectResponse == nil {
+		return
+	}
+
+	for _, seen := range g.chain {
+		if seen == e.Request.URL {
+			g.fail(e.Request.URL)
+			return
+		}
+	}
+	g.chain = append(g.chain, e.Request.URL)
+	if len(g.chain)-1 > g.maxRedirects {
+		g.fail("")
+	}
+}
+
+// fail records the error and aborts the crawl. Callers must hold g.mu.
+func (g *redirectGuard) fail(repeated string) {
+	chain := append([]string{}, g.chain...)
+	if repeated != "" {
+		chain = append(chain, repeated)
+	}
+	g.err = fmt.Errorf("%w after %d redirects: %s", ErrRedirectLoop, len(g.chain)-1, strings.Join(chain, " -> "))
+	g.abort()
+}
+
+// Chain returns the page URL followed by the redirects seen so far.
+func (g *redirectGuard) Chain() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string{}, g.chain...)
+}
+
+// Err returns the redirect error, if the guard aborted the crawl.
+func (g *redirectGuard) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
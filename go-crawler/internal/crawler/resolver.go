@@ -0,0 +1,26 @@
+package crawler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// hostResolverRules converts host overrides into Chrome's
+// --host-resolver-rules syntax, e.g. "MAP example.com 10.0.0.5".
+func hostResolverRules(overrides map[string]string) string {
+	if len(overrides) == 0 {
+		return ""
+	}
+	hosts := make([]string, 0, len(overrides))
+	for host := range overrides {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	rules := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		rules = append(rules, fmt.Sprintf("MAP %s %s", host, overrides[host]))
+	}
+	return strings.Join(rules, ", ")
+}
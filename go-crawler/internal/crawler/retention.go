@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/storage"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// retentionBatchSize bounds how many pages a single cleanup statement deletes,
+// so the cleanup never holds locks on large parts of the table.
+const retentionBatchSize = 500
+
+// startRetentionCleanup periodically deletes pages that haven't been updated
+// within DATA_RETENTION_DAYS, along with their crawled markers and failure
+// screenshots.
+func (c *Crawler) startRetentionCleanup() {
+	ticker := time.NewTicker(time.Duration(c.config.RetentionCleanupInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+			c.cleanupExpired()
+		}
+	}
+}
+
+// DeleteDomain deletes all stored pages of a domain, along with their crawled
+// markers, so the domain can be crawled again, and their failure screenshots.
+// It returns how many pages were deleted.
+func (c *Crawler) DeleteDomain(ctx context.Context, host string) (int, error) {
+	pages, err := c.pageStore.DeleteDomain(ctx, host)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.stateStore.UnmarkCrawled(ctx, deletedKeys(pages)); err != nil {
+		c.logger.Error("failed to clear crawled markers", zap.String("domain", host), zap.Error(err))
+	}
+	c.removeFailureScreenshots(pages)
+	return len(pages), nil
+}
+
+// deletedKeys returns the storage.PageKey of each deleted page.
+func deletedKeys(pages []storage.DeletedPage) []string {
+	keys := make([]string, len(pages))
+	for i, p := range pages {
+		keys[i] = storage.PageKey(p.URL, p.Device)
+	}
+	return keys
+}
+
+func (c *Crawler) cleanupExpired() {
+	cutoff := time.Now().AddDate(0, 0, -c.config.DataRetentionDays)
+	total := 0
+	for c.ctx.Err() == nil {
+		pages, err := c.pageStore.DeleteExpired(c.ctx, cutoff, retentionBatchSize)
+		if err != nil {
+			c.logger.Error("failed to delete expired pages", zap.Error(err))
+			break
+		}
+		if err := c.stateStore.UnmarkCrawled(c.ctx, deletedKeys(pages)); err != nil {
+			c.logger.Error("failed to clear crawled markers of expired pages", zap.Error(err))
+		}
+		c.removeFailureScreenshots(pages)
+		c.metrics.AddRetentionDeleted(len(pages))
+		total += len(pages)
+		if len(pages) < retentionBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		c.logger.Info("deleted expired pages", zap.Int("count", total), zap.Time("cutoff", cutoff))
+	}
+}
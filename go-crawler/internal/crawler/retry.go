@@ -0,0 +1,135 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"crawler/internal/storage"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// retryQueueReserve is the divisor of the task queue capacity kept free for
+	// new submissions, so a failure backlog can't starve them
+	retryQueueReserve = 4
+	// maxRetryBackoff caps how many intervals the scheduler waits while the
+	// queue has no room for retries or the delayed queue is unavailable
+	maxRetryBackoff = 8
+)
+
+// startRetryScheduler periodically moves scheduled crawls and retries that
+// have become due from the delayed queues back onto the task queue. It backs
+// off while the queue is near full, the in-flight retry cap is reached or the
+// delayed queues are unreachable, and waits while crawling is paused.
+func (c *Crawler) startRetryScheduler() {
+	interval := time.Duration(c.config.RetryInterval) * time.Second
+	wait := interval
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-timer.C:
+		}
+		// Leave due tasks in the delayed queues while paused, like the workers
+		// leave the task queue
+		if !c.pause.wait(c.stopChan) {
+			return
+		}
+
+		// Scheduled crawls are new work, so they don't count against the retry cap
+		budget := c.retryBudget()
+		tasks, err := c.popDue(c.stateStore.PopDueTasks, c.queueBudget())
+		if err == nil {
+			var retries []domain.URLTask
+			retries, err = c.popDue(c.stateStore.PopDueRetries, budget-len(tasks))
+			for i := range retries {
+				retries[i].Retry = true
+			}
+			tasks = append(tasks, retries...)
+		}
+		switch {
+		case errors.Is(err, storage.ErrQueueUnavailable):
+			wait = min(wait*2, interval*maxRetryBackoff)
+			c.logger.Warn("delayed queue unavailable, backing off", zap.Duration("wait", wait), zap.Error(err))
+			c.metrics.IncErrorsTotal("queue_unavailable")
+		case budget <= 0:
+			wait = min(wait*2, interval*maxRetryBackoff)
+			c.logger.Debug("no room for retries, backing off", zap.Int("queue_size", len(c.taskQueue)),
+				zap.Int64("retries_in_flight", c.retriesInFlight.Load()), zap.Duration("wait", wait))
+		default:
+			wait = interval
+			if err != nil {
+				c.logger.Error("failed to fetch due tasks", zap.Error(err))
+			}
+		}
+
+		for i, task := range tasks {
+			if task.Retry {
+				c.retriesInFlight.Add(1)
+			}
+			c.pending.add(task)
+			select {
+			case c.taskQueue <- task:
+			case <-c.stopChan:
+				c.pending.remove(pageKey(task))
+				if task.Retry {
+					c.retriesInFlight.Add(-1)
+				}
+				c.requeue(tasks[i:])
+				return
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// requeue puts tasks popped from the delayed queues back on them, due now,
+// when the scheduler stops before they could be moved onto the task queue.
+func (c *Crawler) requeue(tasks []domain.URLTask) {
+	// c.ctx is already canceled on shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now()
+	for _, task := range tasks {
+		schedule := c.stateStore.ScheduleTask
+		if task.Retry {
+			schedule = c.stateStore.ScheduleRetry
+		}
+		if err := schedule(ctx, task, now); err != nil {
+			c.logger.Error("failed to put back a due task on shutdown", zap.String("url", task.URL), zap.Error(err))
+		}
+	}
+}
+
+// popDue pops up to budget due tasks with pop, or none when there is no room.
+func (c *Crawler) popDue(pop func(context.Context, time.Time, int64) ([]domain.URLTask, error), budget int) ([]domain.URLTask, error) {
+	if budget <= 0 {
+		return nil, nil
+	}
+	return pop(c.ctx, time.Now(), int64(budget))
+}
+
+// queueBudget returns how many due tasks may be moved onto the task queue
+// now: at most a batch, within the queue space not reserved for submissions.
+func (c *Crawler) queueBudget() int {
+	budget := cap(c.taskQueue) - cap(c.taskQueue)/retryQueueReserve - len(c.taskQueue)
+	if c.config.RetryBatchSize > 0 {
+		budget = min(budget, c.config.RetryBatchSize)
+	}
+	return budget
+}
+
+// retryBudget returns how many due retries may be moved onto the task queue
+// now: the queue budget, within the in-flight cap.
+func (c *Crawler) retryBudget() int {
+	budget := c.queueBudget()
+	if c.config.RetryMaxInFlight > 0 {
+		budget = min(budget, c.config.RetryMaxInFlight-int(c.retriesInFlight.Load()))
+	}
+	return budget
+}
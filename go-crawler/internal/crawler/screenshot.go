@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"go.uber.org/zap"
+)
+
+// screenshotTimeout bounds capturing a failed page, which may be the one that
+// just timed out.
+const screenshotTimeout = 5 * time.Second
+
+// captureFailureScreenshot saves a screenshot of the page a crawl failed on to
+// FAILURE_SCREENSHOT_DIR and returns its file name, or "" when screenshots are
+// disabled, the tab was never opened or the capture failed.
+func (c *Crawler) captureFailureScreenshot(browserCtx context.Context, url string) string {
+	if c.config.FailureScreenshotDir == "" {
+		return ""
+	}
+	// Capturing without a tab would launch a browser just for the screenshot
+	if cc := chromedp.FromContext(browserCtx); cc == nil || cc.Target == nil {
+		return ""
+	}
+
+	// The crawl's own timeout has usually expired, but the tab is still open
+	ctx, cancel := context.WithTimeout(browserCtx, screenshotTimeout)
+	defer cancel()
+	var buf []byte
+	if err := chromedp.Run(ctx, chromedp.CaptureScreenshot(&buf)); err != nil {
+		c.logger.Warn("failed to capture failure screenshot", zap.String("url", url), zap.Error(err))
+		c.metrics.IncErrorsTotal("screenshot_failed")
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	name := fmt.Sprintf("%s-%d.png", hex.EncodeToString(sum[:8]), time.Now().Unix())
+	if err := os.WriteFile(filepath.Join(c.config.FailureScreenshotDir, name), buf, 0o644); err != nil {
+		c.logger.Warn("failed to save failure screenshot", zap.String("url", url), zap.Error(err))
+		c.metrics.IncErrorsTotal("screenshot_failed")
+		return ""
+	}
+	return name
+}
+
+// removeFailureScreenshots deletes the screenshots of pages deleted from the
+// store.
+func (c *Crawler) removeFailureScreenshots(pages []storage.DeletedPage) {
+	if c.config.FailureScreenshotDir == "" {
+		return
+	}
+	for _, p := range pages {
+		if p.FailScreenshot == "" {
+			continue
+		}
+		// Base keeps a tampered record from pointing outside the directory
+		path := filepath.Join(c.config.FailureScreenshotDir, filepath.Base(p.FailScreenshot))
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			c.logger.Warn("failed to delete failure screenshot", zap.String("url", p.URL), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"crawler/internal/domain"
+	"fmt"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// autoScrollJS scrolls to the bottom of the page until it stops growing, the
+// scroll limit is reached or the time budget runs out. After each scroll it
+// waits up to waitMs for lazy-loaded content. It evaluates to the number of
+// scrolls made.
+const autoScrollJS = `(async (maxScrolls, waitMs, timeoutMs) => {
+	const root = document.scrollingElement || document.documentElement;
+	const deadline = Date.now() + timeoutMs;
+	let scrolls = 0;
+	let height = root.scrollHeight;
+	while (scrolls < maxScrolls && Date.now() < deadline) {
+		window.scrollTo(0, root.scrollHeight);
+		scrolls++;
+		const waitUntil = Math.min(Date.now() + waitMs, deadline);
+		while (root.scrollHeight <= height && Date.now() < waitUntil) {
+			await new Promise(resolve => setTimeout(resolve, 100));
+		}
+		if (root.scrollHeight <= height) break;
+		height = root.scrollHeight;
+	}
+	return scrolls;
+})(%d, %d, %d)`
+
+// wantsAutoScroll reports whether a task's page should be scrolled to load
+// infinite-scroll content before extraction.
+func (c *Crawler) wantsAutoScroll(task domain.URLTask, host string) bool {
+	return task.AutoScroll || c.config.AutoScrollDomainSet[host]
+}
+
+// autoScroll returns an action that scrolls the page to load more content
+// and records how many scrolls it made.
+func (c *Crawler) autoScroll(scrolls *int) chromedp.Action {
+	js := fmt.Sprintf(autoScrollJS, c.config.AutoScrollMaxScrolls, c.config.AutoScrollWaitMs, c.config.AutoScrollTimeout*1000)
+	return chromedp.Evaluate(js, scrolls, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	})
+}
@@ -0,0 +1,182 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"crawler/internal/storage"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pendingTasks tracks the tasks on the in-process task queue or being
+// crawled, which live nowhere else and would be lost with the process, and
+// which of their pages are still waiting on the queue. Pages are tracked by
+// their pageKey, as each device preset crawls its own.
+type pendingTasks struct {
+	mu     sync.Mutex
+	urls   map[string]int
+	queued map[string]int
+	tasks  map[string]domain.URLTask // The latest task of each page
+}
+
+func newPendingTasks() *pendingTasks {
+	return &pendingTasks{urls: make(map[string]int), queued: make(map[string]int), tasks: make(map[string]domain.URLTask)}
+}
+
+func (p *pendingTasks) add(task domain.URLTask) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := pageKey(task)
+	p.urls[key]++
+	p.queued[key]++
+	p.tasks[key] = task
+}
+
+// addUnlessQueued adds task unless its page is already waiting on the queue,
+// reporting whether it was added. A page being crawled can be queued again.
+func (p *pendingTasks) addUnlessQueued(task domain.URLTask) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := pageKey(task)
+	if p.queued[key] > 0 {
+		return false
+	}
+	p.urls[key]++
+	p.queued[key]++
+	p.tasks[key] = task
+	return true
+}
+
+// start records that a worker took the page of key off the queue.
+func (p *pendingTasks) start(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.queued[key] <= 1 {
+		delete(p.queued, key)
+		return
+	}
+	p.queued[key]--
+}
+
+func (p *pendingTasks) done(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.urls[key] <= 1 {
+		delete(p.urls, key)
+		delete(p.tasks, key)
+		return
+	}
+	p.urls[key]--
+}
+
+// remove undoes add for a task that didn't make it onto the queue.
+func (p *pendingTasks) remove(key string) {
+	p.start(key)
+	p.done(key)
+}
+
+func (p *pendingTasks) list() []domain.URLTask {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tasks := make([]domain.URLTask, 0, len(p.tasks))
+	for _, task := range p.tasks {
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// pageKey returns the storage.PageKey of a task's page, which identifies it
+// in the stores.
+func pageKey(task domain.URLTask) string {
+	return storage.PageKey(task.URL, task.Device)
+}
+
+// startQueueSnapshots periodically copies the queue to Postgres, so it can be
+// restored if the queue backend loses its state, and deletes the snapshots
+// of instances that have stopped taking them.
+func (c *Crawler) startQueueSnapshots() {
+	ticker := time.NewTicker(time.Duration(c.config.QueueSnapshotInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+			if _, err := c.SnapshotQueue(c.ctx); err != nil {
+				c.logger.Error("failed to snapshot queue", zap.Error(err))
+			}
+			if c.config.QueueSnapshotMaxAge > 0 {
+				before := time.Now().Add(-time.Duration(c.config.QueueSnapshotMaxAge) * time.Second)
+				if err := c.pageStore.DeleteQueueSnapshots(c.ctx, before); err != nil {
+					c.logger.Error("failed to delete old queue snapshots", zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// SnapshotQueue stores the tasks this instance has queued or is crawling,
+// together with the delayed retry and schedule queues, and returns how many
+// were stored.
+func (c *Crawler) SnapshotQueue(ctx context.Context) (int, error) {
+	now := time.Now()
+	retries, err := c.stateStore.ScheduledRetries(ctx)
+	if err != nil {
+		return 0, err
+	}
+	scheduled, err := c.stateStore.ScheduledTasks(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// A URL can be in several places; keep the one due first
+	earliest := make(map[string]storage.ScheduledURL, len(retries)+len(scheduled))
+	for _, task := range c.pending.list() {
+		earliest[pageKey(task)] = storage.ScheduledURL{URL: pageKey(task), DueAt: now, Task: task}
+	}
+	for _, e := range append(retries, scheduled...) {
+		if first, ok := earliest[e.URL]; !ok || e.DueAt.Before(first.DueAt) {
+			earliest[e.URL] = e
+		}
+	}
+	entries := make([]storage.ScheduledURL, 0, len(earliest))
+	for _, e := range earliest {
+		entries = append(entries, e)
+	}
+
+	if err := c.pageStore.SaveQueueSnapshot(ctx, c.instance, entries, now); err != nil {
+		return 0, err
+	}
+	c.logger.Debug("queue snapshot saved", zap.Int("urls", len(entries)))
+	return len(entries), nil
+}
+
+// RestoreQueue puts the snapshotted tasks of all instances back on the
+// delayed queues at their original due times, skipping URLs completed since
+// the snapshot, and then deletes the restored snapshots. Retries go back on
+// the retry queue, everything else on the schedule queue. It returns how
+// many URLs were restored.
+func (c *Crawler) RestoreQueue(ctx context.Context) (int, error) {
+	started := time.Now()
+	entries, err := c.pageStore.QueueSnapshot(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, e := range entries {
+		schedule := c.stateStore.ScheduleTask
+		if e.Task.Retry {
+			schedule = c.stateStore.ScheduleRetry
+		}
+		if err := schedule(ctx, e.Task, e.DueAt); err != nil {
+			return i, err
+		}
+	}
+	// Instances still running take a fresh snapshot on their next tick
+	if err := c.pageStore.DeleteQueueSnapshots(ctx, started); err != nil {
+		return len(entries), err
+	}
+	return len(entries), nil
+}
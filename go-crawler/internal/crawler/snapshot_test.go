@@ -0,0 +1,116 @@
+package crawler
+
+import (
+	"context"
+	"crawler/internal/config"
+	"crawler/internal/domain"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAddUnlessQueuedConcurrent(t *testing.T) {
+	p := newPendingTasks()
+	task := domain.URLTask{URL: "https://example.com/", ForceCrawl: true}
+
+	var added atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p.addUnlessQueued(task) {
+				added.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := added.Load(); got != 1 {
+		t.Fatalf("%d concurrent adds succeeded, want 1", got)
+	}
+
+	// Taken off the queue, the URL may be queued again while it is crawled
+	p.start(task.URL)
+	if !p.addUnlessQueued(task) {
+		t.Fatal("URL being crawled could not be queued again")
+	}
+	if p.addUnlessQueued(task) {
+		t.Fatal("URL queued twice")
+	}
+	if got := len(p.list()); got != 1 {
+		t.Fatalf("list has %d tasks, want 1", got)
+	}
+
+	p.done(task.URL)
+	p.remove(task.URL)
+	if got := len(p.list()); got != 0 {
+		t.Fatalf("list has %d tasks after both finished, want 0", got)
+	}
+}
+
+func TestSubmitForcedTwice(t *testing.T) {
+	c := &Crawler{
+		config:    &config.Config{},
+		pending:   newPendingTasks(),
+		taskQueue: make(chan domain.URLTask, 10),
+	}
+
+	urls := []string{"https://example.com/page", "https://EXAMPLE.com:443/page"}
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = c.Submit(domain.URLTask{URL: u, ForceCrawl: true})
+		}()
+	}
+	wg.Wait()
+
+	var queued, rejected int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			queued++
+		case errors.Is(err, ErrAlreadyQueued):
+			rejected++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if queued != 1 || rejected != 1 || len(c.taskQueue) != 1 {
+		t.Fatalf("got %d queued, %d already queued and %d on the queue; want 1 of each", queued, rejected, len(c.taskQueue))
+	}
+}
+
+func TestTrySubmit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Crawler{
+		config:    &config.Config{},
+		ctx:       ctx,
+		pending:   newPendingTasks(),
+		taskQueue: make(chan domain.URLTask, 1),
+	}
+
+	if err := c.TrySubmit(domain.URLTask{URL: "https://example.com/a"}); err != nil {
+		t.Fatalf("first task: %v", err)
+	}
+	full := domain.URLTask{URL: "https://example.com/b"}
+	if err := c.TrySubmit(full); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("task on a full queue returned %v, want ErrQueueFull", err)
+	}
+
+	// A rejected task isn't left pending, so it can be submitted again
+	<-c.taskQueue
+	if err := c.TrySubmit(full); err != nil {
+		t.Fatalf("task rejected earlier: %v", err)
+	}
+
+	<-c.taskQueue
+	cancel()
+	if err := c.TrySubmit(domain.URLTask{URL: "https://example.com/c"}); !errors.Is(err, ErrStopping) {
+		t.Fatalf("task after shutdown returned %v, want ErrStopping", err)
+	}
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// waitForDOMSettleJS resolves once the DOM has had no mutations for 500ms,
+// or after 5s at the latest.
+const waitForDOMSettleJS = `new Promise(resolve => {
+	let timer = setTimeout(done, 500);
+	const deadline = setTimeout(done, 5000);
+	const observer = new MutationObserver(() => { clearTimeout(timer); timer = setTimeout(done, 500); });
+	observer.observe(document, {subtree: true, childList: true, characterData: true});
+	function done() { observer.disconnect(); clearTimeout(deadline); resolve(true); }
+})`
+
+// spaNavigate loads a single-page app and then routes it to the target view
+// on the client side, for apps that don't render a route on direct navigation.
+// Hash routes ("#/path") are set via location.hash on the page without the
+// fragment; other paths are loaded from the app root and pushed onto history.
+func spaNavigate(target, referrer string) []chromedp.Action {
+	u, err := url.Parse(target)
+	if err != nil {
+		return []chromedp.Action{navigate(target, referrer), chromedp.WaitVisible("body", chromedp.ByQuery)}
+	}
+
+	entry := *u
+	var routeJS string
+	if strings.HasPrefix(u.Fragment, "/") || strings.HasPrefix(u.Fragment, "!") {
+		entry.Fragment = ""
+		routeJS = fmt.Sprintf(`location.hash = %s`, jsString(u.Fragment))
+	} else {
+		route := u.EscapedPath()
+		if u.RawQuery != "" {
+			route += "?" + u.RawQuery
+		}
+		if u.Fragment != "" {
+			route += "#" + u.EscapedFragment()
+		}
+		entry.Path, entry.RawPath, entry.RawQuery, entry.Fragment = "/", "", "", ""
+		routeJS = fmt.Sprintf(`history.pushState(null, "", %s); window.dispatchEvent(new PopStateEvent("popstate", {state: null}))`, jsString(route))
+	}
+
+	var ignored interface{}
+	return []chromedp.Action{
+		navigate(entry.String(), referrer),
+		chromedp.WaitVisible("body", chromedp.ByQuery),
+		chromedp.Evaluate(routeJS, &ignored),
+		chromedp.Evaluate(waitForDOMSettleJS, &ignored, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}),
+	}
+}
+
+// jsString quotes s as a JavaScript string literal.
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// jsStrings formats ss as a JavaScript array literal of strings.
+func jsStrings(ss []string) string {
+	b, _ := json.Marshal(ss)
+	return string(b)
+}
@@ -0,0 +1,36 @@
+package crawler
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// Stats is a point-in-time view of the crawler's state, served by /api/stats.
+type Stats struct {
+	Paused               bool  `json:"paused"`
+	Workers              int   `json:"workers"`
+	EffectiveConcurrency int   `json:"effective_concurrency"` // Lower than workers during warm-up
+	Queued               int   `json:"queued"`
+	QueueCapacity        int   `json:"queue_capacity"`
+	RetryQueued          int64 `json:"retry_queued"`
+	RetriesInFlight      int64 `json:"retries_in_flight"`
+}
+
+// Stats returns the crawler's current state.
+func (c *Crawler) Stats(ctx context.Context) Stats {
+	stats := Stats{
+		Paused:               c.pause.isPaused(),
+		Workers:              c.config.CrawlWorkers,
+		EffectiveConcurrency: c.effectiveConcurrency(),
+		Queued:               len(c.taskQueue),
+		QueueCapacity:        cap(c.taskQueue),
+		RetriesInFlight:      c.retriesInFlight.Load(),
+	}
+	depth, _, err := c.stateStore.RetryQueueStats(ctx)
+	if err != nil {
+		c.logger.Warn("failed to get retry queue stats", zap.Error(err))
+	}
+	stats.RetryQueued = depth
+	return stats
+}
@@ -0,0 +1,113 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunSummary describes what the crawler has done since it started. It is
+// served by /api/summary and logged, and optionally posted, on shutdown.
+type RunSummary struct {
+	StartedAt     time.Time                 `json:"started_at"`
+	UptimeSeconds float64                   `json:"uptime_seconds"`
+	Crawled       int                       `json:"crawled"` // Finished attempts, successful or not
+	Succeeded     int                       `json:"succeeded"`
+	Failed        int                       `json:"failed"`
+	Skipped       int                       `json:"skipped"` // Not counted in Crawled
+	Queued        int                       `json:"queued"`
+	RetryQueued   int64                     `json:"retry_queued"`
+	Domains       map[string]DomainRunStats `json:"domains"`
+}
+
+// DomainRunStats is the per-domain breakdown of a RunSummary.
+type DomainRunStats struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+// runStats counts crawl outcomes since startup.
+type runStats struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	domains   map[string]DomainRunStats
+}
+
+func newRunStats() *runStats {
+	return &runStats{startedAt: time.Now(), domains: make(map[string]DomainRunStats)}
+}
+
+func (s *runStats) record(host string, succeeded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.domains[host]
+	if succeeded {
+		stats.Succeeded++
+	} else {
+		stats.Failed++
+	}
+	s.domains[host] = stats
+}
+
+func (s *runStats) recordSkipped(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.domains[host]
+	stats.Skipped++
+	s.domains[host] = stats
+}
+
+// Summary returns the current run summary.
+func (c *Crawler) Summary(ctx context.Context) RunSummary {
+	c.runStats.mu.Lock()
+	summary := RunSummary{
+		StartedAt:     c.runStats.startedAt,
+		UptimeSeconds: time.Since(c.runStats.startedAt).Seconds(),
+		Queued:        len(c.taskQueue),
+		Domains:       make(map[string]DomainRunStats, len(c.runStats.domains)),
+	}
+	for host, stats := range c.runStats.domains {
+		summary.Domains[host] = stats
+		summary.Succeeded += stats.Succeeded
+		summary.Failed += stats.Failed
+		summary.Skipped += stats.Skipped
+	}
+	c.runStats.mu.Unlock()
+	summary.Crawled = summary.Succeeded + summary.Failed
+
+	depth, _, err := c.stateStore.RetryQueueStats(ctx)
+	if err != nil {
+		c.logger.Warn("failed to get retry queue stats for summary", zap.Error(err))
+	}
+	summary.RetryQueued = depth
+	return summary
+}
+
+// ReportSummary logs the run summary and posts it to SUMMARY_WEBHOOK_URL if
+// configured. It is meant to be called once the crawler has stopped.
+func (c *Crawler) ReportSummary(ctx context.Context) {
+	summary := c.Summary(ctx)
+	c.logger.Info("crawl run summary",
+		zap.Time("started_at", summary.StartedAt),
+		zap.Float64("uptime_seconds", summary.UptimeSeconds),
+		zap.Int("crawled", summary.Crawled),
+		zap.Int("succeeded", summary.Succeeded),
+		zap.Int("failed", summary.Failed),
+		zap.Int("skipped", summary.Skipped),
+		zap.Int("queued", summary.Queued),
+		zap.Int64("retry_queued", summary.RetryQueued),
+		zap.Any("domains", summary.Domains),
+	)
+
+	if c.config.SummaryWebhookURL == "" {
+		return
+	}
+	payload, _ := json.Marshal(summary)
+	if err := postWebhook(ctx, c.config.SummaryWebhookURL, payload); err != nil {
+		c.logger.Error("failed to send run summary webhook", zap.Error(err))
+	}
+}
@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+)
+
+// throughputWindow is how many recent crawl completions the rate is based on.
+// A crawl completes once the browser has loaded its page, whether it then
+// succeeds or fails.
+const throughputWindow = 100
+
+// throughputTracker estimates the crawl rate from the times of the most
+// recent crawl completions.
+type throughputTracker struct {
+	mu    sync.Mutex
+	times [throughputWindow]time.Time
+	next  int
+	count int
+}
+
+func (t *throughputTracker) record(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.times[t.next] = now
+	t.next = (t.next + 1) % throughputWindow
+	t.count = min(t.count+1, throughputWindow)
+}
+
+// rate returns the recent completions per second, or 0 when there isn't
+// enough history for an estimate.
+func (t *throughputTracker) rate() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.count < 2 {
+		return 0
+	}
+	newest := t.times[(t.next+throughputWindow-1)%throughputWindow]
+	oldest := t.times[(t.next+throughputWindow-t.count)%throughputWindow]
+	elapsed := newest.Sub(oldest).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(t.count-1) / elapsed
+}
+
+// EstimateWait roughly estimates how long a task at the given queue position
+// will wait before being crawled, based on recent throughput. It reports false
+// when no estimate is possible yet.
+func (c *Crawler) EstimateWait(position int) (time.Duration, bool) {
+	rate := c.throughput.rate()
+	if rate == 0 {
+		return 0, false
+	}
+	return time.Duration(float64(position) / rate * float64(time.Second)), true
+}
@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// maxURLLength matches the limit most browsers and servers support.
+const maxURLLength = 2048
+
+// ValidateURL checks whether a URL may be submitted for crawling. It is used
+// by Submit, so single and batch submissions share the same rules.
+func (c *Crawler) ValidateURL(rawURL string) error {
+	if len(rawURL) > maxURLLength {
+		return ErrURLTooLong
+	}
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrInvalidScheme
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()) {
+		return ErrPrivateAddress
+	}
+	if c.isBlockedDomain(host) {
+		return ErrBlockedDomain
+	}
+	if c.config.BlockedExtensionSet[strings.ToLower(path.Ext(u.Path))] {
+		return ErrBlockedExtension
+	}
+	return nil
+}
+
+// isBlockedDomain reports whether host or one of its parent domains is blocked.
+func (c *Crawler) isBlockedDomain(host string) bool {
+	for host != "" {
+		if c.config.BlockedDomainSet[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return false
+		}
+		host = parent
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+)
+
+// warmupGate limits how many crawls run at once while the crawler warms up,
+// ramping linearly from a starting concurrency to the worker count over the
+// warm-up period, so targets and proxies don't see full load at startup.
+type warmupGate struct {
+	mu       sync.Mutex
+	start    time.Time
+	duration time.Duration
+	from, to int
+	active   int
+	released chan struct{} // Closed and replaced whenever a crawl finishes
+}
+
+func newWarmupGate(duration time.Duration, from, to int) *warmupGate {
+	return &warmupGate{
+		start:    time.Now(),
+		duration: duration,
+		from:     max(1, min(from, to)),
+		to:       to,
+		released: make(chan struct{}),
+	}
+}
+
+// limit returns the concurrency allowed at the given time.
+func (g *warmupGate) limit(now time.Time) int {
+	elapsed := now.Sub(g.start)
+	if elapsed >= g.duration {
+		return g.to
+	}
+	return g.from + int(float64(g.to-g.from)*float64(elapsed)/float64(g.duration))
+}
+
+// acquire waits until the current limit allows another crawl. It returns
+// false if stop is closed first.
+func (g *warmupGate) acquire(stop <-chan struct{}) bool {
+	for {
+		g.mu.Lock()
+		if g.active < g.limit(time.Now()) {
+			g.active++
+			g.mu.Unlock()
+			return true
+		}
+		released := g.released
+		g.mu.Unlock()
+
+		// The limit also grows on its own, so recheck periodically
+		select {
+		case <-released:
+		case <-time.After(250 * time.Millisecond):
+		case <-stop:
+			return false
+		}
+	}
+}
+
+func (g *warmupGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.active--
+	close(g.released)
+	g.released = make(chan struct{})
+}
+
+// effectiveConcurrency returns how many crawls may currently run at once.
+func (c *Crawler) effectiveConcurrency() int {
+	if c.warmup == nil {
+		return c.config.CrawlWorkers
+	}
+	return c.warmup.limit(time.Now())
+}
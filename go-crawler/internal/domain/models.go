@@ -1,36 +1,281 @@
-package domain
-
-import "time"
-
-// CrawlRequest is the payload for the API
-type CrawlRequest struct {
-	URLs       []string `json:"urls"`
-	ForceCrawl bool     `json:"force_crawl"` // Bypass 2-day rule
-}
-
-// PageData holds the extracted information from a crawled page
-type PageData struct {
-	URL        string
-	Title      string
-	Content    string
-	Headers    []string // e.g., H1, H2 tags
-	MetaTags   map[string]string
-	Images     []string
-	Status     string // "completed", "failed", "processing"
-	FailReason string
-	CrawledAt  time.Time
-}
-
-// URLTask represents a single URL to be processed by a worker
-type URLTask struct {
-	URL        string
-	ForceCrawl bool
-}
-
-// CrawlStatusResponse is the API response for a URL status query
-type CrawlStatusResponse struct {
-	URL        string    `json:"url"`
-	Status     string    `json:"status"`
-	FailReason string    `json:"fail_reason,omitempty"`
-	UpdatedAt  time.Time `json:"updated_at"`
-}
+package domain
+
+import "time"
+
+// CrawlRequest is the payload for the API
+type CrawlRequest struct {
+	URLs       []string `json:"urls"`
+	ForceCrawl bool     `json:"force_crawl"`       // Bypass 2-day rule
+	Referer    string   `json:"referer,omitempty"` // Sent as the Referer of the navigation
+	// Route single-page apps on the client side after the initial load
+	SPANavigation bool `json:"spa_navigation,omitempty"`
+	// Remove cookie consent banners before extraction
+	RemoveConsentBanners bool `json:"remove_consent_banners,omitempty"`
+	// Browser geolocation, timezone and locale overrides; none by default
+	Emulation *Emulation `json:"emulation,omitempty"`
+	// Named device preset to emulate, e.g. "iphone"; the browser's own
+	// viewport and user agent by default
+	Device string `json:"device,omitempty"`
+	// Extractors to run, e.g. ["content", "meta_tags"]; EXTRACTORS by default
+	Extract []string `json:"extract,omitempty"`
+	// schema.org type, e.g. "Product" or "Article", whose JSON-LD item is
+	// extracted and checked for the type's required properties
+	SchemaType string `json:"schema_type,omitempty"`
+	// URLs still queued at this time are abandoned; the submission is then
+	// tracked as a job, under its crawl_request_id
+	JobDeadline *time.Time `json:"job_deadline,omitempty"`
+	// Crawl no earlier than this time. The tasks wait in the schedule queue
+	// and are then crawled like any other
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	// Also crawl the language alternates announced via hreflang
+	FollowHreflang bool `json:"follow_hreflang,omitempty"`
+	// Scroll infinite-scroll pages to load more content before extraction
+	AutoScroll bool `json:"auto_scroll,omitempty"`
+	// Don't run the page's scripts, capturing the server-rendered page only
+	DisableJavaScript bool `json:"disable_javascript,omitempty"`
+	// Sent with the page's own requests, on top of the domain's configured
+	// headers and cookies, which they override
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies map[string]string `json:"cookies,omitempty"`
+}
+
+// Emulation holds the browser overrides used to crawl localized content
+type Emulation struct {
+	Geolocation *Geolocation `json:"geolocation,omitempty"`
+	Timezone    string       `json:"timezone,omitempty"` // IANA name, e.g. "Europe/Berlin"
+	Locale      string       `json:"locale,omitempty"`   // ICU locale, e.g. "de_DE"
+}
+
+// Geolocation is an emulated position in degrees, with accuracy in meters
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty"`
+}
+
+// SubmitResult is the per-URL outcome of a crawl submission
+type SubmitResult struct {
+	URL    string `json:"url"`
+	Status string `json:"status"` // "accepted", "scheduled", "rejected", "already_queued", or "duplicate_in_batch" for repeats of an earlier URL of the request
+	Error  string `json:"error,omitempty"`
+	// When a scheduled URL becomes eligible for crawling
+	EligibleAt *time.Time `json:"eligible_at,omitempty"`
+
+	// Best-effort estimates for accepted URLs; the ETA is omitted until there
+	// is enough recent throughput to base it on
+	QueuePosition int      `json:"queue_position,omitempty"`
+	ETASeconds    *float64 `json:"eta_seconds,omitempty"`
+}
+
+// SubmitCrawlResponse is the API response for a crawl submission
+type SubmitCrawlResponse struct {
+	CrawlRequestID string         `json:"crawl_request_id"`
+	Message        string         `json:"message"`
+	Results        []SubmitResult `json:"results"`
+}
+
+// PageData holds the extracted information from a crawled page
+type PageData struct {
+	URL         string            `json:"url"`
+	Domain      string            `json:"domain"`
+	Title       string            `json:"title"`
+	Content     string            `json:"content"`
+	Markdown    string            `json:"markdown,omitempty"` // The main content as markdown, only when EXTRACT_MARKDOWN is enabled or requested
+	RawHTML     string            `json:"-"`                  // Archived page HTML, only kept when STORE_RAW_HTML is enabled
+	Headers     []string          `json:"headers"`            // e.g., H1, H2 tags
+	MetaTags    map[string]string `json:"meta_tags"`
+	Keywords    []string          `json:"keywords"`               // From <meta name="keywords">, split on commas
+	PublishedAt *time.Time        `json:"published_at,omitempty"` // Article dates, nil when absent or unparseable
+	ModifiedAt  *time.Time        `json:"modified_at,omitempty"`
+	Images      []string          `json:"images"`
+	Hreflang    map[string]string `json:"hreflang,omitempty"`     // Language code -> absolute URL of the alternate
+	Feeds       []string          `json:"feeds,omitempty"`        // Absolute URLs of the RSS and Atom feeds announced
+	DOMHash     string            `json:"dom_hash,omitempty"`     // Hash of the tag structure, ignoring text
+	ContentHash string            `json:"content_hash,omitempty"` // SHA-256 of Content; the ETag of API responses
+	// Every <link rel> entry: lower-cased rel type, e.g. "canonical", "next"
+	// or "author", to the absolute URLs linked with it
+	Links map[string][]string `json:"links,omitempty"`
+	// Structured data from itemscope/itemprop attributes, nested items included
+	Microdata []*MicrodataItem `json:"microdata,omitempty"`
+	// URL of the earlier page with the same content, whose content this page's
+	// record doesn't repeat; only set when DEDUPLICATE_CONTENT is enabled
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	// A cookie consent banner was removed or accepted before extraction
+	ConsentHandled bool      `json:"consent_handled"`
+	Emails         []string  `json:"emails,omitempty"` // Only populated when contact extraction is enabled
+	Phones         []string  `json:"phones,omitempty"`
+	Status         string    `json:"status"` // "completed", "failed", "processing", "skipped"
+	FailReason     string    `json:"fail_reason,omitempty"`
+	CrawledAt      time.Time `json:"crawled_at"`
+	// File name of the screenshot taken when the last attempt failed, relative
+	// to FAILURE_SCREENSHOT_DIR
+	FailScreenshot string `json:"fail_screenshot,omitempty"`
+	// Browser overrides the page was crawled with, if any
+	Emulation *Emulation `json:"emulation,omitempty"`
+	// Device preset the page was crawled with, if any. Each device keeps a
+	// record of its own of a URL
+	Device string `json:"device,omitempty"`
+	// "http" when the plain HTTP fallback extracted more than the browser did,
+	// otherwise "browser"
+	ExtractionSource string `json:"extraction_source,omitempty"`
+	// Validators of the page's response, sent back on recrawls when
+	// CONDITIONAL_RECRAWL is enabled
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// Cookies in the browser at the end of the crawl, when CAPTURE_COOKIES is enabled
+	Cookies []Cookie `json:"cookies,omitempty"`
+	// The page's scripts were not run
+	JavaScriptDisabled bool `json:"javascript_disabled"`
+	// The crawl landed on a login or paywall gate, whose content was dropped
+	Gated    bool   `json:"gated"`
+	GateType string `json:"gate_type,omitempty"` // e.g. "login" or "paywall"
+	// Auto-scroll iterations run before extraction; 0 when not scrolled
+	ScrollIterations int `json:"scroll_iterations"`
+	// Fields from the domain's extraction rules: strings, or lists of strings
+	// for multiple-match fields
+	CustomFields map[string]any `json:"custom_fields,omitempty"`
+	// The JSON-LD item of the schema.org type the crawl asked for, normalized,
+	// and which of the type's required properties it lacks
+	StructuredData *StructuredData `json:"structured_data,omitempty"`
+	// Version of the extraction schema the record was produced with; 0 if never extracted
+	SchemaVersion int `json:"schema_version"`
+	// Set when extraction caps cut the page short; only used for logging
+	Truncated bool `json:"-"`
+
+	// Network usage accumulated from the browser's network events
+	RequestCount     int   `json:"request_count"`
+	BytesTransferred int64 `json:"bytes_transferred"`
+}
+
+// MicrodataItem is an item declared with itemscope, e.g. a schema.org
+// Product. Property values are strings, or nested items; a property can occur
+// more than once, so each maps to a list
+type MicrodataItem struct {
+	Type       []string         `json:"type,omitempty"` // From itemtype, e.g. "https://schema.org/Product"
+	ID         string           `json:"id,omitempty"`   // From itemid
+	Properties map[string][]any `json:"properties"`
+}
+
+// StructuredData is the JSON-LD item of a requested schema.org type found on
+// a page, with a report of how complete it is
+type StructuredData struct {
+	Type    string         `json:"type"`              // The requested type, e.g. "Product"
+	Item    map[string]any `json:"item,omitempty"`    // The first matching item, normalized; nil when none matched
+	Matches int            `json:"matches"`           // Items of the type found on the page
+	Missing []string       `json:"missing,omitempty"` // Required properties the item lacks or leaves empty
+	Valid   bool           `json:"valid"`             // An item matched and has every required property
+}
+
+// Cookie is a cookie set during a crawl. Value is only recorded when
+// CAPTURE_COOKIE_VALUES is enabled
+type Cookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value,omitempty"`
+	Domain   string     `json:"domain"`
+	Path     string     `json:"path"`
+	Expires  *time.Time `json:"expires,omitempty"` // nil for session cookies
+	HTTPOnly bool       `json:"http_only"`
+	Secure   bool       `json:"secure"`
+	SameSite string     `json:"same_site,omitempty"`
+}
+
+// URLTask represents a single URL to be processed by a worker
+type URLTask struct {
+	URL        string
+	ForceCrawl bool
+	Referer    string // The page this URL was discovered on, if any
+	// SPANavigation loads the app first and then routes to the URL via JS
+	SPANavigation        bool
+	RemoveConsentBanners bool
+	Emulation            *Emulation
+	Device               string
+	Extract              []string
+	SchemaType           string
+	JobID                string // The job the URL was submitted with, if any
+	FollowHreflang       bool
+	AutoScroll           bool
+	DisableJavaScript    bool
+	Headers              map[string]string
+	Cookies              map[string]string
+	Retry                bool // Taken from the delayed retry queue
+
+	// Record a CrawlExplanation of the crawl, for GET /api/page?explain=true
+	Explain bool
+}
+
+// CrawlStatusResponse is the API response for a URL status query
+type CrawlStatusResponse struct {
+	URL        string    `json:"url"`
+	Status     string    `json:"status"`
+	FailReason string    `json:"fail_reason,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// Screenshot of the failed page, when FAILURE_SCREENSHOT_DIR is set
+	FailScreenshot string `json:"fail_screenshot,omitempty"`
+	// Device preset of the record, if any
+	Device string `json:"device,omitempty"`
+
+	SchemaVersion int `json:"schema_version"`
+
+	// Where the URL is in its retry lifecycle; fail_reason holds the last error
+	RetryCount  int64      `json:"retry_count"`
+	MaxRetries  int        `json:"max_retries"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	// Set with the "scheduled" status of a URL waiting in the schedule
+	// queue, e.g. one submitted with not_before
+	EligibleAt *time.Time `json:"eligible_at,omitempty"`
+
+	RequestCount     int   `json:"request_count"`
+	BytesTransferred int64 `json:"bytes_transferred"`
+
+	// Only set when the client asks for a freshness check via max_age_seconds
+	Stale         bool `json:"stale,omitempty"`
+	RecrawlQueued bool `json:"recrawl_queued,omitempty"`
+}
+
+// CrawlExplanation is the diagnostic report of a crawl run with explain, for
+// tuning per-domain settings. It is only computed when asked for
+type CrawlExplanation struct {
+	FinalURL      string   `json:"final_url,omitempty"`
+	RedirectChain []string `json:"redirect_chain,omitempty"` // The page URL and the redirects followed from it, in order
+	StatusCode    int64    `json:"status_code,omitempty"`
+	// The wait conditions the crawl got past before capturing the page, in order
+	Waits []string `json:"waits,omitempty"`
+	// The custom field rules of the domain and how many elements they matched
+	Selectors []SelectorMatch `json:"selectors,omitempty"`
+	// Requests aborted as ads or trackers, up to 100
+	BlockedResources []string `json:"blocked_resources,omitempty"`
+	ExtractionSource string   `json:"extraction_source,omitempty"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+// SelectorMatch is the number of elements a custom field's selector matched
+type SelectorMatch struct {
+	Field    string `json:"field"`
+	Selector string `json:"selector"`
+	Matches  int    `json:"matches"`
+}
+
+// ExplainedPage is the response of GET /api/page?explain=true
+type ExplainedPage struct {
+	Page    *PageData         `json:"page,omitempty"`
+	Error   string            `json:"error,omitempty"` // Why the crawl failed or was skipped
+	Explain *CrawlExplanation `json:"explain"`
+}
+
+// DuplicateGroup lists the pages found to have the same content as a
+// canonical page, returned by /api/duplicates
+type DuplicateGroup struct {
+	Canonical  string   `json:"canonical"`
+	Duplicates []string `json:"duplicates"`
+}
+
+// DomainSummary is the per-domain rollup returned by /api/domains
+type DomainSummary struct {
+	Domain        string    `json:"domain"`
+	Pages         int       `json:"pages"`
+	Completed     int       `json:"completed"`
+	Failed        int       `json:"failed"`
+	Processing    int       `json:"processing"`
+	Skipped       int       `json:"skipped"`
+	LastCrawledAt time.Time `json:"last_crawled_at"` // Most recent update of any page of the domain
+}
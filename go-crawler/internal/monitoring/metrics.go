@@ -1,33 +1,354 @@
-package monitoring
-
-import (
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-)
-
-// Metrics holds all Prometheus metrics for the application.
-type Metrics struct {
-	CrawledTotal *prometheus.CounterVec
-	ErrorsTotal  *prometheus.CounterVec
-}
-
-func NewMetrics() *Metrics {
-	return &Metrics{
-		CrawledTotal: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "crawler_urls_processed_total",
-			Help: "The total number of URLs processed",
-		}, nil),
-		ErrorsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "crawler_errors_total",
-			Help: "The total number of errors encountered",
-		}, []string{"type"}), // e.g., 'crawl_failed', 'db_save_failed'
-	}
-}
-
-func (m *Metrics) IncCrawledTotal() {
-	m.CrawledTotal.WithLabelValues().Inc()
-}
-
-func (m *Metrics) IncErrorsTotal(errorType string) {
-	m.ErrorsTotal.WithLabelValues(errorType).Inc()
-}
+package monitoring
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics holds all Prometheus metrics for the application.
+type Metrics struct {
+	CrawledTotal          *prometheus.CounterVec
+	CrawlSkippedTotal     *prometheus.CounterVec
+	ErrorsTotal           *prometheus.CounterVec
+	NetworkRequestsTotal  prometheus.Counter
+	BytesTransferredTotal prometheus.Counter
+	RequestsPerCrawl      prometheus.Histogram
+	DomainInFlight        *prometheus.GaugeVec
+	QueueSize             prometheus.Gauge
+	RetryQueueDepth       prometheus.Gauge
+	OldestRetrySeconds    prometheus.Gauge
+	DomainDelaySeconds    *prometheus.GaugeVec
+	QueueEventsTotal      *prometheus.CounterVec
+	SoftBudgetExceeded    prometheus.Counter
+	RetentionDeleted      prometheus.Counter
+	DOMStructureChanges   prometheus.Counter
+	ProxyInFlight         *prometheus.GaugeVec
+	AllocatorWaitSeconds  prometheus.Histogram
+	AllocatorTimeouts     prometheus.Counter
+	ExtractedItems        *prometheus.HistogramVec
+	ContentLengthBytes    prometheus.Histogram
+	EffectiveConcurrency  prometheus.Gauge
+	EmptyExtractionRate   *prometheus.GaugeVec
+	EmptyExtractionSpikes prometheus.Counter
+	BlockedRequests       prometheus.Histogram
+	HTTPFallbacks         *prometheus.CounterVec
+	HTTPFallbackTruncated prometheus.Counter
+	NotModified           prometheus.Counter
+	PageSizeBytes         *prometheus.HistogramVec
+	ResponseTimeSeconds   *prometheus.HistogramVec
+	ChromeProcesses       prometheus.Gauge
+	ChromeOrphansReaped   prometheus.Counter
+	CrawlEventsDropped    prometheus.Counter
+	GatedPages            *prometheus.CounterVec
+	DomainRequests        *prometheus.CounterVec
+	RateLimitShared       prometheus.Gauge
+	StaleRecovered        prometheus.Counter
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		CrawledTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_urls_processed_total",
+			Help: "The total number of URLs processed",
+		}, nil),
+		CrawlSkippedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_urls_skipped_total",
+			Help: "The total number of URLs skipped without being crawled",
+		}, []string{"reason"}), // e.g., 'recently_crawled'
+		ErrorsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_errors_total",
+			Help: "The total number of errors encountered",
+		}, []string{"type"}), // e.g., 'crawl_failed', 'db_save_failed'
+		NetworkRequestsTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_network_requests_total",
+			Help: "The total number of network requests made by the browser while crawling",
+		}),
+		BytesTransferredTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_bytes_transferred_total",
+			Help: "The total number of bytes transferred by the browser while crawling",
+		}),
+		RequestsPerCrawl: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "crawler_requests_per_crawl",
+			Help:    "The number of network requests made per crawled page",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 11), // 1 .. 1024
+		}),
+		DomainInFlight: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crawler_domain_inflight_crawls",
+			Help: "The number of crawls currently running per domain",
+		}, []string{"domain"}),
+		QueueEventsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_queue_events_total",
+			Help: "The number of queue events emitted",
+		}, []string{"event"}), // 'queue_empty' or 'queue_backlog'
+		SoftBudgetExceeded: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_soft_budget_exceeded_total",
+			Help: "The number of crawls preempted by the worker's soft per-cycle budget",
+		}),
+		RetentionDeleted: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_retention_deleted_total",
+			Help: "The number of pages deleted for being older than the retention window",
+		}),
+		DOMStructureChanges: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_dom_structure_changes_total",
+			Help: "The number of recrawled pages whose DOM structure hash changed",
+		}),
+		ProxyInFlight: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crawler_proxy_inflight_crawls",
+			Help: "The number of crawls currently running through each proxy, capped by PROXY_MAX_CONCURRENCY",
+		}, []string{"proxy"}),
+		AllocatorWaitSeconds: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "crawler_allocator_wait_seconds",
+			Help:    "How long crawls waited for a browser from the pool bounded by BROWSER_POOL_SIZE",
+			Buckets: []float64{0.001, 0.01, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}),
+		AllocatorTimeouts: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_allocator_timeouts_total",
+			Help: "The number of crawls that gave up waiting for a browser after BROWSER_ACQUIRE_TIMEOUT",
+		}),
+		ExtractedItems: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "crawler_extracted_items",
+			Help:    "The number of items extracted per successfully crawled page",
+			Buckets: append([]float64{0}, prometheus.ExponentialBuckets(1, 2, 13)...), // 0, 1 .. 4096
+		}, []string{"field"}), // 'images', 'headers', 'meta_tags', 'keywords'
+		ContentLengthBytes: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "crawler_content_length_bytes",
+			Help:    "The length of the text content extracted per successfully crawled page",
+			Buckets: append([]float64{0}, prometheus.ExponentialBuckets(64, 4, 9)...), // 0, 64 .. 4 MiB
+		}),
+		EffectiveConcurrency: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "crawler_effective_concurrency",
+			Help: "The number of crawls currently allowed to run at once, lower than the worker count during warm-up",
+		}),
+		EmptyExtractionRate: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crawler_empty_extraction_rate",
+			Help: "The share of recently crawled pages per domain extracted without a title or content",
+		}, []string{"domain"}),
+		EmptyExtractionSpikes: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_empty_extraction_spikes_total",
+			Help: "The number of times a domain's empty extraction rate crossed the alert threshold",
+		}),
+		BlockedRequests: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "crawler_blocked_requests_per_crawl",
+			Help:    "The number of browser requests to blocked resource domains aborted per crawled page",
+			Buckets: []float64{0, 1, 2, 5, 10, 20, 50, 100, 200},
+		}),
+		HTTPFallbacks: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_http_fallbacks_total",
+			Help: "The number of near-empty browser extractions re-fetched over plain HTTP, by which extraction was kept",
+		}, []string{"winner"}), // 'browser', 'http' or 'error'
+		HTTPFallbackTruncated: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_http_fallback_truncated_total",
+			Help: "The number of plain HTTP fallback responses truncated at HTTP_FALLBACK_MAX_BODY",
+		}),
+		NotModified: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_not_modified_total",
+			Help: "The number of recrawls answered with 304 Not Modified, whose stored record was kept",
+		}),
+		PageSizeBytes: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "crawler_page_size_bytes",
+			Help:    "The bytes transferred per crawled page, including its resources, by status class of the main document",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 9), // 1 KiB .. 64 MiB
+		}, []string{"status_class"}), // '2xx' .. '5xx', or 'none'
+		ResponseTimeSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "crawler_response_time_seconds",
+			Help:    "The time from requesting a page's main document, including redirects, to its response, by status class",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"status_class"}),
+		QueueSize: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "crawler_queue_size",
+			Help: "The number of URLs waiting in the task queue",
+		}),
+		RetryQueueDepth: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "crawler_retry_queue_depth",
+			Help: "The number of URLs scheduled for retry",
+		}),
+		OldestRetrySeconds: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "crawler_oldest_retry_seconds",
+			Help: "Seconds since the earliest scheduled retry became due (negative if not yet due)",
+		}),
+		DomainDelaySeconds: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crawler_domain_delay_seconds",
+			Help: "The current adaptive delay between requests per domain",
+		}, []string{"domain"}),
+		ChromeProcesses: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "crawler_chrome_processes",
+			Help: "The number of browser processes launched by this crawler that are running",
+		}),
+		ChromeOrphansReaped: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_chrome_orphans_reaped_total",
+			Help: "The number of browser processes left behind by earlier crawler runs that were killed",
+		}),
+		CrawlEventsDropped: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_crawl_events_dropped_total",
+			Help: "The number of crawl events not delivered to a gRPC event stream that fell behind",
+		}),
+		GatedPages: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_gated_pages_total",
+			Help: "The number of crawls that landed on a login or paywall gate, by gate type",
+		}, []string{"type"}),
+		DomainRequests: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_domain_requests_total",
+			Help: "The number of request slots reserved per domain; summed over instances, its rate is the effective per-domain rate. Domains past RATE_LIMIT_METRIC_DOMAINS count as \"other\"",
+		}, []string{"domain"}),
+		RateLimitShared: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "crawler_rate_limit_shared",
+			Help: "1 while domain pacing is shared through the state store, 0 while it falls back to this instance alone",
+		}),
+		StaleRecovered: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_stale_processing_recovered_total",
+			Help: "The number of URLs re-enqueued after being left in processing, e.g. by a crashed worker",
+		}),
+	}
+}
+
+func (m *Metrics) IncCrawledTotal() {
+	m.CrawledTotal.WithLabelValues().Inc()
+}
+
+func (m *Metrics) IncCrawlSkipped(reason string) {
+	m.CrawlSkippedTotal.WithLabelValues(reason).Inc()
+}
+
+func (m *Metrics) IncErrorsTotal(errorType string) {
+	m.ErrorsTotal.WithLabelValues(errorType).Inc()
+}
+
+func (m *Metrics) ObserveNetworkUsage(requests int, bytes int64) {
+	m.NetworkRequestsTotal.Add(float64(requests))
+	m.BytesTransferredTotal.Add(float64(bytes))
+	m.RequestsPerCrawl.Observe(float64(requests))
+}
+
+func (m *Metrics) IncDomainInFlight(domain string) {
+	m.DomainInFlight.WithLabelValues(domain).Inc()
+}
+
+func (m *Metrics) DecDomainInFlight(domain string) {
+	m.DomainInFlight.WithLabelValues(domain).Dec()
+}
+
+func (m *Metrics) SetQueueSize(size int) {
+	m.QueueSize.Set(float64(size))
+}
+
+func (m *Metrics) SetRetryQueueStats(depth int64, oldestAge time.Duration) {
+	m.RetryQueueDepth.Set(float64(depth))
+	m.OldestRetrySeconds.Set(oldestAge.Seconds())
+}
+
+func (m *Metrics) SetDomainDelay(domain string, delay time.Duration) {
+	m.DomainDelaySeconds.WithLabelValues(domain).Set(delay.Seconds())
+}
+
+func (m *Metrics) IncQueueEvents(event string) {
+	m.QueueEventsTotal.WithLabelValues(event).Inc()
+}
+
+func (m *Metrics) IncSoftBudgetExceeded() {
+	m.SoftBudgetExceeded.Inc()
+}
+
+func (m *Metrics) AddRetentionDeleted(count int) {
+	m.RetentionDeleted.Add(float64(count))
+}
+
+func (m *Metrics) IncDOMStructureChanges() {
+	m.DOMStructureChanges.Inc()
+}
+
+func (m *Metrics) IncProxyInFlight(proxy string) {
+	m.ProxyInFlight.WithLabelValues(proxy).Inc()
+}
+
+func (m *Metrics) DecProxyInFlight(proxy string) {
+	m.ProxyInFlight.WithLabelValues(proxy).Dec()
+}
+
+func (m *Metrics) ObserveAllocatorWait(wait time.Duration) {
+	m.AllocatorWaitSeconds.Observe(wait.Seconds())
+}
+
+func (m *Metrics) IncAllocatorTimeouts() {
+	m.AllocatorTimeouts.Inc()
+}
+
+func (m *Metrics) ObserveExtraction(images, headers, metaTags, keywords, contentLength int) {
+	m.ExtractedItems.WithLabelValues("images").Observe(float64(images))
+	m.ExtractedItems.WithLabelValues("headers").Observe(float64(headers))
+	m.ExtractedItems.WithLabelValues("meta_tags").Observe(float64(metaTags))
+	m.ExtractedItems.WithLabelValues("keywords").Observe(float64(keywords))
+	m.ContentLengthBytes.Observe(float64(contentLength))
+}
+
+func (m *Metrics) SetEffectiveConcurrency(limit int) {
+	m.EffectiveConcurrency.Set(float64(limit))
+}
+
+func (m *Metrics) SetEmptyExtractionRate(domain string, rate float64) {
+	m.EmptyExtractionRate.WithLabelValues(domain).Set(rate)
+}
+
+func (m *Metrics) DeleteEmptyExtractionRate(domain string) {
+	m.EmptyExtractionRate.DeleteLabelValues(domain)
+}
+
+func (m *Metrics) IncEmptyExtractionSpikes() {
+	m.EmptyExtractionSpikes.Inc()
+}
+
+func (m *Metrics) ObserveBlockedRequests(count int) {
+	m.BlockedRequests.Observe(float64(count))
+}
+
+func (m *Metrics) IncHTTPFallbacks(winner string) {
+	m.HTTPFallbacks.WithLabelValues(winner).Inc()
+}
+
+func (m *Metrics) ObservePageResponse(statusClass string, bytes int64, responseTime time.Duration) {
+	m.PageSizeBytes.WithLabelValues(statusClass).Observe(float64(bytes))
+	if responseTime > 0 {
+		m.ResponseTimeSeconds.WithLabelValues(statusClass).Observe(responseTime.Seconds())
+	}
+}
+
+func (m *Metrics) IncHTTPFallbackTruncated() {
+	m.HTTPFallbackTruncated.Inc()
+}
+
+func (m *Metrics) IncNotModified() {
+	m.NotModified.Inc()
+}
+
+func (m *Metrics) SetChromeProcesses(count int) {
+	m.ChromeProcesses.Set(float64(count))
+}
+
+func (m *Metrics) IncChromeOrphansReaped() {
+	m.ChromeOrphansReaped.Inc()
+}
+
+func (m *Metrics) AddCrawlEventsDropped(count int) {
+	m.CrawlEventsDropped.Add(float64(count))
+}
+
+func (m *Metrics) IncGatedPages(gateType string) {
+	m.GatedPages.WithLabelValues(gateType).Inc()
+}
+
+func (m *Metrics) IncDomainRequests(domain string) {
+	m.DomainRequests.WithLabelValues(domain).Inc()
+}
+
+func (m *Metrics) SetRateLimitShared(shared bool) {
+	if shared {
+		m.RateLimitShared.Set(1)
+	} else {
+		m.RateLimitShared.Set(0)
+	}
+}
+
+func (m *Metrics) AddStaleRecovered(count int) {
+	m.StaleRecovered.Add(float64(count))
+}
@@ -0,0 +1,733 @@
+package storage
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileStore is a PageStore that keeps each page as a JSON file under a
+// directory, sharded by the first two hex digits of the SHA-256 of its
+// PageKey.
+// Writes go through a temporary file and a rename, so readers never see a
+// partial record, and are serialized within the process. Records are read,
+// modified and written back, so only one process may use a directory, which
+// a lock file enforces. There are no indexes: listings, summaries and exports
+// read every file, so it suits small local crawls, not searching or
+// aggregating large ones.
+type FileStore struct {
+	dir  string
+	mu   sync.RWMutex
+	lock *os.File // Held for the life of the process
+}
+
+// fileRecord is the stored form of a page. PageData.CrawledAt holds the time
+// of the last update, like updated_at in Postgres.
+type fileRecord struct {
+	Page      domain.PageData `json:"page"`
+	RawHTML   string          `json:"raw_html,omitempty"`
+	CreatedAt time.Time       `json:"created_at"` // Orders pages like the ids of PostgresStore
+}
+
+// key returns the PageKey of the record's page.
+func (r *fileRecord) key() string {
+	return PageKey(r.Page.URL, r.Page.Device)
+}
+
+// fileSnapshot is the stored queue snapshot of one instance.
+type fileSnapshot struct {
+	Instance   string         `json:"instance"`
+	SnapshotAt time.Time      `json:"snapshot_at"`
+	Entries    []ScheduledURL `json:"entries"`
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	s := &FileStore{dir: dir}
+	for _, sub := range []string{s.pagesDir(), s.snapshotDir()} {
+		if err := os.MkdirAll(sub, 0o755); err != nil {
+			return nil, fmt.Errorf("unable to create data directory: %w", err)
+		}
+	}
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock data directory, which supports a single instance: %w", err)
+	}
+	s.lock = lock
+	return s, nil
+}
+
+func (s *FileStore) Ping(ctx context.Context) error {
+	_, err := os.Stat(s.pagesDir())
+	return err
+}
+
+// SaveData stores extracted page data, merging it with the stored record the
+// same way PostgresStore does.
+func (s *FileStore) SaveData(ctx context.Context, data *domain.PageData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if data.Domain == "" {
+		data.Domain = hostOf(data.URL)
+	}
+	old, err := s.read(PageKey(data.URL, data.Device))
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	now := time.Now()
+	rec := &fileRecord{Page: *data, CreatedAt: now}
+	rec.Page.CrawledAt = now
+	rec.Page.RawHTML = ""
+	if old != nil {
+		rec.CreatedAt = old.CreatedAt
+		if rec.Page.SchemaVersion == 0 {
+			rec.Page.SchemaVersion = old.Page.SchemaVersion
+		}
+		if rec.Page.DOMHash == "" {
+			rec.Page.DOMHash = old.Page.DOMHash
+		}
+		if rec.Page.ContentHash == "" && !data.Gated {
+			rec.Page.ContentHash = old.Page.ContentHash
+		}
+		if len(old.Page.MetaTags) > 0 {
+			rec.Page.MetaTags = maps.Clone(old.Page.MetaTags)
+			maps.Copy(rec.Page.MetaTags, data.MetaTags)
+		}
+	}
+
+	// A page whose content others duplicate hands it to them before changing
+	// it, becoming a duplicate itself or landing on a gate
+	if old != nil && old.Page.ContentHash != "" && old.Page.DuplicateOf == "" &&
+		(data.DuplicateOf != "" || data.Gated || data.ContentHash != "" && data.ContentHash != old.Page.ContentHash) {
+		if err := s.promoteDuplicates(map[string]*fileRecord{old.key(): old}); err != nil {
+			return err
+		}
+	}
+
+	// Keep the stored content and archived HTML when none is given, unless
+	// the page is a duplicate, which points to the page holding its content,
+	// or gated, which makes it stale
+	if old != nil && data.DuplicateOf == "" && !data.Gated {
+		rec.Page.Content, rec.Page.Markdown, rec.RawHTML = old.Page.Content, old.Page.Markdown, old.RawHTML
+	}
+	if data.Content != "" || data.Markdown != "" || data.RawHTML != "" {
+		rec.Page.Content, rec.Page.Markdown = data.Content, data.Markdown
+		if data.RawHTML != "" {
+			rec.RawHTML = data.RawHTML
+		}
+	}
+	return s.write(rec)
+}
+
+// GetCrawlStatus retrieves the current status of a URL.
+func (s *FileStore) GetCrawlStatus(ctx context.Context, url string) (*domain.CrawlStatusResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, err := s.read(url)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.CrawlStatusResponse{
+		URL:              rec.Page.URL,
+		Status:           rec.Page.Status,
+		FailReason:       rec.Page.FailReason,
+		FailScreenshot:   rec.Page.FailScreenshot,
+		Device:           rec.Page.Device,
+		UpdatedAt:        rec.Page.CrawledAt,
+		RequestCount:     rec.Page.RequestCount,
+		BytesTransferred: rec.Page.BytesTransferred,
+		SchemaVersion:    rec.Page.SchemaVersion,
+	}, nil
+}
+
+// RecordFailReason stores the error and screenshot, if any, of a failed
+// attempt that will be retried, without changing the page's status.
+func (s *FileStore) RecordFailReason(ctx context.Context, url, reason, screenshot string) error {
+	return s.update(url, func(rec *fileRecord) bool {
+		rec.Page.FailReason, rec.Page.FailScreenshot = reason, screenshot
+		return true
+	})
+}
+
+// MarkSkipped records why a URL was skipped, storing it if it isn't stored
+// yet. A completed page keeps its status and data.
+func (s *FileStore) MarkSkipped(ctx context.Context, url, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	rec, err := s.read(url)
+	if isNotFound(err) {
+		page, device := SplitPageKey(url)
+		rec = &fileRecord{Page: domain.PageData{URL: page, Device: device, Domain: hostOf(page)}, CreatedAt: now}
+	} else if err != nil {
+		return err
+	}
+	if rec.Page.Status == "completed" {
+		return nil
+	}
+	rec.Page.Status, rec.Page.FailReason, rec.Page.CrawledAt = "skipped", reason, now
+	return s.write(rec)
+}
+
+// MarkUnchanged records that a completed page was found unchanged, updating
+// its crawl time and keeping its data.
+func (s *FileStore) MarkUnchanged(ctx context.Context, url string) error {
+	return s.update(url, func(rec *fileRecord) bool {
+		if rec.Page.Status != "completed" {
+			return false
+		}
+		rec.Page.CrawledAt = time.Now()
+		return true
+	})
+}
+
+// GetPageData retrieves the stored data of a URL.
+func (s *FileStore) GetPageData(ctx context.Context, url string) (*domain.PageData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, err := s.read(url)
+	if err != nil {
+		return nil, err
+	}
+	return &rec.Page, nil
+}
+
+// GetDOMHash retrieves the stored DOM structure hash of a URL, or an empty
+// string when the URL has none.
+func (s *FileStore) GetDOMHash(ctx context.Context, url string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, err := s.read(url)
+	if isNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return rec.Page.DOMHash, nil
+}
+
+// GetRawHTML retrieves the archived HTML of a previously crawled URL.
+func (s *FileStore) GetRawHTML(ctx context.Context, url string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, err := s.read(url)
+	if err != nil {
+		return "", err
+	}
+	if rec.RawHTML == "" {
+		return "", fmt.Errorf("not_found")
+	}
+	return rec.RawHTML, nil
+}
+
+// URLsBelowSchemaVersion returns up to limit URLs with archived HTML whose
+// records were extracted with a schema older than version.
+func (s *FileStore) URLsBelowSchemaVersion(ctx context.Context, version, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.matchingKeys(limit, func(rec *fileRecord) bool {
+		return rec.Page.SchemaVersion < version && rec.RawHTML != ""
+	})
+}
+
+// DeleteDomain deletes all pages of a domain, returning the deleted pages.
+func (s *FileStore) DeleteDomain(ctx context.Context, domainName string) ([]DeletedPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(0, func(rec *fileRecord) bool {
+		return rec.Page.Domain == domainName
+	})
+}
+
+// DeleteExpired deletes up to limit pages last updated before the cutoff,
+// returning them. Pages still being crawled are left alone.
+func (s *FileStore) DeleteExpired(ctx context.Context, before time.Time, limit int) ([]DeletedPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(limit, func(rec *fileRecord) bool {
+		return rec.Page.CrawledAt.Before(before) && rec.Page.Status != "processing"
+	})
+}
+
+// StaleProcessing returns up to limit URLs that have been "processing" since
+// before, as left by a crashed worker.
+func (s *FileStore) StaleProcessing(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.matchingKeys(limit, func(rec *fileRecord) bool {
+		return rec.Page.Status == "processing" && rec.Page.CrawledAt.Before(before)
+	})
+}
+
+// ResetStaleProcessing marks the given pages as skipped for having been
+// interrupted, unless a crawl has marked them as processing again, or
+// finished them, since before.
+func (s *FileStore) ResetStaleProcessing(ctx context.Context, urls []string, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, url := range urls {
+		rec, err := s.read(url)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if rec.Page.Status != "processing" || !rec.Page.CrawledAt.Before(before) {
+			continue
+		}
+		rec.Page.Status, rec.Page.FailReason, rec.Page.CrawledAt = "skipped", "interrupted", time.Now()
+		if err := s.write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CanonicalURL returns the URL of the earliest stored page with the given
+// content hash that isn't itself a duplicate, other than url.
+func (s *FileStore) CanonicalURL(ctx context.Context, contentHash, url string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	url, device := SplitPageKey(url)
+	keys, err := s.matchingKeys(1, func(rec *fileRecord) bool {
+		return rec.Page.ContentHash == contentHash && rec.Page.URL != url && rec.Page.Device == device &&
+			rec.Page.Status == "completed" && rec.Page.DuplicateOf == ""
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("not_found")
+	}
+	canonical, _ := SplitPageKey(keys[0])
+	return canonical, nil
+}
+
+// DuplicateGroups returns up to limit canonical pages with the URLs found to
+// duplicate them, optionally of a single domain, largest groups first.
+func (s *FileStore) DuplicateGroups(ctx context.Context, domainName string, limit int) ([]domain.DuplicateGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	byCanonical := make(map[string][]string)
+	err := s.each(func(rec *fileRecord) error {
+		if rec.Page.DuplicateOf != "" && (domainName == "" || rec.Page.Domain == domainName) {
+			byCanonical[rec.Page.DuplicateOf] = append(byCanonical[rec.Page.DuplicateOf], rec.Page.URL)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]domain.DuplicateGroup, 0, len(byCanonical))
+	for canonical, urls := range byCanonical {
+		sort.Strings(urls)
+		groups = append(groups, domain.DuplicateGroup{Canonical: canonical, Duplicates: urls})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Duplicates) != len(groups[j].Duplicates) {
+			return len(groups[i].Duplicates) > len(groups[j].Duplicates)
+		}
+		return groups[i].Canonical < groups[j].Canonical
+	})
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
+
+// DomainSummaries returns page counts and the last crawl time of every domain.
+// Domains that haven't been crawled the longest come first.
+func (s *FileStore) DomainSummaries(ctx context.Context) ([]domain.DomainSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	byDomain := make(map[string]*domain.DomainSummary)
+	err := s.each(func(rec *fileRecord) error {
+		if rec.Page.Domain == "" {
+			return nil
+		}
+		d, ok := byDomain[rec.Page.Domain]
+		if !ok {
+			d = &domain.DomainSummary{Domain: rec.Page.Domain}
+			byDomain[rec.Page.Domain] = d
+		}
+		d.Pages++
+		switch rec.Page.Status {
+		case "completed":
+			d.Completed++
+		case "failed":
+			d.Failed++
+		case "processing":
+			d.Processing++
+		case "skipped":
+			d.Skipped++
+		}
+		if rec.Page.CrawledAt.After(d.LastCrawledAt) {
+			d.LastCrawledAt = rec.Page.CrawledAt
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]domain.DomainSummary, 0, len(byDomain))
+	for _, d := range byDomain {
+		summaries = append(summaries, *d)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if !summaries[i].LastCrawledAt.Equal(summaries[j].LastCrawledAt) {
+			return summaries[i].LastCrawledAt.Before(summaries[j].LastCrawledAt)
+		}
+		return summaries[i].Domain < summaries[j].Domain
+	})
+	return summaries, nil
+}
+
+// CountDomainPages returns the number of pages stored for a domain.
+func (s *FileStore) CountDomainPages(ctx context.Context, domainName string) (int, error) {
+	urls, err := s.DomainURLs(ctx, domainName)
+	return len(urls), err
+}
+
+// DomainURLs returns the URLs of all pages stored for a domain.
+func (s *FileStore) DomainURLs(ctx context.Context, domainName string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.matchingKeys(0, func(rec *fileRecord) bool {
+		return rec.Page.Domain == domainName
+	})
+}
+
+// SaveQueueSnapshot replaces the queue snapshot of an instance with the given
+// entries. Each instance only overwrites what it snapshotted itself.
+func (s *FileStore) SaveQueueSnapshot(ctx context.Context, instance string, entries []ScheduledURL, at time.Time) error {
+	raw, err := json.Marshal(fileSnapshot{Instance: instance, SnapshotAt: at, Entries: entries})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.snapshotDir(), hashName(instance)+".json"), raw)
+}
+
+// QueueSnapshot returns the snapshotted URLs of all instances, leaving out
+// those completed since their snapshot was taken.
+func (s *FileStore) QueueSnapshot(ctx context.Context) ([]ScheduledURL, error) {
+	snapshots, err := s.readSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	earliest := make(map[string]ScheduledURL)
+	for _, snapshot := range snapshots {
+		for _, e := range snapshot.Entries {
+			rec, err := s.read(e.URL)
+			if err != nil && !isNotFound(err) {
+				return nil, err
+			}
+			if rec != nil && rec.Page.Status == "completed" && !rec.Page.CrawledAt.Before(snapshot.SnapshotAt) {
+				continue
+			}
+			if e.Task.URL == "" {
+				e.Task.URL, e.Task.Device = SplitPageKey(e.URL) // Taken before tasks were snapshotted
+			}
+			if first, ok := earliest[e.URL]; !ok || e.DueAt.Before(first.DueAt) {
+				earliest[e.URL] = e
+			}
+		}
+	}
+
+	entries := make([]ScheduledURL, 0, len(earliest))
+	for _, e := range earliest {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+	return entries, nil
+}
+
+// DeleteQueueSnapshots deletes the queue snapshots taken before the given
+// time, of all instances.
+func (s *FileStore) DeleteQueueSnapshots(ctx context.Context, before time.Time) error {
+	snapshots, err := s.readSnapshots()
+	if err != nil {
+		return err
+	}
+	for file, snapshot := range snapshots {
+		if !snapshot.SnapshotAt.Before(before) {
+			continue
+		}
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSnapshots reads the queue snapshots of all instances, by file.
+func (s *FileStore) readSnapshots() (map[string]*fileSnapshot, error) {
+	files, err := filepath.Glob(filepath.Join(s.snapshotDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make(map[string]*fileSnapshot, len(files))
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Deleted since the listing
+		}
+		if err != nil {
+			return nil, err
+		}
+		var snapshot fileSnapshot
+		if err := json.Unmarshal(raw, &snapshot); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		snapshots[file] = &snapshot
+	}
+	return snapshots, nil
+}
+
+// ExportDomain passes all pages of a domain updated since the given time to
+// fn, in no particular order, reading one file at a time.
+func (s *FileStore) ExportDomain(ctx context.Context, domainName string, since time.Time, fn func(*domain.PageData) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.each(func(rec *fileRecord) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if rec.Page.Domain != domainName || rec.Page.CrawledAt.Before(since) {
+			return nil
+		}
+		return fn(&rec.Page)
+	})
+}
+
+func (s *FileStore) pagesDir() string {
+	return filepath.Join(s.dir, "pages")
+}
+
+func (s *FileStore) snapshotDir() string {
+	return filepath.Join(s.dir, "queue_snapshot")
+}
+
+// pagePath returns the file of the record of a PageKey. Records of no device
+// preset are named after their URL alone, as before devices had their own.
+func (s *FileStore) pagePath(key string) string {
+	name := hashName(key)
+	return filepath.Join(s.pagesDir(), name[:2], name+".json")
+}
+
+func (s *FileStore) read(key string) (*fileRecord, error) {
+	raw, err := os.ReadFile(s.pagePath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("not_found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec fileRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return &rec, nil
+}
+
+func (s *FileStore) write(rec *fileRecord) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	path := s.pagePath(rec.key())
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, raw)
+}
+
+// update applies fn to the stored record of a PageKey and writes it back if
+// fn reports a change. A page that isn't stored is left alone.
+func (s *FileStore) update(key string, fn func(*fileRecord) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, err := s.read(key)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !fn(rec) {
+		return nil
+	}
+	return s.write(rec)
+}
+
+// each calls fn with every stored record, in no particular order.
+func (s *FileStore) each(fn func(*fileRecord) error) error {
+	return filepath.WalkDir(s.pagesDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		raw, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Deleted by another process meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		var rec fileRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return fn(&rec)
+	})
+}
+
+// matchingKeys returns the PageKeys of up to limit records matching fn, or
+// all of them when limit is 0, oldest first.
+func (s *FileStore) matchingKeys(limit int, fn func(*fileRecord) bool) ([]string, error) {
+	type match struct {
+		key       string
+		createdAt time.Time
+	}
+	var matches []match
+	err := s.each(func(rec *fileRecord) error {
+		if fn(rec) {
+			matches = append(matches, match{rec.key(), rec.CreatedAt})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].createdAt.Before(matches[j].createdAt) })
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	keys := make([]string, len(matches))
+	for i, m := range matches {
+		keys[i] = m.key
+	}
+	return keys, nil
+}
+
+// delete removes up to limit records matching fn, or all of them when limit
+// is 0, oldest first, returning them. Records duplicating a removed one
+// are given its content first. The caller holds the write lock.
+func (s *FileStore) delete(limit int, fn func(*fileRecord) bool) ([]DeletedPage, error) {
+	keys, err := s.matchingKeys(limit, fn)
+	if err != nil {
+		return nil, err
+	}
+	removed := make(map[string]*fileRecord, len(keys))
+	for _, key := range keys {
+		rec, err := s.read(key)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		removed[key] = rec
+	}
+	if err := s.promoteDuplicates(removed); err != nil {
+		return nil, err
+	}
+	pages := make([]DeletedPage, 0, len(removed))
+	for _, key := range keys {
+		rec, ok := removed[key]
+		if !ok {
+			continue
+		}
+		if err := os.Remove(s.pagePath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return pages, err
+		}
+		pages = append(pages, DeletedPage{URL: rec.Page.URL, Device: rec.Page.Device, FailScreenshot: rec.Page.FailScreenshot})
+	}
+	return pages, nil
+}
+
+// promoteDuplicates gives the content of each of the records, by PageKey,
+// that others of its device duplicate to the earliest of those others not
+// among the records, and points the rest to it, so duplicates keep their
+// content when the record's changes or goes. The caller holds the write lock.
+func (s *FileStore) promoteDuplicates(records map[string]*fileRecord) error {
+	duplicates := make(map[string][]*fileRecord)
+	err := s.each(func(rec *fileRecord) error {
+		canonical := PageKey(rec.Page.DuplicateOf, rec.Page.Device)
+		if _, ok := records[canonical]; ok && rec.Page.DuplicateOf != "" {
+			if _, gone := records[rec.key()]; !gone {
+				duplicates[canonical] = append(duplicates[canonical], rec)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, recs := range duplicates {
+		sort.Slice(recs, func(i, j int) bool { return recs[i].CreatedAt.Before(recs[j].CreatedAt) })
+		canonical, heir := records[key], recs[0]
+		heir.Page.DuplicateOf = ""
+		heir.Page.Content, heir.Page.Markdown = canonical.Page.Content, canonical.Page.Markdown
+		if canonical.RawHTML != "" {
+			heir.RawHTML = canonical.RawHTML
+		}
+		for _, rec := range recs[1:] {
+			rec.Page.DuplicateOf = heir.Page.URL
+		}
+		for _, rec := range recs {
+			if err := s.write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path by renaming a fully written
+// temporary file over it.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func hashName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func isNotFound(err error) bool {
+	return err != nil && err.Error() == "not_found"
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"testing"
+)
+
+func TestFileStoreMarkSkipped(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Skips are decided before the URL is marked as processing
+	const unseen = "https://example.com/new"
+	if err := s.MarkSkipped(ctx, unseen, "job_deadline"); err != nil {
+		t.Fatal(err)
+	}
+	status, err := s.GetCrawlStatus(ctx, unseen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != "skipped" || status.FailReason != "job_deadline" {
+		t.Errorf("unseen URL: status %q, reason %q; want skipped, job_deadline", status.Status, status.FailReason)
+	}
+	page, err := s.GetPageData(ctx, unseen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Domain != "example.com" {
+		t.Errorf("unseen URL: domain %q, want example.com", page.Domain)
+	}
+
+	const failed = "https://example.com/failed"
+	if err := s.SaveData(ctx, &domain.PageData{URL: failed, Status: "failed", FailReason: "timeout"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MarkSkipped(ctx, failed, "job_deadline"); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := s.GetCrawlStatus(ctx, failed); err != nil || status.Status != "skipped" || status.FailReason != "job_deadline" {
+		t.Errorf("failed URL: got %+v, %v; want skipped, job_deadline", status, err)
+	}
+
+	const completed = "https://example.com/done"
+	if err := s.SaveData(ctx, &domain.PageData{URL: completed, Status: "completed", Title: "Done", Content: "text"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MarkSkipped(ctx, completed, "recently_crawled"); err != nil {
+		t.Fatal(err)
+	}
+	page, err = s.GetPageData(ctx, completed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Status != "completed" || page.FailReason != "" || page.Content != "text" {
+		t.Errorf("completed URL: status %q, reason %q, content %q; want it left alone", page.Status, page.FailReason, page.Content)
+	}
+}
+
+func TestFileStoreDevices(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	const page = "https://example.com/"
+	desktop, phone := PageKey(page, ""), PageKey(page, "iphone")
+	if err := s.SaveData(ctx, &domain.PageData{URL: page, Status: "completed", Title: "Desktop"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveData(ctx, &domain.PageData{URL: page, Device: "iphone", Status: "completed", Title: "Phone"}); err != nil {
+		t.Fatal(err)
+	}
+	for key, title := range map[string]string{desktop: "Desktop", phone: "Phone"} {
+		data, err := s.GetPageData(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.Title != title {
+			t.Errorf("%q: title %q, want %q", key, data.Title, title)
+		}
+	}
+	status, err := s.GetCrawlStatus(ctx, phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.URL != page || status.Device != "iphone" {
+		t.Errorf("phone status: URL %q, device %q", status.URL, status.Device)
+	}
+
+	if err := s.MarkSkipped(ctx, PageKey(page, "ipad"), "recently_crawled"); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := s.DomainURLs(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Errorf("got pages %q, want one per device", keys)
+	}
+
+	pages, err := s.DeleteDomain(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices := make(map[string]bool)
+	for _, p := range pages {
+		if p.URL != page {
+			t.Errorf("deleted URL %q, want %q", p.URL, page)
+		}
+		devices[p.Device] = true
+	}
+	if len(devices) != 3 || !devices[""] || !devices["iphone"] || !devices["ipad"] {
+		t.Errorf("deleted devices %v, want each record once", devices)
+	}
+}
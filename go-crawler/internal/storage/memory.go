@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process StateStore for local development and tests.
+// Nothing is persisted, and state is not shared between instances, so it
+// must only be used with a single crawler process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	crawled map[string]time.Time // URL -> expiry
+	retries map[string]memoryCounter
+	queue   map[string]memoryTask // URL -> retry
+	planned map[string]memoryTask // URL -> crawl scheduled for later
+	running map[string]domain.URLTask
+	claims  map[string]memoryClaim
+	slots   map[string]time.Time // Domain -> last reserved request slot
+	paused  bool
+}
+
+// memoryTask is a task waiting in a delayed queue until it becomes due.
+type memoryTask struct {
+	task  domain.URLTask
+	dueAt time.Time
+}
+
+type memoryClaim struct {
+	resp    []byte
+	expires time.Time
+}
+
+type memoryCounter struct {
+	count   int64
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		crawled: make(map[string]time.Time),
+		retries: make(map[string]memoryCounter),
+		queue:   make(map[string]memoryTask),
+		planned: make(map[string]memoryTask),
+		running: make(map[string]domain.URLTask),
+		claims:  make(map[string]memoryClaim),
+		slots:   make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// MarkAsCrawled records a URL as crawled until the TTL elapses.
+func (s *MemoryStore) MarkAsCrawled(ctx context.Context, url string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crawled[url] = time.Now().Add(ttl)
+	return nil
+}
+
+// IsRecentlyCrawled checks if a URL has been crawled within the TTL.
+func (s *MemoryStore) IsRecentlyCrawled(ctx context.Context, url string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expires, ok := s.crawled[url]
+	if ok && time.Now().After(expires) {
+		delete(s.crawled, url)
+		return false, nil
+	}
+	return ok, nil
+}
+
+// UnmarkCrawled removes the crawled markers of the given URLs.
+func (s *MemoryStore) UnmarkCrawled(ctx context.Context, urls []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, url := range urls {
+		delete(s.crawled, url)
+	}
+	return nil
+}
+
+// IncrementRetryCount increments the retry counter for a URL. Like the Redis
+// counter, it resets after 24 hours.
+func (s *MemoryStore) IncrementRetryCount(ctx context.Context, url string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	counter := s.retries[url]
+	if now.After(counter.expires) {
+		counter.count = 0
+	}
+	counter.count++
+	counter.expires = now.Add(24 * time.Hour)
+	s.retries[url] = counter
+	return counter.count, nil
+}
+
+// RetryInfo returns the retry count of a URL and when its next retry is
+// scheduled, or a zero time if none is.
+func (s *MemoryStore) RetryInfo(ctx context.Context, url string) (int64, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	if counter, ok := s.retries[url]; ok && time.Now().Before(counter.expires) {
+		count = counter.count
+	}
+	return count, s.queue[url].dueAt, nil
+}
+
+// ScheduleRetry adds a task to the delayed retry queue, to become eligible at the given time.
+func (s *MemoryStore) ScheduleRetry(ctx context.Context, task domain.URLTask, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue[taskKey(task)] = memoryTask{task: task, dueAt: at}
+	return nil
+}
+
+// PopDueRetries removes and returns up to limit tasks whose retry time has
+// passed, earliest first.
+func (s *MemoryStore) PopDueRetries(ctx context.Context, now time.Time, limit int64) ([]domain.URLTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return popDueTasks(s.queue, now, limit), nil
+}
+
+// RetryQueueStats returns the number of scheduled retries and the earliest scheduled time.
+func (s *MemoryStore) RetryQueueStats(ctx context.Context) (int64, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var oldest time.Time
+	for _, t := range s.queue {
+		if oldest.IsZero() || t.dueAt.Before(oldest) {
+			oldest = t.dueAt
+		}
+	}
+	return int64(len(s.queue)), oldest, nil
+}
+
+// ScheduledRetries returns every task in the delayed retry queue.
+func (s *MemoryStore) ScheduledRetries(ctx context.Context) ([]ScheduledURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return scheduledTasks(s.queue, true), nil
+}
+
+// ScheduleTask adds a task to the schedule queue, to be crawled once the
+// given time has passed.
+func (s *MemoryStore) ScheduleTask(ctx context.Context, task domain.URLTask, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.planned[taskKey(task)] = memoryTask{task: task, dueAt: at}
+	return nil
+}
+
+// PopDueTasks removes and returns up to limit scheduled tasks that have
+// become due, earliest first.
+func (s *MemoryStore) PopDueTasks(ctx context.Context, now time.Time, limit int64) ([]domain.URLTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return popDueTasks(s.planned, now, limit), nil
+}
+
+// TaskScheduledAt returns when the scheduled task of a URL becomes due, or a
+// zero time if none is scheduled.
+func (s *MemoryStore) TaskScheduledAt(ctx context.Context, url string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.planned[url].dueAt, nil
+}
+
+// ScheduledTasks returns every task in the schedule queue.
+func (s *MemoryStore) ScheduledTasks(ctx context.Context) ([]ScheduledURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return scheduledTasks(s.planned, false), nil
+}
+
+// SaveProcessingTask records the task of a URL whose crawl is starting.
+func (s *MemoryStore) SaveProcessingTask(ctx context.Context, task domain.URLTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running[taskKey(task)] = task
+	return nil
+}
+
+// ProcessingTask returns the task recorded for a URL being crawled, or a bare
+// task for the URL when there is none.
+func (s *MemoryStore) ProcessingTask(ctx context.Context, url string) (domain.URLTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task, ok := s.running[url]; ok {
+		return task, nil
+	}
+	return decodeTask(url, ""), nil
+}
+
+// DeleteProcessingTask forgets the task of a URL once its crawl has ended.
+func (s *MemoryStore) DeleteProcessingTask(ctx context.Context, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, url)
+	return nil
+}
+
+// popDueTasks removes and returns up to limit tasks of a delayed queue that
+// are due, earliest first.
+func popDueTasks(queue map[string]memoryTask, now time.Time, limit int64) []domain.URLTask {
+	var due []memoryTask
+	for _, t := range queue {
+		if !t.dueAt.After(now) {
+			due = append(due, t)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].dueAt.Before(due[j].dueAt) })
+	if int64(len(due)) > limit {
+		due = due[:limit]
+	}
+	tasks := make([]domain.URLTask, 0, len(due))
+	for _, t := range due {
+		delete(queue, taskKey(t.task))
+		tasks = append(tasks, t.task)
+	}
+	return tasks
+}
+
+// scheduledTasks returns every task of a delayed queue, marked as retries
+// when retry is set.
+func scheduledTasks(queue map[string]memoryTask, retry bool) []ScheduledURL {
+	scheduled := make([]ScheduledURL, 0, len(queue))
+	for url, t := range queue {
+		task := t.task
+		task.Retry = retry
+		scheduled = append(scheduled, ScheduledURL{URL: url, DueAt: t.dueAt, Task: task})
+	}
+	return scheduled
+}
+
+// ClaimIdempotencyKey reserves a submission key for the TTL, which saving the
+// response replaces. When the key is already taken it returns the response
+// stored for it, which is empty while the first submission is in progress.
+func (s *MemoryStore) ClaimIdempotencyKey(ctx context.Context, key string, ttl time.Duration) (bool, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if claim, ok := s.claims[key]; ok && now.Before(claim.expires) {
+		return false, claim.resp, nil
+	}
+	s.claims[key] = memoryClaim{expires: now.Add(ttl)}
+	return true, nil, nil
+}
+
+// SaveIdempotentResponse stores the response of a claimed submission key.
+func (s *MemoryStore) SaveIdempotentResponse(ctx context.Context, key string, resp []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claims[key] = memoryClaim{resp: resp, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// ReleaseIdempotencyKey gives up a claimed key without storing a response.
+func (s *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claims, key)
+	return nil
+}
+
+// ReserveDomainSlot reserves the next request slot of a domain, spaced delay
+// after the previous reservation, and returns its time. Slots are never
+// expired, as there is one per domain crawled.
+func (s *MemoryStore) ReserveDomainSlot(ctx context.Context, domain string, delay, ttl time.Duration) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.slots[domain].Add(delay)
+	if now := time.Now(); next.Before(now) {
+		next = now
+	}
+	s.slots[domain] = next
+	return next, nil
+}
+
+// SetPaused sets or clears the paused flag.
+func (s *MemoryStore) SetPaused(ctx context.Context, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+	return nil
+}
+
+// IsPaused reports whether crawling is paused.
+func (s *MemoryStore) IsPaused(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused, nil
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// migrationIdentifier matches the names the migrations give the tables,
+// indexes and function they create, and the URL constraint of the pages
+// table. Identifiers that merely contain one, such as the column
+// crawled_pages_id, don't match.
+var migrationIdentifier = regexp.MustCompile(`\b(crawled_pages_url_key|crawled_pages|page_content|page_metadata|page_images|page_headers|queue_snapshot|trigger_set_timestamp|idx_\w+)\b`)
+
+// RenderMigration rewrites a migration written against the default table
+// names for an optional schema and table prefix, the POSTGRES_SCHEMA and
+// TABLE_PREFIX the store is opened with. Tables and the timestamp function
+// are qualified with the schema and prefixed, as are indexes, whose names
+// must be unique within the schema. The schema must already exist.
+func RenderMigration(sql, schema, prefix string) (string, error) {
+	tables, err := newTableNames(schema, prefix)
+	if err != nil {
+		return "", err
+	}
+	names := map[string]string{
+		"crawled_pages":         tables.pages,
+		"page_content":          tables.content,
+		"page_metadata":         tables.metadata,
+		"page_images":           tables.images,
+		"page_headers":          tables.headers,
+		"queue_snapshot":        tables.snapshot,
+		"trigger_set_timestamp": qualifiedName(schema, prefix+"trigger_set_timestamp"),
+	}
+	return migrationIdentifier.ReplaceAllStringFunc(sql, func(ident string) string {
+		if name, ok := names[ident]; ok {
+			return name
+		}
+		// Index and constraint names can't be qualified; they live in their
+		// table's schema
+		return pgx.Identifier{prefix + ident}.Sanitize()
+	}), nil
+}
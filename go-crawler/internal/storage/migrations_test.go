@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRenderMigrationPrefixed(t *testing.T) {
+	files, err := filepath.Glob("../../migrations/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	// Every table, index and function a migration creates must be renamed
+	created := regexp.MustCompile(`(?i)CREATE\s+(?:OR\s+REPLACE\s+)?(?:TABLE|INDEX|FUNCTION)\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)`)
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range created.FindAllStringSubmatch(string(raw), -1) {
+			if !migrationIdentifier.MatchString(m[1]) {
+				t.Errorf("%s creates %s, which RenderMigration doesn't rename", filepath.Base(file), m[1])
+			}
+		}
+
+		rendered, err := RenderMigration(string(raw), "crawler", "acme_")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The prefixed names contain the defaults, but not as whole words
+		if left := migrationIdentifier.FindAllString(rendered, -1); len(left) > 0 {
+			t.Errorf("%s still refers to %v", filepath.Base(file), left)
+		}
+	}
+
+	raw, err := os.ReadFile("../../migrations/007_add_domain.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := RenderMigration(string(raw), "crawler", "acme_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`ALTER TABLE "crawler"."acme_crawled_pages" ADD COLUMN IF NOT EXISTS domain TEXT;`,
+		`CREATE INDEX IF NOT EXISTS "acme_idx_crawled_pages_domain_id" ON "crawler"."acme_crawled_pages" (domain, id);`,
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("rendered 007 lacks %s:\n%s", want, rendered)
+		}
+	}
+
+	// Postgres names the URL constraint after the prefixed table
+	raw, err = os.ReadFile("../../migrations/032_key_pages_by_device.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err = RenderMigration(string(raw), "crawler", "acme_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `ALTER TABLE "crawler"."acme_crawled_pages" DROP CONSTRAINT IF EXISTS "acme_crawled_pages_url_key";`; !strings.Contains(rendered, want) {
+		t.Errorf("rendered 032 lacks %s:\n%s", want, rendered)
+	}
+}
+
+func TestRenderMigrationDefault(t *testing.T) {
+	rendered, err := RenderMigration("CREATE INDEX IF NOT EXISTS idx_crawled_pages_updated_at ON crawled_pages (updated_at);", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `CREATE INDEX IF NOT EXISTS "idx_crawled_pages_updated_at" ON "crawled_pages" (updated_at);`; rendered != want {
+		t.Errorf("got %s, want %s", rendered, want)
+	}
+}
+
+func TestRenderMigrationInvalidNames(t *testing.T) {
+	for _, tt := range []struct{ schema, prefix string }{
+		{"crawler; DROP TABLE x", ""},
+		{"", "acme-"},
+	} {
+		if _, err := RenderMigration("SELECT 1;", tt.schema, tt.prefix); err == nil {
+			t.Errorf("schema %q and prefix %q were accepted", tt.schema, tt.prefix)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"time"
+)
+
+// PageStore holds the crawled pages and their extracted data, along with the
+// queue snapshots that outlive the state store. PostgresStore is the
+// production implementation; FileStore keeps pages as JSON files for simple
+// local use. Lookups of a missing URL return an error reading "not_found".
+// Each device preset crawls a page of its own, so pages are identified by
+// their PageKey: the parameters named url take one, and the listings of URLs
+// return them.
+type PageStore interface {
+	Ping(ctx context.Context) error
+	SaveData(ctx context.Context, data *domain.PageData) error
+	GetCrawlStatus(ctx context.Context, url string) (*domain.CrawlStatusResponse, error)
+	RecordFailReason(ctx context.Context, url, reason, screenshot string) error
+	MarkSkipped(ctx context.Context, url, reason string) error
+	MarkUnchanged(ctx context.Context, url string) error
+	GetPageData(ctx context.Context, url string) (*domain.PageData, error)
+	GetDOMHash(ctx context.Context, url string) (string, error)
+	GetRawHTML(ctx context.Context, url string) (string, error)
+	URLsBelowSchemaVersion(ctx context.Context, version, limit int) ([]string, error)
+	DeleteDomain(ctx context.Context, domainName string) ([]DeletedPage, error)
+	DeleteExpired(ctx context.Context, before time.Time, limit int) ([]DeletedPage, error)
+	StaleProcessing(ctx context.Context, before time.Time, limit int) ([]string, error)
+	ResetStaleProcessing(ctx context.Context, urls []string, before time.Time) error
+	CanonicalURL(ctx context.Context, contentHash, url string) (string, error)
+	DuplicateGroups(ctx context.Context, domainName string, limit int) ([]domain.DuplicateGroup, error)
+	DomainSummaries(ctx context.Context) ([]domain.DomainSummary, error)
+	CountDomainPages(ctx context.Context, domainName string) (int, error)
+	DomainURLs(ctx context.Context, domainName string) ([]string, error)
+	SaveQueueSnapshot(ctx context.Context, instance string, entries []ScheduledURL, at time.Time) error
+	QueueSnapshot(ctx context.Context) ([]ScheduledURL, error)
+	DeleteQueueSnapshots(ctx context.Context, before time.Time) error
+	ExportDomain(ctx context.Context, domainName string, since time.Time, fn func(*domain.PageData) error) error
+}
+
+// DeletedPage is a page removed by DeleteDomain or DeleteExpired, with the
+// failure screenshot that was kept for it outside the store, if any.
+type DeletedPage struct {
+	URL            string
+	Device         string
+	FailScreenshot string
+}
+
+var (
+	_ PageStore = (*PostgresStore)(nil)
+	_ PageStore = (*FileStore)(nil)
+)
@@ -1,91 +1,644 @@
-package storage
-
-import (
-	"context"
-	"crawler/internal/domain"
-	"fmt"
-
-	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
-)
-
-// PostgresStore handles interactions with the PostgreSQL database.
-type PostgresStore struct {
-	db *pgxpool.Pool
-}
-
-func NewPostgresStore(connStr string) (*PostgresStore, error) {
-	db, err := pgxpool.New(context.Background(), connStr)
-	if err != nil {
-		return nil, fmt.Errorf("unable to connect to database: %w", err)
-	}
-	return &PostgresStore{db: db}, nil
-}
-
-func (s *PostgresStore) Ping(ctx context.Context) error {
-	return s.db.Ping(ctx)
-}
-
-// SaveData saves extracted page data within a single transaction.
-func (s *PostgresStore) SaveData(ctx context.Context, data *domain.PageData) error {
-	tx, err := s.db.Begin(ctx)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback(ctx)
-
-	var pageID int
-	err = tx.QueryRow(ctx,
-		`INSERT INTO crawled_pages (url, title, status, fail_reason)
-		 VALUES ($1, $2, $3, $4)
-		 ON CONFLICT (url) DO UPDATE SET
-		   title = EXCLUDED.title, status = EXCLUDED.status, fail_reason = EXCLUDED.fail_reason, updated_at = NOW()
-		 RETURNING id`,
-		data.URL, data.Title, data.Status, data.FailReason,
-	).Scan(&pageID)
-	if err != nil {
-		return err
-	}
-
-	// Insert content
-	if data.Content != "" {
-		_, err = tx.Exec(ctx,
-			`INSERT INTO page_content (page_id, content) VALUES ($1, $2)
-			 ON CONFLICT (page_id) DO UPDATE SET content = EXCLUDED.content`,
-			pageID, data.Content)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Batch insert metadata
-	if len(data.MetaTags) > 0 {
-		batch := &pgx.Batch{}
-		for key, value := range data.MetaTags {
-			batch.Queue(`INSERT INTO page_metadata (page_id, meta_key, meta_value) VALUES ($1, $2, $3)
-			             ON CONFLICT (page_id, meta_key) DO UPDATE SET meta_value = EXCLUDED.meta_value`,
-				pageID, key, value)
-		}
-		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
-			return err
-		}
-	}
-
-	// Similar batch inserts for images and headers...
-
-	return tx.Commit(ctx)
-}
-
-// GetCrawlStatus retrieves the current status of a URL.
-func (s *PostgresStore) GetCrawlStatus(ctx context.Context, url string) (*domain.CrawlStatusResponse, error) {
-	var status domain.CrawlStatusResponse
-	err := s.db.QueryRow(ctx,
-		`SELECT url, status, fail_reason, updated_at FROM crawled_pages WHERE url = $1`,
-		url,
-	).Scan(&status.URL, &status.Status, &status.FailReason, &status.UpdatedAt)
-
-	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("not_found")
-	}
-	return &status, err
-}
+package storage
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore handles interactions with the PostgreSQL database.
+type PostgresStore struct {
+	db     *pgxpool.Pool
+	tables tableNames
+}
+
+// tableNames holds the quoted, schema-qualified names of the tables we use.
+type tableNames struct {
+	pages    string
+	content  string
+	metadata string
+	images   string
+	headers  string
+	snapshot string
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// newTableNames builds table names from an optional schema and table prefix.
+// Both are validated against a strict identifier pattern before use in SQL.
+func newTableNames(schema, prefix string) (tableNames, error) {
+	if schema != "" && !identifierPattern.MatchString(schema) {
+		return tableNames{}, fmt.Errorf("invalid schema name %q", schema)
+	}
+	if prefix != "" && !identifierPattern.MatchString(prefix) {
+		return tableNames{}, fmt.Errorf("invalid table prefix %q", prefix)
+	}
+
+	name := func(table string) string { return qualifiedName(schema, prefix+table) }
+	return tableNames{
+		pages:    name("crawled_pages"),
+		content:  name("page_content"),
+		metadata: name("page_metadata"),
+		images:   name("page_images"),
+		headers:  name("page_headers"),
+		snapshot: name("queue_snapshot"),
+	}, nil
+}
+
+// qualifiedName quotes name, qualified with schema unless it is empty.
+func qualifiedName(schema, name string) string {
+	if schema == "" {
+		return pgx.Identifier{name}.Sanitize()
+	}
+	return pgx.Identifier{schema, name}.Sanitize()
+}
+
+func NewPostgresStore(connStr, schema, tablePrefix string) (*PostgresStore, error) {
+	tables, err := newTableNames(schema, tablePrefix)
+	if err != nil {
+		return nil, err
+	}
+	db, err := pgxpool.New(context.Background(), connStr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to database: %w", err)
+	}
+	return &PostgresStore{db: db, tables: tables}, nil
+}
+
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+// SaveData saves extracted page data within a single transaction.
+func (s *PostgresStore) SaveData(ctx context.Context, data *domain.PageData) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if data.Domain == "" {
+		data.Domain = hostOf(data.URL)
+	}
+
+	// A page whose content others duplicate hands it to them before changing
+	// it, becoming a duplicate itself or landing on a gate
+	var oldID int
+	var oldHash string
+	err = tx.QueryRow(ctx,
+		`SELECT id, COALESCE(content_hash, '') FROM `+s.tables.pages+` WHERE url = $1 AND device = $2 AND duplicate_of IS NULL`,
+		data.URL, data.Device,
+	).Scan(&oldID, &oldHash)
+	if err != nil && err != pgx.ErrNoRows {
+		return err
+	}
+	if oldHash != "" && (data.DuplicateOf != "" || data.Gated || data.ContentHash != "" && data.ContentHash != oldHash) {
+		if err := s.promoteDuplicates(ctx, tx, []int{oldID}); err != nil {
+			return err
+		}
+	}
+
+	var pageID int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO `+s.tables.pages+` AS cp (url, domain, title, status, fail_reason, request_count, bytes_transferred, emails, phones, keywords, published_at, modified_at, schema_version, dom_hash, consent_handled, emulation, hreflang, content_hash, custom_fields, scroll_iterations, fail_screenshot, feeds, extraction_source, duplicate_of, microdata, cookies, javascript_disabled, device, gated, gate_type, links, structured_data, etag, last_modified)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, ''), $15, $16, $17, NULLIF($18, ''), $19, $20, NULLIF($21, ''), $22, NULLIF($23, ''), NULLIF($24, ''), $25, $26, $27, $28, $29, NULLIF($30, ''), $31, $32, NULLIF($33, ''), NULLIF($34, ''))
+		 ON CONFLICT (url, device) DO UPDATE SET
+		   domain = EXCLUDED.domain, title = EXCLUDED.title, status = EXCLUDED.status, fail_reason = EXCLUDED.fail_reason, fail_screenshot = EXCLUDED.fail_screenshot,
+		   request_count = EXCLUDED.request_count, bytes_transferred = EXCLUDED.bytes_transferred,
+		   emails = EXCLUDED.emails, phones = EXCLUDED.phones, keywords = EXCLUDED.keywords,
+		   published_at = EXCLUDED.published_at, modified_at = EXCLUDED.modified_at,
+		   schema_version = COALESCE(NULLIF(EXCLUDED.schema_version, 0), cp.schema_version),
+		   dom_hash = COALESCE(EXCLUDED.dom_hash, cp.dom_hash), consent_handled = EXCLUDED.consent_handled,
+		   emulation = EXCLUDED.emulation, hreflang = EXCLUDED.hreflang, feeds = EXCLUDED.feeds, custom_fields = EXCLUDED.custom_fields,
+		   scroll_iterations = EXCLUDED.scroll_iterations, extraction_source = EXCLUDED.extraction_source,
+		   duplicate_of = EXCLUDED.duplicate_of, microdata = EXCLUDED.microdata, cookies = EXCLUDED.cookies, javascript_disabled = EXCLUDED.javascript_disabled, gated = EXCLUDED.gated, gate_type = EXCLUDED.gate_type, links = EXCLUDED.links, structured_data = EXCLUDED.structured_data, etag = EXCLUDED.etag, last_modified = EXCLUDED.last_modified, content_hash = CASE WHEN EXCLUDED.gated THEN NULL ELSE COALESCE(EXCLUDED.content_hash, cp.content_hash) END, updated_at = NOW()
+		 RETURNING id`,
+		data.URL, data.Domain, data.Title, data.Status, data.FailReason, data.RequestCount, data.BytesTransferred, data.Emails, data.Phones, data.Keywords,
+		data.PublishedAt, data.ModifiedAt, data.SchemaVersion, data.DOMHash, data.ConsentHandled, data.Emulation, data.Hreflang, data.ContentHash, data.CustomFields, data.ScrollIterations, data.FailScreenshot, data.Feeds, data.ExtractionSource, data.DuplicateOf, data.Microdata, data.Cookies, data.JavaScriptDisabled, data.Device, data.Gated, data.GateType, data.Links, data.StructuredData, data.ETag, data.LastModified,
+	).Scan(&pageID)
+	if err != nil {
+		return err
+	}
+
+	// Duplicates point to the page holding their content instead, and gated
+	// pages only showed the gate, so earlier content would be stale
+	if data.DuplicateOf != "" || data.Gated {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+s.tables.content+` WHERE page_id = $1`, pageID); err != nil {
+			return err
+		}
+	}
+
+	// Insert content, keeping any previously archived HTML when none is given
+	if data.Content != "" || data.Markdown != "" || data.RawHTML != "" {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO `+s.tables.content+` AS pc (page_id, content, markdown, raw_html) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+			 ON CONFLICT (page_id) DO UPDATE SET
+			   content = EXCLUDED.content, markdown = EXCLUDED.markdown, raw_html = COALESCE(EXCLUDED.raw_html, pc.raw_html)`,
+			pageID, data.Content, data.Markdown, data.RawHTML)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Batch insert metadata
+	if len(data.MetaTags) > 0 {
+		batch := &pgx.Batch{}
+		for key, value := range data.MetaTags {
+			batch.Queue(`INSERT INTO `+s.tables.metadata+` (page_id, meta_key, meta_value) VALUES ($1, $2, $3)
+			             ON CONFLICT (page_id, meta_key) DO UPDATE SET meta_value = EXCLUDED.meta_value`,
+				pageID, key, value)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return err
+		}
+	}
+
+	// Similar batch inserts for images and headers...
+
+	return tx.Commit(ctx)
+}
+
+// GetCrawlStatus retrieves the current status of a URL.
+func (s *PostgresStore) GetCrawlStatus(ctx context.Context, url string) (*domain.CrawlStatusResponse, error) {
+	url, device := SplitPageKey(url)
+	var status domain.CrawlStatusResponse
+	err := s.db.QueryRow(ctx,
+		`SELECT url, device, status, fail_reason, COALESCE(fail_screenshot, ''), updated_at, request_count, bytes_transferred, schema_version FROM `+s.tables.pages+` WHERE url = $1 AND device = $2`,
+		url, device,
+	).Scan(&status.URL, &status.Device, &status.Status, &status.FailReason, &status.FailScreenshot, &status.UpdatedAt, &status.RequestCount, &status.BytesTransferred, &status.SchemaVersion)
+
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("not_found")
+	}
+	return &status, err
+}
+
+// RecordFailReason stores the error and screenshot, if any, of a failed
+// attempt that will be retried, without changing the page's status.
+func (s *PostgresStore) RecordFailReason(ctx context.Context, url, reason, screenshot string) error {
+	url, device := SplitPageKey(url)
+	_, err := s.db.Exec(ctx, `UPDATE `+s.tables.pages+` SET fail_reason = $3, fail_screenshot = NULLIF($4, '') WHERE url = $1 AND device = $2`, url, device, reason, screenshot)
+	return err
+}
+
+// MarkSkipped records why a URL was skipped, storing it if it isn't stored
+// yet. A completed page keeps its status and data.
+func (s *PostgresStore) MarkSkipped(ctx context.Context, url, reason string) error {
+	url, device := SplitPageKey(url)
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO `+s.tables.pages+` AS cp (url, device, domain, status, fail_reason) VALUES ($1, $2, $3, 'skipped', $4)
+		 ON CONFLICT (url, device) DO UPDATE SET status = 'skipped', fail_reason = EXCLUDED.fail_reason, updated_at = NOW()
+		 WHERE cp.status <> 'completed'`,
+		url, device, hostOf(url), reason)
+	return err
+}
+
+// MarkUnchanged records that a completed page was found unchanged, updating
+// its crawl time and keeping its data.
+func (s *PostgresStore) MarkUnchanged(ctx context.Context, url string) error {
+	url, device := SplitPageKey(url)
+	_, err := s.db.Exec(ctx,
+		`UPDATE `+s.tables.pages+` SET updated_at = NOW() WHERE url = $1 AND device = $2 AND status = 'completed'`,
+		url, device)
+	return err
+}
+
+// GetPageData retrieves the stored data of a URL.
+func (s *PostgresStore) GetPageData(ctx context.Context, url string) (*domain.PageData, error) {
+	url, device := SplitPageKey(url)
+	var data domain.PageData
+	err := s.db.QueryRow(ctx,
+		`SELECT `+s.pageDataColumns()+`
+		 FROM `+s.tables.pages+` cp
+		 LEFT JOIN `+s.tables.content+` pc ON pc.page_id = cp.id
+		 WHERE cp.url = $1 AND cp.device = $2`,
+		url, device,
+	).Scan(pageDataFields(&data)...)
+
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("not_found")
+	}
+	return &data, err
+}
+
+// GetDOMHash retrieves the stored DOM structure hash of a URL, or an empty
+// string when the URL has none.
+func (s *PostgresStore) GetDOMHash(ctx context.Context, url string) (string, error) {
+	url, device := SplitPageKey(url)
+	var domHash string
+	err := s.db.QueryRow(ctx,
+		`SELECT COALESCE(dom_hash, '') FROM `+s.tables.pages+` WHERE url = $1 AND device = $2`,
+		url, device,
+	).Scan(&domHash)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return domHash, err
+}
+
+// GetRawHTML retrieves the archived HTML of a previously crawled URL.
+func (s *PostgresStore) GetRawHTML(ctx context.Context, url string) (string, error) {
+	url, device := SplitPageKey(url)
+	var rawHTML *string
+	err := s.db.QueryRow(ctx,
+		`SELECT pc.raw_html FROM `+s.tables.content+` pc
+		 JOIN `+s.tables.pages+` cp ON cp.id = pc.page_id
+		 WHERE cp.url = $1 AND cp.device = $2`,
+		url, device,
+	).Scan(&rawHTML)
+
+	if err == pgx.ErrNoRows || (err == nil && rawHTML == nil) {
+		return "", fmt.Errorf("not_found")
+	}
+	if err != nil {
+		return "", err
+	}
+	return *rawHTML, nil
+}
+
+// URLsBelowSchemaVersion returns up to limit URLs with archived HTML whose
+// records were extracted with a schema older than version.
+func (s *PostgresStore) URLsBelowSchemaVersion(ctx context.Context, version, limit int) ([]string, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT cp.url, cp.device FROM `+s.tables.pages+` cp
+		 JOIN `+s.tables.content+` pc ON pc.page_id = cp.id
+		 WHERE cp.schema_version < $1 AND pc.raw_html IS NOT NULL
+		 ORDER BY cp.id
+		 LIMIT $2`,
+		version, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectPageKeys(rows)
+}
+
+// DeleteDomain deletes all pages of a domain, and through cascades their
+// content, metadata, images and headers, returning the deleted pages.
+func (s *PostgresStore) DeleteDomain(ctx context.Context, domainName string) ([]DeletedPage, error) {
+	return s.deletePages(ctx,
+		`SELECT id FROM `+s.tables.pages+` WHERE domain = $1 FOR UPDATE`,
+		domainName)
+}
+
+// DeleteExpired deletes up to limit pages last updated before the cutoff,
+// returning them. Pages still being crawled are left alone.
+func (s *PostgresStore) DeleteExpired(ctx context.Context, before time.Time, limit int) ([]DeletedPage, error) {
+	return s.deletePages(ctx,
+		`SELECT id FROM `+s.tables.pages+`
+		 WHERE updated_at < $1 AND status <> 'processing'
+		 ORDER BY id
+		 LIMIT $2
+		 FOR UPDATE`,
+		before, limit)
+}
+
+// deletePages deletes the pages whose IDs query selects, returning them.
+// Pages duplicating a deleted one are given its content first.
+func (s *PostgresStore) deletePages(ctx context.Context, query string, args ...any) ([]DeletedPage, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, err
+	}
+	if err := s.promoteDuplicates(ctx, tx, ids); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, `DELETE FROM `+s.tables.pages+` WHERE id = ANY($1) RETURNING url, device, COALESCE(fail_screenshot, '')`, ids)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := pgx.CollectRows(rows, pgx.RowToStructByPos[DeletedPage])
+	if err != nil {
+		return nil, err
+	}
+	return pages, tx.Commit(ctx)
+}
+
+// promoteDuplicates gives the content of each page of ids that others
+// duplicate to the earliest of those others not in ids, and points the rest
+// to it, so duplicates keep their content when the page's changes or goes.
+func (s *PostgresStore) promoteDuplicates(ctx context.Context, tx pgx.Tx, ids []int) error {
+	type heir struct {
+		canonicalID  int
+		canonicalURL string
+		device       string
+		id           int
+		url          string
+	}
+	rows, err := tx.Query(ctx,
+		`SELECT DISTINCT ON (c.id) c.id, c.url, c.device, d.id, d.url
+		 FROM `+s.tables.pages+` c
+		 JOIN `+s.tables.pages+` d ON d.duplicate_of = c.url AND d.device = c.device
+		 WHERE c.id = ANY($1) AND NOT d.id = ANY($1)
+		 ORDER BY c.id, d.id`,
+		ids)
+	if err != nil {
+		return err
+	}
+	var heirs []heir
+	for rows.Next() {
+		var h heir
+		if err := rows.Scan(&h.canonicalID, &h.canonicalURL, &h.device, &h.id, &h.url); err != nil {
+			rows.Close()
+			return err
+		}
+		heirs = append(heirs, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, h := range heirs {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO `+s.tables.content+` AS pc (page_id, content, markdown, raw_html)
+			 SELECT $1, content, markdown, raw_html FROM `+s.tables.content+` WHERE page_id = $2
+			 ON CONFLICT (page_id) DO UPDATE SET
+			   content = EXCLUDED.content, markdown = EXCLUDED.markdown, raw_html = COALESCE(EXCLUDED.raw_html, pc.raw_html)`,
+			h.id, h.canonicalID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE `+s.tables.pages+` SET duplicate_of = NULLIF($1, url) WHERE duplicate_of = $2 AND device = $3`,
+			h.url, h.canonicalURL, h.device)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StaleProcessing returns up to limit URLs that have been "processing" since
+// before, as left by a crashed worker.
+func (s *PostgresStore) StaleProcessing(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT url, device FROM `+s.tables.pages+`
+		 WHERE status = 'processing' AND updated_at < $1
+		 ORDER BY id
+		 LIMIT $2`,
+		before, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectPageKeys(rows)
+}
+
+// ResetStaleProcessing marks the given pages as skipped for having been
+// interrupted, unless a crawl has marked them as processing again, or
+// finished them, since before.
+func (s *PostgresStore) ResetStaleProcessing(ctx context.Context, urls []string, before time.Time) error {
+	pages, devices := make([]string, len(urls)), make([]string, len(urls))
+	for i, key := range urls {
+		pages[i], devices[i] = SplitPageKey(key)
+	}
+	_, err := s.db.Exec(ctx,
+		`UPDATE `+s.tables.pages+` SET status = 'skipped', fail_reason = 'interrupted', updated_at = NOW()
+		 WHERE (url, device) IN (SELECT * FROM unnest($1::text[], $2::text[])) AND status = 'processing' AND updated_at < $3`,
+		pages, devices, before,
+	)
+	return err
+}
+
+// CanonicalURL returns the URL of the earliest stored page with the given
+// content hash that isn't itself a duplicate, other than url.
+func (s *PostgresStore) CanonicalURL(ctx context.Context, contentHash, url string) (string, error) {
+	url, device := SplitPageKey(url)
+	var canonical string
+	err := s.db.QueryRow(ctx,
+		`SELECT url FROM `+s.tables.pages+`
+		 WHERE content_hash = $1 AND url <> $2 AND device = $3 AND status = 'completed' AND duplicate_of IS NULL
+		 ORDER BY id
+		 LIMIT 1`,
+		contentHash, url, device,
+	).Scan(&canonical)
+	if err == pgx.ErrNoRows {
+		return "", fmt.Errorf("not_found")
+	}
+	return canonical, err
+}
+
+// DuplicateGroups returns up to limit canonical pages with the URLs found to
+// duplicate them, optionally of a single domain, largest groups first.
+func (s *PostgresStore) DuplicateGroups(ctx context.Context, domainName string, limit int) ([]domain.DuplicateGroup, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT duplicate_of, array_agg(url ORDER BY url)
+		 FROM `+s.tables.pages+`
+		 WHERE duplicate_of IS NOT NULL AND ($1 = '' OR domain = $1)
+		 GROUP BY duplicate_of
+		 ORDER BY COUNT(*) DESC, duplicate_of
+		 LIMIT $2`,
+		domainName, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[domain.DuplicateGroup])
+}
+
+// DomainSummaries returns page counts and the last crawl time of every domain,
+// derived from the pages table so the crawl path needs no extra writes.
+// Domains that haven't been crawled the longest come first.
+func (s *PostgresStore) DomainSummaries(ctx context.Context) ([]domain.DomainSummary, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT domain, COUNT(*),
+		   COUNT(*) FILTER (WHERE status = 'completed'),
+		   COUNT(*) FILTER (WHERE status = 'failed'),
+		   COUNT(*) FILTER (WHERE status = 'processing'),
+		   COUNT(*) FILTER (WHERE status = 'skipped'),
+		   MAX(updated_at)
+		 FROM `+s.tables.pages+`
+		 WHERE domain IS NOT NULL
+		 GROUP BY domain
+		 ORDER BY MAX(updated_at), domain`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.DomainSummary, error) {
+		var d domain.DomainSummary
+		err := row.Scan(&d.Domain, &d.Pages, &d.Completed, &d.Failed, &d.Processing, &d.Skipped, &d.LastCrawledAt)
+		return d, err
+	})
+}
+
+// CountDomainPages returns the number of pages stored for a domain.
+func (s *PostgresStore) CountDomainPages(ctx context.Context, domainName string) (int, error) {
+	var count int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM `+s.tables.pages+` WHERE domain = $1`, domainName).Scan(&count)
+	return count, err
+}
+
+// DomainURLs returns the URLs of all pages stored for a domain.
+func (s *PostgresStore) DomainURLs(ctx context.Context, domainName string) ([]string, error) {
+	rows, err := s.db.Query(ctx, `SELECT url, device FROM `+s.tables.pages+` WHERE domain = $1 ORDER BY id`, domainName)
+	if err != nil {
+		return nil, err
+	}
+	return collectPageKeys(rows)
+}
+
+// SaveQueueSnapshot replaces the queue snapshot of an instance with the given
+// entries, which must have distinct URLs. Each instance only overwrites what
+// it snapshotted itself.
+func (s *PostgresStore) SaveQueueSnapshot(ctx context.Context, instance string, entries []ScheduledURL, at time.Time) error {
+	urls := make([]string, len(entries))
+	dueAt := make([]time.Time, len(entries))
+	tasks := make([]string, len(entries))
+	for i, e := range entries {
+		task, err := json.Marshal(e.Task)
+		if err != nil {
+			return err
+		}
+		urls[i], dueAt[i], tasks[i] = e.URL, e.DueAt, string(task)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM `+s.tables.snapshot+` WHERE instance = $1`, instance); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO `+s.tables.snapshot+` (instance, url, due_at, task, snapshot_at)
+		 SELECT $1, e.url, e.due_at, e.task::jsonb, $5
+		 FROM unnest($2::text[], $3::timestamptz[], $4::text[]) AS e(url, due_at, task)`,
+		instance, urls, dueAt, tasks, at,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// QueueSnapshot returns the snapshotted URLs of all instances, leaving out
+// those completed since their snapshot was taken.
+func (s *PostgresStore) QueueSnapshot(ctx context.Context) ([]ScheduledURL, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT DISTINCT ON (q.url) q.url, q.due_at, COALESCE(q.task::text, '')
+		 FROM `+s.tables.snapshot+` q
+		 LEFT JOIN `+s.tables.pages+` cp ON cp.url = split_part(q.url, ' ', 1) AND cp.device = split_part(q.url, ' ', 2)
+		 WHERE cp.status IS DISTINCT FROM 'completed' OR cp.updated_at < q.snapshot_at
+		 ORDER BY q.url, q.due_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ScheduledURL, error) {
+		var e ScheduledURL
+		var task string
+		err := row.Scan(&e.URL, &e.DueAt, &task)
+		e.Task = decodeTask(e.URL, task)
+		return e, err
+	})
+}
+
+// DeleteQueueSnapshots deletes the queue snapshots taken before the given
+// time, of all instances.
+func (s *PostgresStore) DeleteQueueSnapshots(ctx context.Context, before time.Time) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM `+s.tables.snapshot+` WHERE snapshot_at < $1`, before)
+	return err
+}
+
+// exportBatchSize is the number of rows fetched per keyset page during exports.
+const exportBatchSize = 500
+
+// ExportDomain streams all pages of a domain updated since the given time to fn,
+// in id order. Rows are fetched in keyset-paginated batches so the full result
+// set is never held in memory.
+func (s *PostgresStore) ExportDomain(ctx context.Context, domainName string, since time.Time, fn func(*domain.PageData) error) error {
+	lastID := 0
+	for {
+		rows, err := s.db.Query(ctx,
+			`SELECT cp.id, `+s.pageDataColumns()+`
+			 FROM `+s.tables.pages+` cp
+			 LEFT JOIN `+s.tables.content+` pc ON pc.page_id = cp.id
+			 WHERE cp.domain = $1 AND cp.updated_at >= $2 AND cp.id > $3
+			 ORDER BY cp.id
+			 LIMIT $4`,
+			domainName, since, lastID, exportBatchSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for rows.Next() {
+			var data domain.PageData
+			if err := rows.Scan(append([]any{&lastID}, pageDataFields(&data)...)...); err != nil {
+				rows.Close()
+				return err
+			}
+			if err := fn(&data); err != nil {
+				rows.Close()
+				return err
+			}
+			count++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if count < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+// pageDataColumns lists the columns scanned by pageDataFields, for queries
+// over crawled_pages aliased as cp joined with page_content aliased as pc.
+func (s *PostgresStore) pageDataColumns() string {
+	return `cp.url, COALESCE(cp.domain, ''), COALESCE(cp.title, ''), cp.status, COALESCE(cp.fail_reason, ''), COALESCE(cp.fail_screenshot, ''),
+		cp.updated_at, cp.request_count, cp.bytes_transferred, cp.emails, cp.phones, cp.keywords,
+		cp.published_at, cp.modified_at, cp.schema_version, COALESCE(cp.dom_hash, ''), cp.consent_handled, cp.cookies, cp.javascript_disabled, cp.emulation, cp.device, cp.gated, COALESCE(cp.gate_type, ''), cp.hreflang, cp.feeds, cp.links, cp.microdata, COALESCE(cp.content_hash, ''), COALESCE(cp.duplicate_of, ''), cp.custom_fields, cp.structured_data, cp.scroll_iterations, COALESCE(cp.extraction_source, ''), COALESCE(cp.etag, ''), COALESCE(cp.last_modified, ''), COALESCE(pc.content, ''), COALESCE(pc.markdown, ''),
+		(SELECT jsonb_object_agg(pm.meta_key, pm.meta_value) FROM ` + s.tables.metadata + ` pm WHERE pm.page_id = cp.id)`
+}
+
+func pageDataFields(data *domain.PageData) []any {
+	return []any{
+		&data.URL, &data.Domain, &data.Title, &data.Status, &data.FailReason, &data.FailScreenshot,
+		&data.CrawledAt, &data.RequestCount, &data.BytesTransferred, &data.Emails, &data.Phones, &data.Keywords,
+		&data.PublishedAt, &data.ModifiedAt, &data.SchemaVersion, &data.DOMHash, &data.ConsentHandled, &data.Cookies, &data.JavaScriptDisabled, &data.Emulation, &data.Device, &data.Gated, &data.GateType, &data.Hreflang, &data.Feeds, &data.Links, &data.Microdata, &data.ContentHash, &data.DuplicateOf, &data.CustomFields, &data.StructuredData, &data.ScrollIterations, &data.ExtractionSource, &data.ETag, &data.LastModified, &data.Content, &data.Markdown, &data.MetaTags,
+	}
+}
+
+// collectPageKeys returns the PageKeys of rows holding a URL and a device.
+func collectPageKeys(rows pgx.Rows) ([]string, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (string, error) {
+		var url, device string
+		err := row.Scan(&url, &device)
+		return PageKey(url, device), err
+	})
+}
+
+// hostOf returns the lower-cased host name of a URL.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -1,51 +1,333 @@
-package storage
-
-import (
-	"context"
-	"fmt"
-	"time"
-
-	"github.com/redis/go-redis/v9"
-)
-
-// RedisStore handles interactions with Redis for caching and queues.
-type RedisStore struct {
-	client *redis.Client
-}
-
-func NewRedisStore(addr string) *RedisStore {
-	rdb := redis.NewClient(&redis.Options{Addr: addr})
-	return &RedisStore{client: rdb}
-}
-
-func (s *RedisStore) Ping(ctx context.Context) error {
-	return s.client.Ping(ctx).Err()
-}
-
-// MarkAsCrawled sets a key with a TTL to prevent re-crawling.
-func (s *RedisStore) MarkAsCrawled(ctx context.Context, url string, ttl time.Duration) error {
-	key := fmt.Sprintf("crawled:%s", url)
-	return s.client.Set(ctx, key, "1", ttl).Err()
-}
-
-// IsRecentlyCrawled checks if a URL has been crawled within the TTL.
-func (s *RedisStore) IsRecentlyCrawled(ctx context.Context, url string) (bool, error) {
-	key := fmt.Sprintf("crawled:%s", url)
-	val, err := s.client.Exists(ctx, key).Result()
-	if err != nil {
-		return false, err
-	}
-	return val == 1, nil
-}
-
-// IncrementRetryCount increments the retry counter for a URL.
-func (s *RedisStore) IncrementRetryCount(ctx context.Context, url string) (int64, error) {
-	key := fmt.Sprintf("retry:%s", url)
-	count, err := s.client.Incr(ctx, key).Result()
-	if err != nil {
-		return 0, err
-	}
-	// Set an expiration on the retry key so it doesn't live forever
-	s.client.Expire(ctx, key, 24*time.Hour)
-	return count, nil
-}
+package storage
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore handles interactions with Redis for caching and queues.
+type RedisStore struct {
+	client    *redis.Client
+	keyPrefix string // Namespace applied to every key, e.g. "tenant:acme:"
+}
+
+func NewRedisStore(addr, keyPrefix string) *RedisStore {
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	return &RedisStore{client: rdb, keyPrefix: keyPrefix}
+}
+
+// key builds a namespaced Redis key. All keys must be built through it.
+func (s *RedisStore) key(format string, args ...any) string {
+	return s.keyPrefix + fmt.Sprintf(format, args...)
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return queueError(s.client.Ping(ctx).Err())
+}
+
+// queueError wraps connection failures in ErrQueueUnavailable. redis.Nil and
+// command errors are returned unchanged.
+func queueError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, redis.ErrClosed) || errors.Is(err, io.EOF) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrQueueUnavailable, err)
+	}
+	return err
+}
+
+// MarkAsCrawled sets a key with a TTL to prevent re-crawling.
+func (s *RedisStore) MarkAsCrawled(ctx context.Context, url string, ttl time.Duration) error {
+	key := s.key("crawled:%s", url)
+	return s.client.Set(ctx, key, "1", ttl).Err()
+}
+
+// IsRecentlyCrawled checks if a URL has been crawled within the TTL.
+func (s *RedisStore) IsRecentlyCrawled(ctx context.Context, url string) (bool, error) {
+	key := s.key("crawled:%s", url)
+	val, err := s.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return val == 1, nil
+}
+
+// UnmarkCrawled removes the crawled markers of the given URLs.
+func (s *RedisStore) UnmarkCrawled(ctx context.Context, urls []string) error {
+	const batchSize = 500
+	for start := 0; start < len(urls); start += batchSize {
+		end := min(start+batchSize, len(urls))
+		keys := make([]string, 0, end-start)
+		for _, url := range urls[start:end] {
+			keys = append(keys, s.key("crawled:%s", url))
+		}
+		if err := s.client.Del(ctx, keys...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IncrementRetryCount increments the retry counter for a URL.
+func (s *RedisStore) IncrementRetryCount(ctx context.Context, url string) (int64, error) {
+	key := s.key("retry:%s", url)
+	count, err := s.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// Set an expiration on the retry key so it doesn't live forever
+	s.client.Expire(ctx, key, 24*time.Hour)
+	return count, nil
+}
+
+// RetryInfo returns the retry count of a URL and when its next retry is
+// scheduled, or a zero time if none is.
+func (s *RedisStore) RetryInfo(ctx context.Context, url string) (int64, time.Time, error) {
+	count, err := s.client.Get(ctx, s.key("retry:%s", url)).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, time.Time{}, err
+	}
+	score, err := s.client.ZScore(ctx, s.key(retryQueueKey), url).Result()
+	if err == redis.Nil {
+		return count, time.Time{}, nil
+	}
+	if err != nil {
+		return count, time.Time{}, err
+	}
+	return count, time.Unix(int64(score), 0), nil
+}
+
+// The delayed queues are sorted sets of URLs scored by the time they become
+// due, each with a hash holding the task of every URL as JSON. Retries of
+// failed crawls wait in the retry queue; new crawls held back until a later
+// time, e.g. submitted with not_before, in the schedule queue.
+const (
+	retryQueueKey    = "retry_queue"
+	retryTasksKey    = "retry_tasks"
+	scheduleQueueKey = "schedule_queue"
+	scheduleTasksKey = "schedule_tasks"
+)
+
+// ScheduleRetry adds a task to the delayed retry queue, to become eligible at the given time.
+func (s *RedisStore) ScheduleRetry(ctx context.Context, task domain.URLTask, at time.Time) error {
+	return s.schedule(ctx, s.key(retryQueueKey), s.key(retryTasksKey), task, at)
+}
+
+// PopDueRetries removes and returns up to limit tasks whose retry time has passed.
+func (s *RedisStore) PopDueRetries(ctx context.Context, now time.Time, limit int64) ([]domain.URLTask, error) {
+	return s.popDue(ctx, s.key(retryQueueKey), s.key(retryTasksKey), now, limit)
+}
+
+// RetryQueueStats returns the number of scheduled retries and the earliest scheduled time.
+func (s *RedisStore) RetryQueueStats(ctx context.Context) (int64, time.Time, error) {
+	depth, err := s.client.ZCard(ctx, s.key(retryQueueKey)).Result()
+	if err != nil || depth == 0 {
+		return 0, time.Time{}, queueError(err)
+	}
+	oldest, err := s.client.ZRangeWithScores(ctx, s.key(retryQueueKey), 0, 0).Result()
+	if err != nil || len(oldest) == 0 {
+		return depth, time.Time{}, queueError(err)
+	}
+	return depth, time.Unix(int64(oldest[0].Score), 0), nil
+}
+
+// ScheduledRetries returns every task in the delayed retry queue.
+func (s *RedisStore) ScheduledRetries(ctx context.Context) ([]ScheduledURL, error) {
+	return s.scheduled(ctx, s.key(retryQueueKey), s.key(retryTasksKey), true)
+}
+
+// ScheduleTask adds a task to the schedule queue, to be crawled once the
+// given time has passed.
+func (s *RedisStore) ScheduleTask(ctx context.Context, task domain.URLTask, at time.Time) error {
+	return s.schedule(ctx, s.key(scheduleQueueKey), s.key(scheduleTasksKey), task, at)
+}
+
+// PopDueTasks removes and returns up to limit scheduled tasks that have
+// become due.
+func (s *RedisStore) PopDueTasks(ctx context.Context, now time.Time, limit int64) ([]domain.URLTask, error) {
+	return s.popDue(ctx, s.key(scheduleQueueKey), s.key(scheduleTasksKey), now, limit)
+}
+
+// TaskScheduledAt returns when the scheduled task of a URL becomes due, or a
+// zero time if none is scheduled.
+func (s *RedisStore) TaskScheduledAt(ctx context.Context, url string) (time.Time, error) {
+	score, err := s.client.ZScore(ctx, s.key(scheduleQueueKey), url).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, queueError(err)
+	}
+	return time.Unix(int64(score), 0), nil
+}
+
+// scheduled returns every task of a delayed queue, marked as retries when
+// retry is set.
+func (s *RedisStore) scheduled(ctx context.Context, queue, tasks string, retry bool) ([]ScheduledURL, error) {
+	members, err := s.client.ZRangeWithScores(ctx, queue, 0, -1).Result()
+	if err != nil || len(members) == 0 {
+		return nil, queueError(err)
+	}
+	urls := make([]string, len(members))
+	for i, m := range members {
+		urls[i], _ = m.Member.(string)
+	}
+	raw, err := s.client.HMGet(ctx, tasks, urls...).Result()
+	if err != nil {
+		return nil, queueError(err)
+	}
+	scheduled := make([]ScheduledURL, len(members))
+	for i, m := range members {
+		stored, _ := raw[i].(string)
+		task := decodeTask(urls[i], stored)
+		task.Retry = retry
+		scheduled[i] = ScheduledURL{URL: urls[i], DueAt: time.Unix(int64(m.Score), 0), Task: task}
+	}
+	return scheduled, nil
+}
+
+// processingTasksKey holds the task of every URL being crawled, as JSON, so
+// the crawl can be resumed with its options if its worker dies.
+const processingTasksKey = "processing_tasks"
+
+// SaveProcessingTask records the task of a URL whose crawl is starting.
+func (s *RedisStore) SaveProcessingTask(ctx context.Context, task domain.URLTask) error {
+	raw, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return queueError(s.client.HSet(ctx, s.key(processingTasksKey), taskKey(task), raw).Err())
+}
+
+// ProcessingTask returns the task recorded for a URL being crawled, or a bare
+// task for the URL when there is none.
+func (s *RedisStore) ProcessingTask(ctx context.Context, url string) (domain.URLTask, error) {
+	raw, err := s.client.HGet(ctx, s.key(processingTasksKey), url).Result()
+	if err != nil && err != redis.Nil {
+		return decodeTask(url, ""), queueError(err)
+	}
+	return decodeTask(url, raw), nil
+}
+
+// DeleteProcessingTask forgets the task of a URL once its crawl has ended.
+func (s *RedisStore) DeleteProcessingTask(ctx context.Context, url string) error {
+	return queueError(s.client.HDel(ctx, s.key(processingTasksKey), url).Err())
+}
+
+// ScheduledTasks returns every task in the schedule queue.
+func (s *RedisStore) ScheduledTasks(ctx context.Context) ([]ScheduledURL, error) {
+	return s.scheduled(ctx, s.key(scheduleQueueKey), s.key(scheduleTasksKey), false)
+}
+
+// schedule stores a task and adds its URL to a delayed queue, replacing any
+// task already queued for the URL.
+func (s *RedisStore) schedule(ctx context.Context, queue, tasks string, task domain.URLTask, at time.Time) error {
+	raw, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, tasks, taskKey(task), raw)
+		pipe.ZAdd(ctx, queue, redis.Z{Score: float64(at.Unix()), Member: taskKey(task)})
+		return nil
+	})
+	return queueError(err)
+}
+
+// popDueScript atomically removes up to ARGV[2] URLs scored at most ARGV[1]
+// from the queue KEYS[1], earliest first, along with their tasks in the hash
+// KEYS[2]. It returns each URL followed by its task, which is empty for URLs
+// queued without one. Being atomic, concurrent pollers never hand out the
+// same task twice.
+var popDueScript = redis.NewScript(`
+local urls = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local out = {}
+for _, url in ipairs(urls) do
+	redis.call('ZREM', KEYS[1], url)
+	out[#out + 1] = url
+	out[#out + 1] = redis.call('HGET', KEYS[2], url) or ''
+	redis.call('HDEL', KEYS[2], url)
+end
+return out
+`)
+
+func (s *RedisStore) popDue(ctx context.Context, queue, tasks string, now time.Time, limit int64) ([]domain.URLTask, error) {
+	pairs, err := popDueScript.Run(ctx, s.client, []string{queue, tasks}, now.Unix(), limit).StringSlice()
+	if err != nil {
+		return nil, queueError(err)
+	}
+	due := make([]domain.URLTask, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		due = append(due, decodeTask(pairs[i], pairs[i+1]))
+	}
+	return due, nil
+}
+
+// ClaimIdempotencyKey reserves a submission key for the TTL, which saving the
+// response replaces. When the key is already taken it returns the response
+// stored for it, which is empty while the first submission is in progress.
+func (s *RedisStore) ClaimIdempotencyKey(ctx context.Context, key string, ttl time.Duration) (bool, []byte, error) {
+	claimed, err := s.client.SetNX(ctx, s.key("idempotency:%s", key), "", ttl).Result()
+	if err != nil || claimed {
+		return claimed, nil, err
+	}
+	resp, err := s.client.Get(ctx, s.key("idempotency:%s", key)).Bytes()
+	if err == redis.Nil {
+		// Expired between the two calls; the caller's retry will claim it
+		return false, nil, nil
+	}
+	return false, resp, err
+}
+
+// SaveIdempotentResponse stores the response of a claimed submission key.
+func (s *RedisStore) SaveIdempotentResponse(ctx context.Context, key string, resp []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.key("idempotency:%s", key), resp, ttl).Err()
+}
+
+// ReleaseIdempotencyKey gives up a claimed key without storing a response.
+func (s *RedisStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.key("idempotency:%s", key)).Err()
+}
+
+// reserveSlotScript atomically moves a domain's next request time to at least
+// delay after the previous one, and no earlier than now. Times are in
+// milliseconds; the key outlives the reserved slot by ARGV[3].
+var reserveSlotScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local next_at = math.max(tonumber(redis.call('GET', KEYS[1]) or '0') + tonumber(ARGV[2]), now)
+redis.call('SET', KEYS[1], next_at, 'PX', next_at - now + tonumber(ARGV[3]))
+return next_at
+`)
+
+// ReserveDomainSlot reserves the next request slot of a domain, spaced delay
+// after the previous reservation by any instance, and returns its time. The
+// pacing state expires ttl after the reserved slot.
+func (s *RedisStore) ReserveDomainSlot(ctx context.Context, domain string, delay, ttl time.Duration) (time.Time, error) {
+	ms, err := reserveSlotScript.Run(ctx, s.client, []string{s.key("pace:%s", domain)},
+		time.Now().UnixMilli(), delay.Milliseconds(), max(ttl, time.Second).Milliseconds()).Int64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// SetPaused sets or clears the fleet-wide paused flag. It never expires.
+func (s *RedisStore) SetPaused(ctx context.Context, paused bool) error {
+	if !paused {
+		return s.client.Del(ctx, s.key("paused")).Err()
+	}
+	return s.client.Set(ctx, s.key("paused"), "1", 0).Err()
+}
+
+// IsPaused reports whether crawling is paused.
+func (s *RedisStore) IsPaused(ctx context.Context) (bool, error) {
+	n, err := s.client.Exists(ctx, s.key("paused")).Result()
+	return n == 1, err
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestQueueError(t *testing.T) {
+	commandErr := errors.New("ERR wrong number of arguments")
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{"nil", nil, false},
+		{"empty result", redis.Nil, false},
+		{"command error", commandErr, false},
+		{"closed client", redis.ErrClosed, true},
+		{"dropped connection", io.EOF, true},
+		{"refused connection", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := queueError(tt.err)
+			if errors.Is(got, ErrQueueUnavailable) != tt.unavailable {
+				t.Fatalf("queueError(%v) = %v, want unavailable %v", tt.err, got, tt.unavailable)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Fatalf("queueError(%v) = %v, which doesn't wrap the original error", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestRedisStoreClosedClient(t *testing.T) {
+	s := NewRedisStore("127.0.0.1:0", "test")
+	if err := s.client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	task := domain.URLTask{URL: "https://example.com/"}
+	now := time.Now()
+
+	ops := map[string]func() error{
+		"Ping":                 func() error { return s.Ping(ctx) },
+		"ScheduleRetry":        func() error { return s.ScheduleRetry(ctx, task, now) },
+		"PopDueRetries":        func() error { _, err := s.PopDueRetries(ctx, now, 10); return err },
+		"RetryQueueStats":      func() error { _, _, err := s.RetryQueueStats(ctx); return err },
+		"ScheduledRetries":     func() error { _, err := s.ScheduledRetries(ctx); return err },
+		"ScheduleTask":         func() error { return s.ScheduleTask(ctx, task, now) },
+		"PopDueTasks":          func() error { _, err := s.PopDueTasks(ctx, now, 10); return err },
+		"ScheduledTasks":       func() error { _, err := s.ScheduledTasks(ctx); return err },
+		"TaskScheduledAt":      func() error { _, err := s.TaskScheduledAt(ctx, task.URL); return err },
+		"SaveProcessingTask":   func() error { return s.SaveProcessingTask(ctx, task) },
+		"ProcessingTask":       func() error { _, err := s.ProcessingTask(ctx, task.URL); return err },
+		"DeleteProcessingTask": func() error { return s.DeleteProcessingTask(ctx, task.URL) },
+	}
+	for name, op := range ops {
+		t.Run(name, func(t *testing.T) {
+			if err := op(); !errors.Is(err, ErrQueueUnavailable) {
+				t.Fatalf("got %v, want ErrQueueUnavailable", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"crawler/internal/domain"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrQueueUnavailable wraps errors of delayed retry queue operations that
+// failed because the backend couldn't be reached, as opposed to a query error
+// or an empty queue, so callers can back off until it is back.
+var ErrQueueUnavailable = errors.New("queue backend unavailable")
+
+// StateStore holds the crawler's short-lived state: recently crawled URLs,
+// retry counters, the delayed retry and schedule queues, the tasks being
+// crawled, submission idempotency keys, per-domain request pacing and whether
+// crawling is paused.
+// Both delayed queues keep the whole task of each URL, so it runs with the
+// options it was submitted with.
+// Pages are tracked by their PageKey, as each device preset crawls a page of
+// its own; the parameters named url take one.
+// RedisStore is the production implementation; MemoryStore lets the crawler
+// run without Redis.
+type StateStore interface {
+	Ping(ctx context.Context) error
+	MarkAsCrawled(ctx context.Context, url string, ttl time.Duration) error
+	IsRecentlyCrawled(ctx context.Context, url string) (bool, error)
+	UnmarkCrawled(ctx context.Context, urls []string) error
+	IncrementRetryCount(ctx context.Context, url string) (int64, error)
+	RetryInfo(ctx context.Context, url string) (int64, time.Time, error)
+	ScheduleRetry(ctx context.Context, task domain.URLTask, at time.Time) error
+	PopDueRetries(ctx context.Context, now time.Time, limit int64) ([]domain.URLTask, error)
+	RetryQueueStats(ctx context.Context) (int64, time.Time, error)
+	ScheduledRetries(ctx context.Context) ([]ScheduledURL, error)
+	ScheduleTask(ctx context.Context, task domain.URLTask, at time.Time) error
+	PopDueTasks(ctx context.Context, now time.Time, limit int64) ([]domain.URLTask, error)
+	ScheduledTasks(ctx context.Context) ([]ScheduledURL, error)
+	TaskScheduledAt(ctx context.Context, url string) (time.Time, error)
+	SaveProcessingTask(ctx context.Context, task domain.URLTask) error
+	ProcessingTask(ctx context.Context, url string) (domain.URLTask, error)
+	DeleteProcessingTask(ctx context.Context, url string) error
+	ClaimIdempotencyKey(ctx context.Context, key string, ttl time.Duration) (bool, []byte, error)
+	SaveIdempotentResponse(ctx context.Context, key string, resp []byte, ttl time.Duration) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	ReserveDomainSlot(ctx context.Context, domain string, delay, ttl time.Duration) (time.Time, error)
+	SetPaused(ctx context.Context, paused bool) error
+	IsPaused(ctx context.Context) (bool, error)
+}
+
+// ScheduledURL is a URL waiting in a delayed queue, or to be put back on
+// one, with the time it becomes due and its task. URL holds the PageKey of
+// the task's page. Retries have Task.Retry set.
+type ScheduledURL struct {
+	URL   string
+	DueAt time.Time
+	Task  domain.URLTask
+}
+
+// PageKey identifies the page of url crawled with a device preset: the URL
+// itself without one, or the URL and the device separated by a space, which
+// normalized URLs don't contain.
+func PageKey(url, device string) string {
+	if device == "" {
+		return url
+	}
+	return url + " " + device
+}
+
+// SplitPageKey returns the URL and device preset of a PageKey.
+func SplitPageKey(key string) (url, device string) {
+	url, device, _ = strings.Cut(key, " ")
+	return url, device
+}
+
+// taskKey returns the PageKey of a task's page.
+func taskKey(task domain.URLTask) string {
+	return PageKey(task.URL, task.Device)
+}
+
+// decodeTask returns the task stored for a page as JSON, or a bare task for
+// the page when there is none, e.g. for URLs queued by an older version.
+func decodeTask(key, raw string) domain.URLTask {
+	var task domain.URLTask
+	if raw == "" || json.Unmarshal([]byte(raw), &task) != nil {
+		task = domain.URLTask{}
+	}
+	task.URL, task.Device = SplitPageKey(key)
+	return task
+}
+
+var (
+	_ StateStore = (*RedisStore)(nil)
+	_ StateStore = (*MemoryStore)(nil)
+)
@@ -0,0 +1,3 @@
+ALTER TABLE crawled_pages
+    ADD COLUMN IF NOT EXISTS request_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS bytes_transferred BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE crawled_pages
+    ADD COLUMN IF NOT EXISTS emails JSONB,
+    ADD COLUMN IF NOT EXISTS phones JSONB;
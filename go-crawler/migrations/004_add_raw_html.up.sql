@@ -0,0 +1 @@
+ALTER TABLE page_content ADD COLUMN IF NOT EXISTS raw_html TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE crawled_pages
+    ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS modified_at TIMESTAMPTZ;
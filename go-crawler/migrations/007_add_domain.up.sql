@@ -0,0 +1,7 @@
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS domain TEXT;
+
+UPDATE crawled_pages
+SET domain = lower(substring(url FROM '^[A-Za-z][A-Za-z0-9+.-]*://(?:[^@/]*@)?([^/:?#]+)'))
+WHERE domain IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_crawled_pages_domain_id ON crawled_pages (domain, id);
@@ -0,0 +1,3 @@
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS schema_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_crawled_pages_schema_version ON crawled_pages (schema_version);
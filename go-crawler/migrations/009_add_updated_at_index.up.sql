@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_crawled_pages_updated_at ON crawled_pages (updated_at);
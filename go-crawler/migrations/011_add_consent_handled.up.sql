@@ -0,0 +1 @@
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS consent_handled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS hreflang JSONB;
@@ -0,0 +1 @@
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS scroll_iterations INT NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- Periodic copies of queued and scheduled URLs per crawler instance, replayed
+-- with POST /api/queue/restore after the queue backend loses its state
+CREATE TABLE IF NOT EXISTS queue_snapshot (
+    instance TEXT NOT NULL,
+    url TEXT NOT NULL,
+    due_at TIMESTAMPTZ NOT NULL,
+    snapshot_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (instance, url)
+);
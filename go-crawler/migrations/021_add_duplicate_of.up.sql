@@ -0,0 +1,3 @@
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS duplicate_of TEXT;
+CREATE INDEX IF NOT EXISTS idx_crawled_pages_content_hash ON crawled_pages (content_hash);
+CREATE INDEX IF NOT EXISTS idx_crawled_pages_duplicate_of ON crawled_pages (duplicate_of) WHERE duplicate_of IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS javascript_disabled BOOLEAN NOT NULL DEFAULT FALSE;
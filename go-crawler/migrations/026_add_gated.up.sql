@@ -0,0 +1,2 @@
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS gated BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS gate_type TEXT;
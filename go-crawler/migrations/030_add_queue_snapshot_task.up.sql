@@ -0,0 +1 @@
+ALTER TABLE queue_snapshot ADD COLUMN IF NOT EXISTS task JSONB;
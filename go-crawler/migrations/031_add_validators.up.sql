@@ -0,0 +1,2 @@
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS etag TEXT;
+ALTER TABLE crawled_pages ADD COLUMN IF NOT EXISTS last_modified TEXT;
@@ -0,0 +1,4 @@
+UPDATE crawled_pages SET device = '' WHERE device IS NULL;
+ALTER TABLE crawled_pages ALTER COLUMN device SET DEFAULT '', ALTER COLUMN device SET NOT NULL;
+ALTER TABLE crawled_pages DROP CONSTRAINT IF EXISTS crawled_pages_url_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_crawled_pages_url_device ON crawled_pages (url, device);
@@ -0,0 +1,89 @@
+package extract
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d\s().-]{6,}\d`)
+	// Ranges such as "2019-2024" match the phone pattern but are almost always years.
+	yearRangePattern = regexp.MustCompile(`^\d{4}\s*-\s*\d{4}$`)
+)
+
+// extractContacts collects email addresses and phone numbers from mailto:/tel:
+// links and from the page's text content. Results are normalized and deduplicated.
+func extractContacts(doc *goquery.Document, content string) (emails, phones []string) {
+	emailSet := make(map[string]bool)
+	phoneSet := make(map[string]bool)
+
+	addEmail := func(e string) {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if emailPattern.FindString(e) != e || emailSet[e] {
+			return
+		}
+		emailSet[e] = true
+		emails = append(emails, e)
+	}
+	addPhone := func(p string) {
+		p = normalizePhone(p)
+		if p == "" || phoneSet[p] {
+			return
+		}
+		phoneSet[p] = true
+		phones = append(phones, p)
+	}
+
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		scheme, rest, ok := strings.Cut(strings.TrimSpace(href), ":")
+		if !ok {
+			return
+		}
+		rest, _, _ = strings.Cut(rest, "?") // drop ?subject=... etc.
+		rest, _ = url.PathUnescape(rest)
+		switch strings.ToLower(scheme) {
+		case "mailto":
+			for _, addr := range strings.Split(rest, ",") {
+				addEmail(addr)
+			}
+		case "tel":
+			addPhone(rest)
+		}
+	})
+
+	for _, e := range emailPattern.FindAllString(content, -1) {
+		addEmail(e)
+	}
+	for _, p := range phonePattern.FindAllString(content, -1) {
+		if !yearRangePattern.MatchString(p) {
+			addPhone(p)
+		}
+	}
+	return emails, phones
+}
+
+// normalizePhone strips formatting, keeping a leading '+' for international
+// numbers. It returns "" when the digit count isn't plausible for a phone number.
+func normalizePhone(p string) string {
+	p = strings.TrimSpace(p)
+	var b strings.Builder
+	if strings.HasPrefix(p, "+") {
+		b.WriteByte('+')
+	}
+	digits := 0
+	for _, r := range p {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			digits++
+		}
+	}
+	if digits < 7 || digits > 15 {
+		return ""
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package extract
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// dateLayouts are tried in order when parsing article dates. Layouts without
+// a zone are interpreted as UTC.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+}
+
+// parseDate parses a date string in any of the supported layouts.
+func parseDate(value string) (*time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t, true
+		}
+	}
+	return nil, false
+}
+
+// extractArticleDates finds an article's publish and modified dates from
+// meta tags, JSON-LD and <time> elements, in that order of preference.
+// Dates that are absent or unparseable are left nil.
+func extractArticleDates(doc *goquery.Document, metaTags map[string]string) (published, modified *time.Time) {
+	setFirst := func(dst **time.Time, values ...string) {
+		for _, v := range values {
+			if *dst != nil {
+				return
+			}
+			if t, ok := parseDate(v); ok {
+				*dst = t
+			}
+		}
+	}
+
+	setFirst(&published, metaTags["article:published_time"], metaTags["og:published_time"], metaTags["date"])
+	setFirst(&modified, metaTags["article:modified_time"], metaTags["og:updated_time"])
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var ld interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &ld); err != nil {
+			return
+		}
+		setFirst(&published, findJSONLDString(ld, "datePublished"))
+		setFirst(&modified, findJSONLDString(ld, "dateModified"))
+	})
+
+	doc.Find("time[datetime]").Each(func(i int, s *goquery.Selection) {
+		datetime, _ := s.Attr("datetime")
+		if itemprop, _ := s.Attr("itemprop"); itemprop == "dateModified" {
+			setFirst(&modified, datetime)
+			return
+		}
+		setFirst(&published, datetime)
+	})
+
+	return published, modified
+}
+
+// findJSONLDString returns the first string value for key found anywhere in a
+// decoded JSON-LD document, including inside arrays and @graph containers.
+func findJSONLDString(node interface{}, key string) string {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if v, ok := n[key].(string); ok {
+			return v
+		}
+		for _, child := range n {
+			if v := findJSONLDString(child, key); v != "" {
+				return v
+			}
+		}
+	case []interface{}:
+		for _, child := range n {
+			if v := findJSONLDString(child, key); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
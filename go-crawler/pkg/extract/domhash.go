@@ -0,0 +1,33 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// domStructureHash hashes the element tree of a document, ignoring text,
+// comments and attributes, so it changes with the page template rather than
+// with its content.
+func domStructureHash(doc *goquery.Document) string {
+	h := sha256.New()
+	for _, n := range doc.Nodes {
+		writeStructure(h, n)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeStructure(h hash.Hash, n *html.Node) {
+	if n.Type == html.ElementNode {
+		h.Write([]byte("<" + n.Data + ">"))
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		writeStructure(h, child)
+	}
+	if n.Type == html.ElementNode {
+		h.Write([]byte("</" + n.Data + ">"))
+	}
+}
@@ -0,0 +1,331 @@
+// Package extract pulls titles, text, metadata and links out of HTML pages.
+// It is the crawler's extraction logic, usable on HTML from any source.
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// ExtractedData holds the information extracted from a page. Fields of
+// disabled extractors are left empty.
+type ExtractedData struct {
+	URL         string              `json:"url"`
+	Title       string              `json:"title"`
+	Content     string              `json:"content"`
+	Headers     []string            `json:"headers"` // H1, H2 and H3 text
+	MetaTags    map[string]string   `json:"meta_tags"`
+	Keywords    []string            `json:"keywords"`               // From <meta name="keywords">, split on commas
+	PublishedAt *time.Time          `json:"published_at,omitempty"` // Article dates, nil when absent or unparseable
+	ModifiedAt  *time.Time          `json:"modified_at,omitempty"`
+	Images      []string            `json:"images"`
+	Hreflang    map[string]string   `json:"hreflang,omitempty"`     // Language code -> absolute URL of the alternate
+	Feeds       []string            `json:"feeds,omitempty"`        // Absolute URLs of the RSS and Atom feeds announced
+	Links       map[string][]string `json:"links,omitempty"`        // <link> rel type -> absolute URLs, e.g. "canonical" or "next"
+	Microdata   []*MicrodataItem    `json:"microdata,omitempty"`    // Top-level itemscope items, e.g. schema.org products
+	DOMHash     string              `json:"dom_hash,omitempty"`     // Hash of the tag structure, ignoring text
+	ContentHash string              `json:"content_hash,omitempty"` // SHA-256 of Content, hex-encoded
+	Emails      []string            `json:"emails,omitempty"`
+	Phones      []string            `json:"phones,omitempty"`
+	Markdown    string              `json:"markdown,omitempty"` // The main content as markdown
+
+	// Values of Options.Fields: a string, or a list for multiple-match fields
+	CustomFields map[string]any `json:"custom_fields,omitempty"`
+
+	// The JSON-LD item of Options.SchemaType and its validation report
+	StructuredData *StructuredData `json:"structured_data,omitempty"`
+
+	// Set when the MaxNodes or MaxContentLength caps cut the page short
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Options toggles the individual extractors and caps the work done on
+// pathological pages.
+type Options struct {
+	MetaTags  bool // Meta tags and the keywords taken from them
+	Headers   bool
+	Images    bool
+	Content   bool // Body text, without scripts and styles
+	Dates     bool // Article publish and modified dates
+	Hreflang  bool
+	Feeds     bool // RSS and Atom feed links
+	Links     bool // Every <link rel> entry, capped at MaxNodes
+	Microdata bool // itemscope items, capped at MaxNodes
+	DOMHash   bool
+	Contacts  bool // Email addresses and phone numbers; privacy-sensitive, so opt-in
+	Markdown  bool // The main content as markdown; costlier than Content, so opt-in
+
+	// Custom fields by name, e.g. a price or SKU of a product page
+	Fields map[string]FieldRule
+
+	// schema.org type, e.g. "Product", whose JSON-LD item is extracted and
+	// checked for required properties; empty skips it
+	SchemaType string
+
+	// Caps that protect callers from pathological pages; 0 means unlimited
+	MaxNodes         int // Per element kind, e.g. headers or images
+	MaxContentLength int // In bytes
+}
+
+// DefaultOptions enables every extractor except contacts and markdown, without
+// caps.
+func DefaultOptions() Options {
+	return Options{
+		MetaTags:  true,
+		Headers:   true,
+		Images:    true,
+		Content:   true,
+		Dates:     true,
+		Hreflang:  true,
+		Feeds:     true,
+		Links:     true,
+		Microdata: true,
+		DOMHash:   true,
+	}
+}
+
+// Extractors are the names under which the extractors can be selected, e.g.
+// by a crawl request. "fields" stands for the custom fields.
+var Extractors = []string{
+	"meta_tags", "headers", "images", "content", "dates", "hreflang",
+	"feeds", "links", "microdata", "dom_hash", "contacts", "markdown", "fields",
+}
+
+// toggle returns the switch of the named extractor, or nil for an unknown name.
+func (o *Options) toggle(name string) *bool {
+	switch name {
+	case "meta_tags":
+		return &o.MetaTags
+	case "headers":
+		return &o.Headers
+	case "images":
+		return &o.Images
+	case "content":
+		return &o.Content
+	case "dates":
+		return &o.Dates
+	case "hreflang":
+		return &o.Hreflang
+	case "feeds":
+		return &o.Feeds
+	case "links":
+		return &o.Links
+	case "microdata":
+		return &o.Microdata
+	case "dom_hash":
+		return &o.DOMHash
+	case "contacts":
+		return &o.Contacts
+	case "markdown":
+		return &o.Markdown
+	}
+	return nil
+}
+
+// Only returns o with just the named extractors enabled, keeping its caps and,
+// if "fields" is named, its custom fields. The title is always extracted.
+func (o Options) Only(names []string) (Options, error) {
+	only := Options{MaxNodes: o.MaxNodes, MaxContentLength: o.MaxContentLength}
+	for _, name := range names {
+		if name == "fields" {
+			only.Fields = o.Fields
+			continue
+		}
+		toggle := only.toggle(name)
+		if toggle == nil {
+			return Options{}, fmt.Errorf("unknown extractor %q", name)
+		}
+		*toggle = true
+	}
+	return only, nil
+}
+
+// ValidateExtractors checks that every name is one of Extractors.
+func ValidateExtractors(names []string) error {
+	_, err := Options{}.Only(names)
+	return err
+}
+
+// Extract parses an HTML page with DefaultOptions. baseURL is the page's URL,
+// against which relative links are resolved.
+func Extract(baseURL string, htmlReader io.Reader) (*ExtractedData, error) {
+	return ExtractWithOptions(baseURL, htmlReader, DefaultOptions())
+}
+
+// ExtractWithOptions parses an HTML page, running only the extractors enabled
+// in opts.
+func ExtractWithOptions(pageURL string, htmlReader io.Reader, opts Options) (*ExtractedData, error) {
+	doc, err := goquery.NewDocumentFromReader(htmlReader)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &ExtractedData{
+		URL:   pageURL,
+		Title: doc.Find("title").First().Text(),
+	}
+
+	// Hash the structure before scripts and styles are stripped below
+	if opts.DOMHash {
+		data.DOMHash = domStructureHash(doc)
+	}
+
+	// Meta tags are also a source of article dates
+	metaTags := make(map[string]string)
+	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		property, _ := s.Attr("property")
+		content, _ := s.Attr("content")
+		key := name
+		if property != "" {
+			key = property
+		}
+		if key != "" && content != "" {
+			metaTags[key] = content
+		}
+	})
+	if opts.MetaTags {
+		data.MetaTags = metaTags
+		data.Keywords = splitKeywords(metaTags["keywords"])
+	}
+	if opts.Hreflang || opts.Feeds || opts.Links || opts.Microdata || opts.Markdown {
+		base := baseURL(doc, pageURL)
+		if opts.Hreflang {
+			data.Hreflang = extractHreflang(doc, base)
+		}
+		if opts.Feeds {
+			data.Feeds = extractFeeds(doc, base)
+		}
+		if opts.Links {
+			var truncated bool
+			data.Links, truncated = extractLinks(doc, base, opts.MaxNodes)
+			data.Truncated = data.Truncated || truncated
+		}
+		if opts.Microdata {
+			var truncated bool
+			data.Microdata, truncated = extractMicrodata(doc, base, opts.MaxNodes)
+			data.Truncated = data.Truncated || truncated
+		}
+		if opts.Markdown {
+			var truncated bool
+			data.Markdown, truncated = extractMarkdown(doc, base, opts.MaxContentLength)
+			data.Truncated = data.Truncated || truncated
+		}
+	}
+	if opts.Dates {
+		data.PublishedAt, data.ModifiedAt = extractArticleDates(doc, metaTags)
+	}
+
+	// Custom fields may select scripts, which are stripped below, as is
+	// the JSON-LD
+	if len(opts.Fields) > 0 {
+		data.CustomFields = extractFields(doc, opts.Fields)
+	}
+	if opts.SchemaType != "" {
+		data.StructuredData = extractStructuredData(doc, opts.SchemaType)
+	}
+
+	if opts.Headers {
+		data.Headers = []string{}
+		headers := doc.Find("h1, h2, h3")
+		if opts.MaxNodes > 0 && headers.Length() > opts.MaxNodes {
+			headers = headers.Slice(0, opts.MaxNodes)
+			data.Truncated = true
+		}
+		headers.Each(func(i int, s *goquery.Selection) {
+			data.Headers = append(data.Headers, s.Text())
+		})
+	}
+
+	if opts.Images {
+		data.Images = []string{}
+		images := doc.Find("img")
+		if opts.MaxNodes > 0 && images.Length() > opts.MaxNodes {
+			images = images.Slice(0, opts.MaxNodes)
+			data.Truncated = true
+		}
+		images.Each(func(i int, s *goquery.Selection) {
+			src, exists := s.Attr("src")
+			if exists && src != "" {
+				data.Images = append(data.Images, src)
+			}
+		})
+	}
+
+	// Contacts are also found in the body text
+	if opts.Content || opts.Contacts {
+		doc.Find("script, style").Each(func(i int, s *goquery.Selection) {
+			s.Remove()
+		})
+		content, truncated := boundedText(doc.Find("body"), opts.MaxContentLength)
+		content = strings.TrimSpace(content)
+		data.Truncated = data.Truncated || truncated
+
+		if opts.Content {
+			data.Content = content
+			sum := sha256.Sum256([]byte(content))
+			data.ContentHash = hex.EncodeToString(sum[:])
+		}
+		if opts.Contacts {
+			data.Emails, data.Phones = extractContacts(doc, content)
+		}
+	}
+
+	return data, nil
+}
+
+// splitKeywords splits a meta keywords value on commas, dropping empty entries.
+func splitKeywords(content string) []string {
+	keywords := []string{}
+	for _, kw := range strings.Split(content, ",") {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			keywords = append(keywords, kw)
+		}
+	}
+	return keywords
+}
+
+// boundedText returns the text of the selection like Selection.Text, but stops
+// once maxLen bytes have been collected, without building the full text
+// first. It reports whether the text was cut short. A maxLen of 0 is unlimited.
+func boundedText(sel *goquery.Selection, maxLen int) (string, bool) {
+	if maxLen <= 0 {
+		return sel.Text(), false
+	}
+
+	var sb strings.Builder
+	truncated := false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if truncated {
+			return
+		}
+		if n.Type == html.TextNode {
+			text := n.Data
+			if remaining := maxLen - sb.Len(); len(text) > remaining {
+				// Cut on a rune boundary
+				for remaining > 0 && !utf8.RuneStart(text[remaining]) {
+					remaining--
+				}
+				text = text[:remaining]
+				truncated = true
+			}
+			sb.WriteString(text)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for _, n := range sel.Nodes {
+		walk(n)
+	}
+	return sb.String(), truncated
+}
@@ -0,0 +1,97 @@
+package extract
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractKeywords(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want []string
+	}{
+		{"several", `<meta name="keywords" content="go, crawler ,chromedp">`, []string{"go", "crawler", "chromedp"}},
+		{"empty entries", `<meta name="keywords" content=", go,, crawler, ">`, []string{"go", "crawler"}},
+		{"single", `<meta name="keywords" content="go">`, []string{"go"}},
+		{"blank", `<meta name="keywords" content="  ">`, []string{}},
+		{"empty content", `<meta name="keywords" content="">`, []string{}},
+		{"missing", `<meta name="description" content="A page">`, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := "<html><head>" + tt.head + "</head><body></body></html>"
+			data, err := Extract("https://example.com/", strings.NewReader(page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.Keywords == nil || !slices.Equal(data.Keywords, tt.want) {
+				t.Errorf("keywords = %#v, want %#v", data.Keywords, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractCapsHugeDOM(t *testing.T) {
+	var page strings.Builder
+	page.WriteString("<html><body>")
+	for i := range 20000 {
+		fmt.Fprintf(&page, "<h1>Heading %d</h1><p>Paragraph %d with some text.</p><img src=\"/img/%d.png\">", i, i, i)
+	}
+	page.WriteString("</body></html>")
+
+	opts := DefaultOptions()
+	opts.MaxNodes = 100
+	opts.MaxContentLength = 4096
+	data, err := ExtractWithOptions("https://example.com/", strings.NewReader(page.String()), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.Truncated {
+		t.Error("huge page not flagged as truncated")
+	}
+	if len(data.Headers) != opts.MaxNodes {
+		t.Errorf("got %d headers, want the cap of %d", len(data.Headers), opts.MaxNodes)
+	}
+	if len(data.Images) != opts.MaxNodes {
+		t.Errorf("got %d images, want the cap of %d", len(data.Images), opts.MaxNodes)
+	}
+	if len(data.Content) == 0 || len(data.Content) > opts.MaxContentLength {
+		t.Errorf("got %d bytes of content, want up to the cap of %d", len(data.Content), opts.MaxContentLength)
+	}
+	if !strings.HasPrefix(data.Content, "Heading 0Paragraph 0") {
+		t.Errorf("content starts with %.40q, want the start of the page", data.Content)
+	}
+}
+
+func TestExtractUnderCaps(t *testing.T) {
+	page := "<html><body><h1>Title</h1><p>Body text.</p><img src=\"/a.png\"></body></html>"
+	opts := DefaultOptions()
+	opts.MaxNodes = 100
+	opts.MaxContentLength = 4096
+	data, err := ExtractWithOptions("https://example.com/", strings.NewReader(page), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Truncated {
+		t.Error("small page flagged as truncated")
+	}
+	if data.Content != "TitleBody text." || len(data.Headers) != 1 || len(data.Images) != 1 {
+		t.Errorf("got content %q, %d headers and %d images; want the whole page", data.Content, len(data.Headers), len(data.Images))
+	}
+}
+
+func TestBoundedTextRuneBoundary(t *testing.T) {
+	page := "<html><body><p>héllo wörld</p></body></html>"
+	opts := DefaultOptions()
+	opts.MaxContentLength = 2 // "h" and half of "é"
+	data, err := ExtractWithOptions("https://example.com/", strings.NewReader(page), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Content != "h" || !data.Truncated {
+		t.Errorf("content = %q, truncated %v; want \"h\", cut before the split rune", data.Content, data.Truncated)
+	}
+}
@@ -0,0 +1,46 @@
+package extract
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// feedTypes are the link types announcing an RSS or Atom feed.
+var feedTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+}
+
+// extractFeeds collects the absolute URLs of the feeds announced via
+// <link rel="alternate" type="application/rss+xml"> or its Atom equivalent,
+// in document order and without duplicates.
+func extractFeeds(doc *goquery.Document, base *url.URL) []string {
+	if base == nil {
+		return nil
+	}
+	var feeds []string
+	seen := make(map[string]bool)
+	doc.Find("link[type][href]").Each(func(i int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		if !strings.Contains(" "+strings.ToLower(rel)+" ", " alternate ") {
+			return
+		}
+		typ, _ := s.Attr("type")
+		if !feedTypes[strings.ToLower(strings.TrimSpace(typ))] {
+			return
+		}
+		href, _ := s.Attr("href")
+		href = strings.TrimSpace(href)
+		abs, err := base.Parse(href)
+		if href == "" || err != nil || (abs.Scheme != "http" && abs.Scheme != "https") {
+			return
+		}
+		if feed := abs.String(); !seen[feed] {
+			seen[feed] = true
+			feeds = append(feeds, feed)
+		}
+	})
+	return feeds
+}
@@ -0,0 +1,80 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// FieldRule extracts a named custom field with a CSS selector. In JSON a rule
+// is either a bare selector string, taking the text of the first match, or an
+// object such as {"selector": "img.product", "attr": "src", "multiple": true}.
+type FieldRule struct {
+	Selector string `json:"selector"`
+	Attr     string `json:"attr,omitempty"`     // Read this attribute instead of the text
+	Multiple bool   `json:"multiple,omitempty"` // Collect every match as a list
+}
+
+func (r *FieldRule) UnmarshalJSON(b []byte) error {
+	var selector string
+	if err := json.Unmarshal(b, &selector); err == nil {
+		*r = FieldRule{Selector: selector}
+		return nil
+	}
+	type plain FieldRule // Without this method, to avoid recursing
+	return json.Unmarshal(b, (*plain)(r))
+}
+
+// Validate checks that the rule has a selector that compiles.
+func (r FieldRule) Validate() error {
+	if strings.TrimSpace(r.Selector) == "" {
+		return errors.New("selector is empty")
+	}
+	if _, err := cascadia.ParseGroup(r.Selector); err != nil {
+		return fmt.Errorf("invalid selector %q: %w", r.Selector, err)
+	}
+	return nil
+}
+
+// extractFields applies custom field rules to a document. Single fields are
+// strings and multiple-match fields are lists of strings; fields without a
+// non-empty match are omitted.
+func extractFields(doc *goquery.Document, rules map[string]FieldRule) map[string]any {
+	var fields map[string]any
+	set := func(name string, value any) {
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		fields[name] = value
+	}
+
+	for name, rule := range rules {
+		matches := doc.Find(rule.Selector)
+		if !rule.Multiple {
+			matches = matches.First()
+		}
+		var values []string
+		matches.Each(func(i int, s *goquery.Selection) {
+			value := strings.TrimSpace(s.Text())
+			if rule.Attr != "" {
+				value, _ = s.Attr(rule.Attr)
+				value = strings.TrimSpace(value)
+			}
+			if value != "" {
+				values = append(values, value)
+			}
+		})
+		switch {
+		case len(values) == 0:
+		case rule.Multiple:
+			set(name, values)
+		default:
+			set(name, values[0])
+		}
+	}
+	return fields
+}
@@ -0,0 +1,50 @@
+package extract
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// baseURL returns the URL relative links of a page resolve against, taking
+// a <base href> into account.
+func baseURL(doc *goquery.Document, pageURL string) *url.URL {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = ref
+		}
+	}
+	return base
+}
+
+// extractHreflang collects <link rel="alternate" hreflang="..."> entries as a
+// map of lower-cased language code (or "x-default") to absolute URL.
+func extractHreflang(doc *goquery.Document, base *url.URL) map[string]string {
+	if base == nil {
+		return nil
+	}
+	var alternates map[string]string
+	doc.Find("link[hreflang][href]").Each(func(i int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		if !strings.Contains(" "+strings.ToLower(rel)+" ", " alternate ") {
+			return
+		}
+		lang, _ := s.Attr("hreflang")
+		href, _ := s.Attr("href")
+		lang, href = strings.ToLower(strings.TrimSpace(lang)), strings.TrimSpace(href)
+		abs, err := base.Parse(href)
+		if lang == "" || href == "" || err != nil || (abs.Scheme != "http" && abs.Scheme != "https") {
+			return
+		}
+		if alternates == nil {
+			alternates = make(map[string]string)
+		}
+		alternates[lang] = abs.String()
+	})
+	return alternates
+}
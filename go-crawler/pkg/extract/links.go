@@ -0,0 +1,49 @@
+package extract
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractLinks collects the <link rel="..." href="..."> entries of a page as a
+// map of lower-cased rel type, e.g. "canonical", "next" or "author", to the
+// absolute URLs linked with it, in document order and without duplicates. A
+// link with several rel types is listed under each. At most maxLinks links are
+// read when it is positive, reporting whether any were dropped.
+func extractLinks(doc *goquery.Document, base *url.URL, maxLinks int) (map[string][]string, bool) {
+	if base == nil {
+		return nil, false
+	}
+	var links map[string][]string
+	seen := make(map[string]bool)
+	read, truncated := 0, false
+	doc.Find("link[rel][href]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if maxLinks > 0 && read >= maxLinks {
+			truncated = true
+			return false
+		}
+		read++
+		rel, _ := s.Attr("rel")
+		href, _ := s.Attr("href")
+		href = strings.TrimSpace(href)
+		abs, err := base.Parse(href)
+		if href == "" || err != nil || (abs.Scheme != "http" && abs.Scheme != "https") {
+			return true
+		}
+		link := abs.String()
+		for _, typ := range strings.Fields(strings.ToLower(rel)) {
+			if seen[typ+" "+link] {
+				continue
+			}
+			seen[typ+" "+link] = true
+			if links == nil {
+				links = make(map[string][]string)
+			}
+			links[typ] = append(links[typ], link)
+		}
+		return true
+	})
+	return links, truncated
+}
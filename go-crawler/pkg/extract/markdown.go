@@ -0,0 +1,437 @@
+package extract
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// maxMarkdownDepth bounds how deeply nested elements are converted; deeper
+// content is kept as plain text.
+const maxMarkdownDepth = 32
+
+// markdownSkipped are the elements left out of the markdown entirely.
+var markdownSkipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+	"canvas": true, "iframe": true, "button": true, "input": true, "select": true,
+	"textarea": true, "head": true,
+}
+
+// markdownChrome are the page furniture elements left out when there is no
+// main or article element and the whole body is converted.
+var markdownChrome = map[string]bool{"nav": true, "aside": true, "footer": true}
+
+// markdownBlocks are the elements that start a block of their own.
+var markdownBlocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true,
+	"nav": true, "ol": true, "p": true, "pre": true, "section": true, "summary": true,
+	"table": true, "ul": true,
+}
+
+// markdownConverter renders HTML as markdown, stopping once maxLen bytes of
+// text have been written when it is positive.
+type markdownConverter struct {
+	base      *url.URL
+	maxLen    int
+	written   int
+	skip      map[string]bool
+	truncated bool
+}
+
+// extractMarkdown converts the page's main content to markdown: the first
+// main or article element, or else the body without its navigation, asides
+// and footers. Headings, lists, links, images, code blocks, quotes and tables
+// are kept; other markup is reduced to its text. The result is capped at
+// maxLen bytes when it is positive, reporting whether it was cut short.
+func extractMarkdown(doc *goquery.Document, base *url.URL, maxLen int) (string, bool) {
+	c := &markdownConverter{base: base, maxLen: maxLen, skip: markdownSkipped}
+	root := doc.Find("main, [role=main]").First()
+	if root.Length() == 0 {
+		root = doc.Find("article").First()
+	}
+	if root.Length() == 0 {
+		root = doc.Find("body")
+		c.skip = make(map[string]bool, len(markdownSkipped)+len(markdownChrome))
+		for tag := range markdownSkipped {
+			c.skip[tag] = true
+		}
+		for tag := range markdownChrome {
+			c.skip[tag] = true
+		}
+	}
+	if root.Length() == 0 {
+		return "", false
+	}
+
+	md := strings.TrimSpace(c.blocks(root.Get(0), "\n\n", 0))
+	if maxLen > 0 && len(md) > maxLen {
+		cut := maxLen
+		for cut > 0 && !utf8.RuneStart(md[cut]) {
+			cut--
+		}
+		md = strings.TrimSpace(md[:cut])
+		c.truncated = true
+	}
+	return md, c.truncated
+}
+
+// full reports whether the length cap has been reached.
+func (c *markdownConverter) full() bool {
+	return c.maxLen > 0 && c.written >= c.maxLen
+}
+
+// blocks renders the children of n, running inline content together into
+// paragraphs and joining the blocks with sep.
+func (c *markdownConverter) blocks(n *html.Node, sep string, depth int) string {
+	var out []string
+	var para strings.Builder
+	flush := func() {
+		if text := tidyInline(para.String()); text != "" {
+			out = append(out, text)
+		}
+		para.Reset()
+	}
+	for child := n.FirstChild; child != nil && !c.full(); child = child.NextSibling {
+		if child.Type == html.ElementNode && c.skip[child.Data] {
+			continue
+		}
+		if child.Type != html.ElementNode || !markdownBlocks[child.Data] {
+			c.inline(&para, child, depth)
+			continue
+		}
+		flush()
+		if block := c.block(child, depth+1); block != "" {
+			out = append(out, block)
+		}
+	}
+	flush()
+	return strings.Join(out, sep)
+}
+
+// block renders a block element.
+func (c *markdownConverter) block(n *html.Node, depth int) string {
+	if depth > maxMarkdownDepth {
+		var sb strings.Builder
+		c.write(&sb, c.text(n))
+		return tidyInline(sb.String())
+	}
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		var sb strings.Builder
+		c.inline(&sb, n, depth)
+		if text := tidyInline(sb.String()); text != "" {
+			level, _ := strconv.Atoi(n.Data[1:])
+			return strings.Repeat("#", level) + " " + strings.ReplaceAll(text, "\n", " ")
+		}
+		return ""
+	case "p", "dt", "summary", "figcaption":
+		var sb strings.Builder
+		c.inline(&sb, n, depth)
+		return tidyInline(sb.String())
+	case "hr":
+		return "---"
+	case "pre":
+		return c.codeBlock(n)
+	case "blockquote":
+		return prefixLines(c.blocks(n, "\n\n", depth), "> ", ">")
+	case "ul", "ol":
+		return c.list(n, depth)
+	case "table":
+		return c.table(n, depth)
+	}
+	return c.blocks(n, "\n\n", depth)
+}
+
+// list renders a list, indenting the continuation lines and nested lists of
+// each item under its marker.
+func (c *markdownConverter) list(n *html.Node, depth int) string {
+	ordered := n.Data == "ol"
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); ordered && err == nil {
+		number = start
+	}
+	var items []string
+	for li := n.FirstChild; li != nil && !c.full(); li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+		body := c.blocks(li, "\n", depth+1)
+		if body == "" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.TrimPrefix(prefixLines(body, indent, ""), indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+// codeBlock renders a pre element as a fenced code block, taking the
+// language from a "language-" or "lang-" class on it or its code element.
+func (c *markdownConverter) codeBlock(n *html.Node) string {
+	code := strings.Trim(c.text(n), "\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+	lang := codeLanguage(n)
+	if lang == "" {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && child.Data == "code" {
+				lang = codeLanguage(child)
+				break
+			}
+		}
+	}
+	c.written += len(code)
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if lang, ok := strings.CutPrefix(class, "language-"); ok {
+			return lang
+		}
+		if lang, ok := strings.CutPrefix(class, "lang-"); ok {
+			return lang
+		}
+	}
+	return ""
+}
+
+// table renders a table as a pipe table, taking its first row as the header.
+func (c *markdownConverter) table(n *html.Node, depth int) string {
+	var rows [][]string
+	columns := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			case "tr":
+				var row []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+						continue
+					}
+					var sb strings.Builder
+					c.inline(&sb, cell, depth)
+					text := strings.ReplaceAll(tidyInline(sb.String()), "\n", " ")
+					row = append(row, strings.ReplaceAll(text, "|", `\|`))
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+					columns = max(columns, len(row))
+				}
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inline renders n as inline markdown into sb. Block elements met along the
+// way, e.g. a div inside a link, are reduced to their inline content.
+func (c *markdownConverter) inline(sb *strings.Builder, n *html.Node, depth int) {
+	if c.full() {
+		return
+	}
+	switch n.Type {
+	case html.TextNode:
+		c.write(sb, collapseSpace(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if c.skip[n.Data] {
+		return
+	}
+	if depth > maxMarkdownDepth {
+		c.write(sb, collapseSpace(c.text(n)))
+		return
+	}
+
+	children := func() string {
+		var inner strings.Builder
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			c.inline(&inner, child, depth+1)
+		}
+		return inner.String()
+	}
+	switch n.Data {
+	case "br":
+		sb.WriteString("\n")
+	case "img":
+		if src := resolveURL(c.base, attr(n, "src")); src != "" && !strings.HasPrefix(src, "data:") {
+			c.write(sb, "!["+collapseSpace(attr(n, "alt"))+"]("+src+")")
+		}
+	case "a":
+		text := children()
+		href := resolveURL(c.base, attr(n, "href"))
+		if strings.TrimSpace(text) == "" || !linkable(href) {
+			sb.WriteString(text)
+			return
+		}
+		sb.WriteString("[" + strings.TrimSpace(text) + "](" + href + ")")
+	case "strong", "b":
+		sb.WriteString(wrapInline(children(), "**"))
+	case "em", "i":
+		sb.WriteString(wrapInline(children(), "_"))
+	case "del", "s", "strike":
+		sb.WriteString(wrapInline(children(), "~~"))
+	case "code", "kbd", "samp":
+		code := collapseSpace(c.text(n))
+		if strings.TrimSpace(code) == "" {
+			return
+		}
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		c.write(sb, fence+code+fence)
+	default:
+		inner := children()
+		if markdownBlocks[n.Data] {
+			inner = " " + inner + " "
+		}
+		sb.WriteString(inner)
+	}
+}
+
+// write appends text to sb, counting it against the length cap.
+func (c *markdownConverter) write(sb *strings.Builder, text string) {
+	sb.WriteString(text)
+	c.written += len(text)
+	if c.full() {
+		c.truncated = true
+	}
+}
+
+// text returns the raw text under n, leaving out skipped elements.
+func (c *markdownConverter) text(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode && c.skip[n.Data] {
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// linkable reports whether a link target is worth keeping in the markdown.
+func linkable(href string) bool {
+	scheme, _, ok := strings.Cut(href, ":")
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// wrapInline surrounds text with an emphasis marker, keeping the marker next
+// to the text as markdown requires.
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+// collapseSpace turns every run of whitespace into a single space, as a
+// browser renders it.
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// tidyInline trims each line of rendered inline content and collapses the
+// spaces left where adjacent elements met.
+func tidyInline(s string) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// prefixLines puts prefix before every line of s, or blank before empty ones.
+func prefixLines(s, prefix, blank string) string {
+	if s == "" {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = blank
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,133 @@
+package extract
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// maxMicrodataDepth bounds how deeply nested itemscopes are followed.
+const maxMicrodataDepth = 16
+
+// MicrodataItem is an item declared with itemscope, e.g. a schema.org Product.
+// Property values are strings, or *MicrodataItem for nested items; a property
+// can occur more than once, so each maps to a list.
+type MicrodataItem struct {
+	Type       []string         `json:"type,omitempty"` // From itemtype, e.g. "https://schema.org/Product"
+	ID         string           `json:"id,omitempty"`   // From itemid
+	Properties map[string][]any `json:"properties"`
+}
+
+// extractMicrodata parses the top-level microdata items of a document, those
+// with itemscope that aren't themselves a property of another item. At most
+// maxItems items are returned when it is positive, reporting whether any were
+// dropped. itemref is not supported.
+func extractMicrodata(doc *goquery.Document, base *url.URL, maxItems int) ([]*MicrodataItem, bool) {
+	var items []*MicrodataItem
+	truncated := false
+	doc.Find("[itemscope]").Not("[itemprop]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if maxItems > 0 && len(items) >= maxItems {
+			truncated = true
+			return false
+		}
+		items = append(items, parseMicrodataItem(s.Get(0), base, 0))
+		return true
+	})
+	return items, truncated
+}
+
+// parseMicrodataItem reads the item declared by an itemscope element.
+func parseMicrodataItem(n *html.Node, base *url.URL, depth int) *MicrodataItem {
+	item := &MicrodataItem{Properties: make(map[string][]any)}
+	if itemType := attr(n, "itemtype"); itemType != "" {
+		item.Type = strings.Fields(itemType)
+	}
+	item.ID = strings.TrimSpace(attr(n, "itemid"))
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			_, scoped := hasAttr(child, "itemscope")
+			names, isProp := hasAttr(child, "itemprop")
+			if scoped && !isProp {
+				continue // An unrelated top-level item
+			}
+			if isProp {
+				var value any
+				if scoped {
+					if depth >= maxMicrodataDepth {
+						continue
+					}
+					value = parseMicrodataItem(child, base, depth+1)
+				} else {
+					value = microdataValue(child, base)
+				}
+				for _, name := range strings.Fields(names) {
+					item.Properties[name] = append(item.Properties[name], value)
+				}
+				if scoped {
+					continue // Its descendants belong to the nested item
+				}
+			}
+			walk(child)
+		}
+	}
+	walk(n)
+	return item
+}
+
+// microdataValue returns the value of a property element, which depends on
+// its tag: an attribute for links, media and machine-readable values, and the
+// text otherwise. URLs are resolved against the page's base URL.
+func microdataValue(n *html.Node, base *url.URL) string {
+	switch n.Data {
+	case "meta":
+		return attr(n, "content")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return resolveURL(base, attr(n, "src"))
+	case "a", "area", "link":
+		return resolveURL(base, attr(n, "href"))
+	case "object":
+		return resolveURL(base, attr(n, "data"))
+	case "data", "meter":
+		return attr(n, "value")
+	case "time":
+		if datetime, ok := hasAttr(n, "datetime"); ok {
+			return datetime
+		}
+	}
+	return strings.TrimSpace(goquery.NewDocumentFromNode(n).Text())
+}
+
+// resolveURL makes a possibly relative URL absolute, returning it unchanged
+// if it can't be resolved.
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if base == nil || ref == "" {
+		return ref
+	}
+	abs, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return abs.String()
+}
+
+func hasAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attr(n *html.Node, key string) string {
+	val, _ := hasAttr(n, key)
+	return val
+}
@@ -0,0 +1,262 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxSchemaDepth bounds how deeply JSON-LD documents are searched and @id
+// references resolved.
+const maxSchemaDepth = 16
+
+// schemaRequired lists the properties an item of a schema.org type needs to
+// be usable, following what search engines require for rich results. Items
+// of other types are matched and normalized, but not checked.
+var schemaRequired = map[string][]string{
+	"Article":        {"headline", "author", "datePublished"},
+	"BreadcrumbList": {"itemListElement"},
+	"Event":          {"name", "startDate", "location"},
+	"FAQPage":        {"mainEntity"},
+	"JobPosting":     {"title", "description", "datePosted", "hiringOrganization"},
+	"LocalBusiness":  {"name", "address"},
+	"Organization":   {"name"},
+	"Person":         {"name"},
+	"Product":        {"name", "offers"},
+	"Recipe":         {"name", "image", "recipeIngredient", "recipeInstructions"},
+	"VideoObject":    {"name", "thumbnailUrl", "uploadDate"},
+}
+
+// schemaSubtypes lists common direct subtypes of schema.org types. A request
+// for a type also matches the subtypes of its subtypes, e.g. a Restaurant
+// (a FoodEstablishment, a LocalBusiness) for "Organization".
+var schemaSubtypes = map[string][]string{
+	"Article":            {"NewsArticle", "BlogPosting", "TechArticle", "ScholarlyArticle", "Report", "SocialMediaPosting"},
+	"FoodEstablishment":  {"Restaurant", "Bakery", "BarOrPub", "CafeOrCoffeeShop", "FastFoodRestaurant"},
+	"LocalBusiness":      {"FoodEstablishment", "Store", "LodgingBusiness", "MedicalBusiness"},
+	"LodgingBusiness":    {"Hotel", "Motel", "Hostel", "BedAndBreakfast"},
+	"NewsArticle":        {"AnalysisNewsArticle", "OpinionNewsArticle", "ReportageNewsArticle"},
+	"Organization":       {"Corporation", "NGO", "LocalBusiness", "EducationalOrganization"},
+	"Product":            {"ProductGroup", "IndividualProduct", "ProductModel"},
+	"SocialMediaPosting": {"BlogPosting", "DiscussionForumPosting"},
+	"Store":              {"BookStore", "ClothingStore", "ElectronicsStore", "GroceryStore"},
+}
+
+// schemaTypes returns schemaType along with all of its subtypes.
+func schemaTypes(schemaType string) map[string]bool {
+	types := make(map[string]bool)
+	pending := []string{schemaType}
+	for len(pending) > 0 {
+		t := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if !types[t] {
+			types[t] = true
+			pending = append(pending, schemaSubtypes[t]...)
+		}
+	}
+	return types
+}
+
+// StructuredData is the JSON-LD item of a requested schema.org type found on
+// a page, with a report of how complete it is.
+type StructuredData struct {
+	Type    string         `json:"type"`              // The requested type, e.g. "Product"
+	Item    map[string]any `json:"item,omitempty"`    // The first matching item, normalized; nil when none matched
+	Matches int            `json:"matches"`           // Items of the type found on the page
+	Missing []string       `json:"missing,omitempty"` // Required properties the item lacks or leaves empty
+	Valid   bool           `json:"valid"`             // An item matched and has every required property
+}
+
+// ValidateSchemaType checks that a requested schema.org type is a bare type
+// name such as "Product".
+func ValidateSchemaType(schemaType string) error {
+	if schemaType == "" {
+		return nil
+	}
+	for _, r := range schemaType {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return fmt.Errorf("invalid schema type %q", schemaType)
+		}
+	}
+	if schemaType[0] < 'A' || schemaType[0] > 'Z' {
+		return errors.New("schema types start with an upper-case letter, e.g. \"Product\"")
+	}
+	return nil
+}
+
+// extractStructuredData finds the JSON-LD items of schemaType, or one of its
+// subtypes, in top-level objects, arrays and @graph containers as well
+// as nested in other items. The first is normalized: schema.org prefixes are
+// dropped from its type and property names, @context is removed and
+// references to other nodes of the page by @id are replaced with the nodes.
+// It is then checked for the type's required properties.
+func extractStructuredData(doc *goquery.Document, schemaType string) *StructuredData {
+	result := &StructuredData{Type: schemaType}
+	wanted := schemaTypes(schemaType)
+
+	var docs []any
+	nodes := make(map[string]map[string]any) // By @id, for resolving references
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var ld any
+		if err := json.Unmarshal([]byte(s.Text()), &ld); err != nil {
+			return
+		}
+		docs = append(docs, ld)
+		indexJSONLD(ld, nodes)
+	})
+
+	var first map[string]any
+	for _, ld := range docs {
+		walkJSONLD(ld, func(node map[string]any) {
+			for _, t := range jsonLDTypes(node) {
+				if wanted[t] {
+					result.Matches++
+					if first == nil {
+						first = node
+					}
+					return
+				}
+			}
+		})
+	}
+	if first == nil {
+		return result
+	}
+
+	result.Item = normalizeJSONLD(first, nodes, make(map[string]bool), 0).(map[string]any)
+	for _, prop := range schemaRequired[schemaType] {
+		if emptyJSONLD(result.Item[prop]) {
+			result.Missing = append(result.Missing, prop)
+		}
+	}
+	result.Valid = len(result.Missing) == 0
+	return result
+}
+
+// walkJSONLD calls fn for every object of a decoded JSON-LD document, level
+// by level, so the items closest to the top come first. Properties are
+// visited in name order, as decoded objects have none of their own.
+func walkJSONLD(root any, fn func(map[string]any)) {
+	level := []any{root}
+	for depth := 0; depth <= maxSchemaDepth && len(level) > 0; depth++ {
+		var next []any
+		for _, node := range level {
+			switch n := node.(type) {
+			case map[string]any:
+				fn(n)
+				for _, key := range slices.Sorted(maps.Keys(n)) {
+					next = append(next, n[key])
+				}
+			case []any:
+				next = append(next, n...)
+			}
+		}
+		level = next
+	}
+}
+
+// indexJSONLD records the nodes of a document that have an @id along with
+// other properties, so references to them can be resolved.
+func indexJSONLD(node any, nodes map[string]map[string]any) {
+	walkJSONLD(node, func(n map[string]any) {
+		if id, ok := n["@id"].(string); ok && len(n) > 1 {
+			if _, seen := nodes[id]; !seen {
+				nodes[id] = n
+			}
+		}
+	})
+}
+
+// jsonLDTypes returns the @type values of a node without schema.org prefixes.
+func jsonLDTypes(node map[string]any) []string {
+	var types []string
+	switch t := node["@type"].(type) {
+	case string:
+		types = append(types, schemaName(t))
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, schemaName(s))
+			}
+		}
+	}
+	return types
+}
+
+// schemaName strips the schema.org prefixes JSON-LD allows on type and
+// property names, e.g. "https://schema.org/Product" or "schema:name".
+func schemaName(name string) string {
+	for _, prefix := range []string{"https://schema.org/", "http://schema.org/", "schema:"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			return rest
+		}
+	}
+	return name
+}
+
+// normalizeJSONLD copies a decoded JSON-LD value, dropping @context and
+// schema.org prefixes and inlining the nodes that bare {"@id": ...} objects
+// refer to. visiting guards against reference cycles.
+func normalizeJSONLD(node any, nodes map[string]map[string]any, visiting map[string]bool, depth int) any {
+	switch n := node.(type) {
+	case map[string]any:
+		id, hasID := n["@id"].(string)
+		if hasID && len(n) == 1 && !visiting[id] && depth < maxSchemaDepth {
+			if target, ok := nodes[id]; ok {
+				return normalizeJSONLD(target, nodes, visiting, depth+1)
+			}
+		}
+		if hasID && !visiting[id] {
+			visiting[id] = true
+			defer delete(visiting, id)
+		}
+		out := make(map[string]any, len(n))
+		for key, value := range n {
+			switch key {
+			case "@context":
+				continue
+			case "@type":
+				types := jsonLDTypes(n)
+				if len(types) == 1 {
+					out[key] = types[0]
+				} else {
+					out[key] = types
+				}
+				continue
+			}
+			if depth >= maxSchemaDepth {
+				out[schemaName(key)] = value
+				continue
+			}
+			out[schemaName(key)] = normalizeJSONLD(value, nodes, visiting, depth+1)
+		}
+		return out
+	case []any:
+		out := make([]any, len(n))
+		for i, child := range n {
+			out[i] = normalizeJSONLD(child, nodes, visiting, depth+1)
+		}
+		return out
+	}
+	return node
+}
+
+// emptyJSONLD reports whether a property value is missing or holds nothing.
+func emptyJSONLD(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
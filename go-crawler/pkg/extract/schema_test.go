@@ -0,0 +1,108 @@
+package extract
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func schemaDoc(t *testing.T, scripts ...string) *goquery.Document {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("<html><head>")
+	for _, s := range scripts {
+		b.WriteString(`<script type="application/ld+json">` + s + `</script>`)
+	}
+	b.WriteString("</head><body></body></html>")
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestExtractStructuredDataGraph(t *testing.T) {
+	doc := schemaDoc(t, `{
+		"@context": "https://schema.org",
+		"@graph": [
+			{"@type": "WebPage", "@id": "#page", "name": "Page"},
+			{"@type": "schema:Product", "@id": "#product", "name": "Lamp", "offers": {"@id": "#offer"}},
+			{"@type": "Offer", "@id": "#offer", "price": "20.00"}
+		]
+	}`)
+
+	got := extractStructuredData(doc, "Product")
+	if got.Matches != 1 || !got.Valid {
+		t.Fatalf("got %d matches, valid %v, missing %v; want 1 valid match", got.Matches, got.Valid, got.Missing)
+	}
+	if got.Item["@type"] != "Product" {
+		t.Errorf("@type = %v, want the unprefixed Product", got.Item["@type"])
+	}
+	if _, ok := got.Item["@context"]; ok {
+		t.Error("@context was kept")
+	}
+	offer, ok := got.Item["offers"].(map[string]any)
+	if !ok || offer["price"] != "20.00" {
+		t.Errorf("offers = %v, want the #offer node inlined", got.Item["offers"])
+	}
+}
+
+func TestExtractStructuredDataArrays(t *testing.T) {
+	doc := schemaDoc(t,
+		`[{"@type": "Person", "name": "Ann"}, {"@type": ["Thing", "Product"], "name": "Mug"}]`,
+		`{"@type": "Product", "name": "Cup"}`,
+	)
+
+	got := extractStructuredData(doc, "Product")
+	if got.Matches != 2 {
+		t.Fatalf("matches = %d, want 2", got.Matches)
+	}
+	if got.Item["name"] != "Mug" {
+		t.Errorf("item name = %v, want the first match, Mug", got.Item["name"])
+	}
+	if !slices.Equal(got.Missing, []string{"offers"}) || got.Valid {
+		t.Errorf("missing = %v, valid %v; want [offers], invalid", got.Missing, got.Valid)
+	}
+}
+
+func TestExtractStructuredDataCycles(t *testing.T) {
+	doc := schemaDoc(t, `{"@graph": [
+		{"@type": "Product", "@id": "#a", "name": "A", "offers": {"price": "1"}, "isRelatedTo": {"@id": "#b"}},
+		{"@type": "Product", "@id": "#b", "name": "B", "isRelatedTo": {"@id": "#a"}},
+		{"@type": "Organization", "@id": "#self", "name": "Self", "parentOrganization": {"@id": "#self"}}
+	]}`)
+
+	got := extractStructuredData(doc, "Product")
+	if got.Matches != 2 || !got.Valid {
+		t.Fatalf("got %d matches, valid %v; want 2, valid", got.Matches, got.Valid)
+	}
+	related, ok := got.Item["isRelatedTo"].(map[string]any)
+	if !ok || related["name"] != "B" {
+		t.Fatalf("isRelatedTo = %v, want #b inlined", got.Item["isRelatedTo"])
+	}
+	back, ok := related["isRelatedTo"].(map[string]any)
+	if !ok || len(back) != 1 || back["@id"] != "#a" {
+		t.Errorf("#b's isRelatedTo = %v, want the bare reference back to #a", related["isRelatedTo"])
+	}
+
+	org := extractStructuredData(doc, "Organization")
+	parent, ok := org.Item["parentOrganization"].(map[string]any)
+	if !ok || len(parent) != 1 || parent["@id"] != "#self" {
+		t.Errorf("parentOrganization = %v, want the bare self reference", org.Item["parentOrganization"])
+	}
+}
+
+func TestExtractStructuredDataSubtypes(t *testing.T) {
+	doc := schemaDoc(t, `{"@type": "Restaurant", "name": "Luigi's", "address": "1 Main St"}`)
+
+	for _, schemaType := range []string{"Restaurant", "FoodEstablishment", "LocalBusiness", "Organization"} {
+		if got := extractStructuredData(doc, schemaType); got.Matches != 1 || !got.Valid {
+			t.Errorf("%s: got %d matches, valid %v; want 1, valid", schemaType, got.Matches, got.Valid)
+		}
+	}
+	if got := extractStructuredData(doc, "Product"); got.Matches != 0 || got.Item != nil {
+		t.Errorf("Product: got %d matches, want none", got.Matches)
+	}
+}